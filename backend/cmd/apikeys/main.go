@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const usage = `usage:
+  apikeys create -name <name> -scopes stocks:read,chart:read [-rate 60] [-burst 20] [-quota 5000]
+  apikeys list
+  apikeys revoke -id <id>`
+
+// local function to issue, list and revoke the api keys of the API
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	repo, err := db.ConnectCockRoachDB(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize database repository: %v", err)
+	}
+
+	defer repo.Close()
+
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "create":
+		create(ctx, repo, args)
+	case "list":
+		list(ctx, repo)
+	case "revoke":
+		revoke(ctx, repo, args)
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func create(ctx context.Context, repo *db.CockRoachRepository, args []string) {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "owner of the key")
+	scopes := flags.String("scopes", "", "comma separated scopes: "+strings.Join(auth.Scopes, ", "))
+	rate := flags.Int("rate", 0, "requests per minute, 0 uses AUTH_RATE_PER_MINUTE and a negative value disables the limit")
+	burst := flags.Int("burst", 0, "requests allowed at once, 0 uses AUTH_BURST")
+	quota := flags.Int("quota", 0, "requests per UTC day, 0 uses AUTH_DAILY_QUOTA and a negative value disables the quota")
+	flags.Parse(args)
+
+	if *name == "" || *scopes == "" {
+		log.Fatal(usage)
+	}
+
+	limits := models.RateLimits{
+		RatePerMinute: *rate,
+		Burst:         *burst,
+		DailyQuota:    *quota,
+	}
+
+	apiKey, key, err := auth.NewAPIKey(*name, strings.Split(*scopes, ","), limits)
+	if err != nil {
+		log.Fatalf("failed to generate api key: %v", err)
+	}
+
+	if err := repo.CreateAPIKey(ctx, apiKey); err != nil {
+		log.Fatalf("failed to store api key: %v", err)
+	}
+
+	log.Printf("created api key %s for %s, it won't be shown again", apiKey.ID, apiKey.Name)
+	fmt.Println(key)
+}
+
+func list(ctx context.Context, repo *db.CockRoachRepository) {
+	keys, err := repo.ListAPIKeys(ctx)
+	if err != nil {
+		log.Fatalf("failed to list api keys: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(keys); err != nil {
+		log.Fatalf("failed to print api keys: %v", err)
+	}
+}
+
+func revoke(ctx context.Context, repo *db.CockRoachRepository, args []string) {
+	flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := flags.String("id", "", "id of the key to revoke")
+	flags.Parse(args)
+
+	if *id == "" {
+		log.Fatal(usage)
+	}
+
+	if err := repo.RevokeAPIKey(ctx, *id); err != nil {
+		log.Fatalf("failed to revoke api key: %v", err)
+	}
+
+	log.Printf("revoked api key %s", *id)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/backtest"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	day        = 24 * time.Hour
+)
+
+// local command to replay the stored rating history and measure the scoring
+// algorithm against the stored daily bars
+func main() {
+	start := flag.String("start", time.Now().AddDate(-1, 0, 0).Format(dateLayout), "first rebalance date (YYYY-MM-DD)")
+	end := flag.String("end", time.Now().Format(dateLayout), "last rebalance date (YYYY-MM-DD)")
+	rebalance := flag.Int("rebalance", 7, "days between rebalances")
+	horizon := flag.Int("horizon", 0, "forward return horizon in days, defaults to the rebalance interval")
+	top := flag.Int("top", 50, "number of stocks held in the portfolio")
+	datasetName := flag.String("dataset", "", "dataset whose history is replayed, defaults to DEFAULT_DATASET")
+	table := flag.String("table", "", "stocks table whose history is replayed, overrides -dataset")
+	barsTable := flag.String("bars", "", "table holding the daily bars, defaults to BARS_TABLE")
+	configFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	startDate, err := time.Parse(dateLayout, *start)
+	if err != nil {
+		log.Fatalf("invalid start date: %v", err)
+	}
+
+	endDate, err := time.Parse(dateLayout, *end)
+	if err != nil {
+		log.Fatalf("invalid end date: %v", err)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Load(ctx, config.WithFlags(configFlags))
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize the app: %v", err)
+	}
+
+	defer a.Close()
+
+	stocksTable := *table
+	if stocksTable == "" {
+		dataset, err := cfg.Dataset(*datasetName)
+		if err != nil {
+			log.Fatalf("failed to find the dataset: %v", err)
+		}
+		stocksTable = dataset.Table
+	}
+
+	// every rating recorded up to the end, so the tickers rated before the
+	// start are still held at the first rebalance
+	history, err := a.Repo.GetStocksHistory(ctx, stocksTable, time.Time{}, endDate)
+	if err != nil {
+		log.Fatalf("failed to load rating history: %v", err)
+	}
+
+	horizonDays := *horizon
+	if horizonDays <= 0 {
+		horizonDays = *rebalance
+	}
+
+	if *barsTable == "" {
+		*barsTable = cfg.BarsTable
+	}
+
+	bars, err := a.Repo.GetDailyBars(ctx, *barsTable, startDate.Add(-7*day), endDate.Add(time.Duration(horizonDays)*day))
+	if err != nil {
+		log.Fatalf("failed to load daily bars: %v", err)
+	}
+
+	log.Printf("replaying %d rating changes against %d daily bars", len(history), len(bars))
+
+	report, err := backtest.Run(backtest.Config{
+		Start:     startDate,
+		End:       endDate,
+		Rebalance: time.Duration(*rebalance) * day,
+		Horizon:   time.Duration(horizonDays) * day,
+		TopN:      *top,
+		Profile:   a.Profile,
+	}, history, bars)
+	if err != nil {
+		log.Fatalf("failed to run backtest: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
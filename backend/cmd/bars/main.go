@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const usage = `usage:
+  bars create
+  bars load -dir prices/
+  bars fetch -tickers AAPL,MSFT`
+
+// local function to create and fill the daily bars of BARS_TABLE, read by the
+// store price provider and the backtest
+func main() {
+	if len(os.Args) < 2 || !slices.Contains([]string{"create", "load", "fetch"}, os.Args[1]) {
+		log.Fatal(usage)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize the app: %v", err)
+	}
+
+	defer a.Close()
+
+	if err := a.Repo.CreateBarsTable(ctx, cfg.BarsTable); err != nil {
+		log.Fatalf("failed to create the bars table: %v", err)
+	}
+
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "create":
+		log.Printf("created %s", cfg.BarsTable)
+	case "load":
+		load(ctx, a, args)
+	case "fetch":
+		fetch(ctx, a, args)
+	}
+}
+
+// load stores the bars of every <TICKER>.csv of the directory.
+func load(ctx context.Context, a *app.App, args []string) {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory of <TICKER>.csv files with a date,open,high,low,close,volume header")
+	flags.Parse(args)
+
+	if *dir == "" {
+		log.Fatal(usage)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(*dir, "*.csv"))
+	if err != nil {
+		log.Fatalf("failed to list csv files: %v", err)
+	}
+
+	provider := chart.NewCSVProvider(*dir)
+	for _, path := range paths {
+		ticker := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		store(ctx, a, provider, ticker)
+	}
+}
+
+// fetch stores the bars the configured price providers return for the tickers.
+func fetch(ctx context.Context, a *app.App, args []string) {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	tickers := flags.String("tickers", "", "comma separated tickers")
+	flags.Parse(args)
+
+	if *tickers == "" {
+		log.Fatal(usage)
+	}
+
+	provider, err := a.Prices()
+	if err != nil {
+		log.Fatalf("failed to build the price providers: %v", err)
+	}
+
+	for _, ticker := range strings.Split(*tickers, ",") {
+		store(ctx, a, provider, strings.TrimSpace(ticker))
+	}
+}
+
+func store(ctx context.Context, a *app.App, provider chart.PriceProvider, ticker string) {
+	table := a.Config.BarsTable
+	ticker = strings.ToUpper(ticker)
+
+	data, err := provider.FetchData(ctx, ticker)
+	if err != nil {
+		log.Fatalf("failed to read the bars of %s: %v", ticker, err)
+	}
+
+	bars := make([]*models.DailyBar, 0, len(data))
+	for _, dailyData := range data {
+		bars = append(bars, &models.DailyBar{Ticker: ticker, DailyData: dailyData})
+	}
+
+	if err := a.Repo.UpsertDailyBars(ctx, bars, table); err != nil {
+		log.Fatalf("failed to store the bars of %s: %v", ticker, err)
+	}
+
+	log.Printf("loaded %d bars of %s into %s", len(bars), ticker, table)
+}
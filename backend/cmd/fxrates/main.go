@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/money"
+)
+
+const usage = `usage:
+  fxrates load -file rates.csv
+  fxrates list [-as-of 2024-03-05]`
+
+// local function to load the exchange rates the targets are converted with
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	repo, err := db.ConnectCockRoachDB(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize database repository: %v", err)
+	}
+
+	defer repo.Close()
+
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "load":
+		load(ctx, repo, args)
+	case "list":
+		list(ctx, repo, args)
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func load(ctx context.Context, repo *db.CockRoachRepository, args []string) {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	path := flags.String("file", "", "CSV of rates with a base,quote,rate,as_of header")
+	flags.Parse(args)
+
+	if *path == "" {
+		log.Fatal(usage)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("failed to open rates: %v", err)
+	}
+
+	defer file.Close()
+
+	rates, err := money.ReadRates(file)
+	if err != nil {
+		log.Fatalf("failed to read rates: %v", err)
+	}
+
+	if err := repo.UpsertFXRates(ctx, rates); err != nil {
+		log.Fatalf("failed to store rates: %v", err)
+	}
+
+	log.Printf("loaded %d rates from %s", len(rates), *path)
+}
+
+func list(ctx context.Context, repo *db.CockRoachRepository, args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	asOf := flags.String("as-of", "", "day of the rates, today when empty")
+	flags.Parse(args)
+
+	day := time.Now()
+	if *asOf != "" {
+		parsed, err := time.Parse(time.DateOnly, *asOf)
+		if err != nil {
+			log.Fatalf("invalid -as-of: %v", err)
+		}
+		day = parsed
+	}
+
+	rates, err := repo.GetFXRates(ctx, day)
+	if err != nil {
+		log.Fatalf("failed to list rates: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(rates); err != nil {
+		log.Fatalf("failed to print rates: %v", err)
+	}
+}
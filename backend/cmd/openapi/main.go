@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+)
+
+// local function to write the OpenAPI document and the frontend types generated from the endpoint definitions
+func main() {
+	jsonPath := flag.String("json", "", "file the OpenAPI document is written to, stdout when no output is given")
+	tsPath := flag.String("ts", "", "file the TypeScript types are written to")
+	flag.Parse()
+
+	doc := spec.NewDocument()
+
+	if *tsPath != "" {
+		if err := os.WriteFile(*tsPath, []byte(spec.TypeScript(doc)), 0o644); err != nil {
+			log.Fatalf("failed to write TypeScript types: %v", err)
+		}
+	}
+
+	if *jsonPath == "" && *tsPath != "" {
+		return
+	}
+
+	docBytes, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to serialize OpenAPI document: %v", err)
+	}
+	docBytes = append(docBytes, '\n')
+
+	if *jsonPath == "" {
+		os.Stdout.Write(docBytes)
+		return
+	}
+
+	if err := os.WriteFile(*jsonPath, docBytes, 0o644); err != nil {
+		log.Fatalf("failed to write OpenAPI document: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/handlers"
+	"github.com/CorreaJose13/StockAPI/internal/server"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// local function to serve every endpoint of the API and the Prometheus metrics from a single process
+func main() {
+	addr := flag.String("addr", ":8080", "address the server listens on")
+	configFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load(ctx, config.WithFlags(configFlags))
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	if err := telemetry.Setup(ctx, "server", cfg); err != nil {
+		log.Fatalf("failed to set up telemetry: %v", err)
+	}
+
+	defer telemetry.Shutdown(context.Background())
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to set up the app: %v", err)
+	}
+
+	defer a.Close()
+
+	routes, err := handlers.Routes(a)
+	if err != nil {
+		log.Fatalf("failed to set up the endpoints: %v", err)
+	}
+
+	srv := server.New(*addr, routes)
+	srv.ReadTimeout = cfg.ServerReadTimeout
+	srv.WriteTimeout = cfg.ServerWriteTimeout
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			a.Logger.Error("failed to shut down server", "error", err)
+		}
+	}()
+
+	a.Logger.Info("serving the API", "addr", *addr, "metrics", server.PrometheusPath)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("server failed: %v", err)
+	}
+}
@@ -2,67 +2,188 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/CorreaJose13/StockAPI/config"
-	"github.com/CorreaJose13/StockAPI/internal/api"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
 	"github.com/CorreaJose13/StockAPI/internal/db"
-	"github.com/CorreaJose13/StockAPI/internal/repository"
+	"github.com/CorreaJose13/StockAPI/internal/ingest"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
 	"github.com/CorreaJose13/StockAPI/models"
 	"github.com/CorreaJose13/StockAPI/utils"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// local function to fetch and store stocks retrieved from the API
+// local function to fetch stocks from the configured sources and sync them into the table of the dataset
 func main() {
-	ctx := context.Background()
+	datasetName := flag.String("dataset", "", "dataset to sync, defaults to DEFAULT_DATASET")
+	dryRun := flag.Bool("dry-run", false, "print the planned inserts, updates and deletes without applying them")
+	force := flag.Bool("force", false, "apply the deletes even when they exceed SYNC_MAX_DELETE_PERCENT")
+	configFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	cfg, err := config.LoadConfig()
+	ctx := telemetry.WithRequestID(context.Background(), telemetry.NewRequestID())
+
+	cfg, err := config.Load(ctx, config.WithFlags(configFlags))
 	if err != nil {
 		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	repo, err := db.ConnectCockRoachDB(cfg)
+	if err := telemetry.Setup(ctx, "stockwise", cfg); err != nil {
+		log.Fatalf("failed to set up telemetry: %v", err)
+	}
+
+	ctx, span := telemetry.Start(ctx, "sync stocks")
+	defer func() {
+		span.End()
+		telemetry.Push(context.Background())
+		telemetry.Shutdown(context.Background())
+	}()
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		fatal(ctx, "failed to initialize the app", err)
+	}
+
+	defer a.Close()
+
+	dataset, err := cfg.Dataset(*datasetName)
 	if err != nil {
-		log.Fatalf("failed to initialize database repository: %v", err)
+		fatal(ctx, "failed to find the dataset", err)
+	}
+
+	stocks := fetchStocks(ctx, a, dataset)
+
+	formattedStocks, labels := formatStocks(ctx, a, stocks)
+
+	formattedStocks, checker := checkStocks(ctx, a, dataset, formattedStocks)
+
+	opts := ingest.NewOptions(cfg).Reconcile
+	opts.DryRun = opts.DryRun || *dryRun
+	opts.Force = opts.Force || *force
+	opts.Quality = checker.Report().Summary()
+	opts.Rejected = checker.Rejected()
+
+	plan, err := a.Repo.BulkUpdateStocks(ctx, formattedStocks, dataset.Table, opts)
+	switch {
+	case errors.Is(err, db.ErrDeleteThreshold) && plan != nil && plan.DryRun:
+		// the plan is still printed so the deletes can be reviewed
+		slog.WarnContext(ctx, "delete threshold exceeded", "error", err)
+	case err != nil:
+		fatal(ctx, "failed to update stocks", err)
 	}
 
-	defer repo.Close()
+	if plan.DryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			fatal(ctx, "failed to print plan", err)
+		}
+		return
+	}
 
-	repository.SetStockRepository(repo)
+	ingest.CountPlan(plan)
 
-	stocks := fetchStocks(cfg)
+	if err := a.Repo.SaveUnmappedLabels(ctx, labels.Labels()); err != nil {
+		slog.WarnContext(ctx, "failed to save unmapped labels", "error", err)
+	}
 
-	formattedStocks := formatStocks(stocks)
+	slog.InfoContext(ctx, "stored stocks", "run_id", plan.RunID, "inserts", len(plan.Inserts),
+		"updates", len(plan.Updates), "deletes", len(plan.Deletes), "purges", len(plan.Purges))
 
-	if err := repository.BulkInsertStocks(ctx, formattedStocks, "stocks"); err != nil {
-		log.Fatalf("failed to insert stocks: %v", err)
+	if err := a.Snapshots.Refresh(ctx, dataset.Table); err != nil {
+		fatal(ctx, "failed to refresh analysis snapshots", err)
 	}
+}
 
+// fatal logs err and exits once the spans recorded so far are exported.
+func fatal(ctx context.Context, msg string, err error) {
+	slog.ErrorContext(ctx, msg, "error", err)
+	telemetry.End(trace.SpanFromContext(ctx), err)
+	telemetry.Push(context.Background())
+	telemetry.Shutdown(context.Background())
+	os.Exit(1)
 }
 
-func fetchStocks(cfg *config.Config) []models.Stock {
-	consumer := api.NewAPIConsumer(cfg)
+func fetchStocks(ctx context.Context, a *app.App, dataset config.Dataset) []models.Stock {
+	source, err := a.Sources(dataset)
+	if err != nil {
+		fatal(ctx, "failed to initialize ratings source", err)
+	}
 
-	log.Println("fetching stocks from API...")
-	stocks, err := consumer.FetchStocks()
+	slog.InfoContext(ctx, "fetching stocks", "dataset", dataset.Name, "source", source.Name())
+	stocks, err := source.FetchStocks(ctx)
 	if err != nil {
-		log.Fatalf("failed to fetch stocks: %v", err)
+		fatal(ctx, "failed to fetch stocks", err)
 	}
 
-	log.Printf("successfully fetched %d stocks", len(stocks))
+	slog.InfoContext(ctx, "fetched stocks", "stocks", len(stocks))
 
 	return stocks
 }
 
-func formatStocks(stocks []models.Stock) []*models.FormattedStock {
+func formatStocks(ctx context.Context, a *app.App, stocks []models.Stock) ([]*models.FormattedStock, taxonomy.Unmapped) {
+	formatter := utils.StockFormatter{Brokerages: a.Brokerages, Taxonomy: a.Taxonomy}
+	unmapped := brokerage.Unmapped{}
+	labels := taxonomy.Unmapped{}
+
 	var formattedStocks []*models.FormattedStock
 	for _, stock := range stocks {
-		formattedStock, err := utils.Formatter(&stock)
+		formattedStock, err := formatter.Format(&stock)
 		if err != nil {
-			log.Fatalf("failed to format stock: %v", err)
+			fatal(ctx, "failed to format stock", err)
 		}
 
+		unmapped.Add(a.Brokerages, formattedStock.Brokerage)
+		labels.Add(a.Taxonomy, formattedStock)
 		formattedStocks = append(formattedStocks, formattedStock)
 	}
-	return formattedStocks
+
+	if len(unmapped) > 0 {
+		slog.WarnContext(ctx, "brokerages missing from the registry", "count", len(unmapped), "names", unmapped.Names())
+	}
+	if len(labels) > 0 {
+		slog.WarnContext(ctx, "labels missing from the taxonomy", "count", len(labels), "labels", labels.Names())
+	}
+
+	return formattedStocks, labels
+}
+
+// checkStocks drops the stocks rejected by the data-quality rules with the
+// checks of the sync pipeline, whose checker reports the issues of all of them
+// and the stocks rejected.
+func checkStocks(ctx context.Context, a *app.App, dataset config.Dataset, stocks []*models.FormattedStock) (
+	[]*models.FormattedStock, *ingest.Checker) {
+	history, err := a.Repo.GetStocksHistory(ctx, dataset.Table, time.Now().Add(-a.Quality.HistoryLookback), time.Time{})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read the history checked for outliers", "table", dataset.Table, "error", err)
+	}
+
+	checker, err := ingest.NewChecker(a.Quality, history)
+	if err != nil {
+		fatal(ctx, "failed to initialize the quality checks", err)
+	}
+
+	var checked []*models.FormattedStock
+	for _, stock := range stocks {
+		if checker.Check(ctx, stock) {
+			checked = append(checked, stock)
+		}
+	}
+
+	report := checker.Report()
+	if summary := report.Summary(); summary.Rejected+summary.Warned+summary.Flagged > 0 {
+		slog.WarnContext(ctx, "data quality issues", "checked", summary.Checked, "rejected", summary.Rejected,
+			"warned", summary.Warned, "flagged", summary.Flagged, "rules", report.Issues())
+	}
+
+	return checked, checker
 }
@@ -1,57 +1,455 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultStocksTable     = "stocks"
+	defaultPriceProviders  = "alphavantage"
+	defaultAlphaVantageURL = "https://www.alphavantage.co/query"
+	defaultBarsTable       = "daily_bars"
+	defaultRatingsSources  = "api"
+
+	defaultDBMaxOpenConns    = "1"
+	defaultDBMaxIdleConns    = "1"
+	defaultDBConnMaxLifetime = "30m"
+	defaultDBConnMaxIdleTime = "5m"
+	defaultDBConnectAttempts = "5"
+	defaultDBConnectBackoff  = "500ms"
+
+	defaultUpstreamTimeout    = "30s"
+	defaultServerReadTimeout  = "30s"
+	defaultServerWriteTimeout = "60s"
+
+	defaultSyncMaxDeletePercent = "20"
+	defaultSyncRetentionDays    = "30"
+
+	defaultAuthWebScopes     = "stocks:read,analysis:read,metrics:read,chart:read"
+	defaultAuthWebTokenTTL   = "15m"
+	defaultAuthRatePerMinute = "60"
+	defaultAuthBurst         = "20"
+	defaultAuthDailyQuota    = "5000"
+
+	defaultCORSAllowedOrigins = "*"
+	defaultCORSAllowedMethods = "GET,POST,OPTIONS"
+	defaultCORSAllowedHeaders = "Content-Type,Authorization,X-API-Key"
+	defaultCORSExposedHeaders = "Retry-After,X-RateLimit-Limit,X-RateLimit-Remaining,X-Quota-Limit,X-Quota-Remaining"
+	defaultCORSMaxAge         = "600"
+
+	defaultCacheMaxAge           = "300"
+	defaultCacheHistoricalMaxAge = "86400"
+
+	defaultSearchBackend = "database"
+
+	defaultReportingCurrency = "USD"
+
+	defaultQualityMaxTargetRatio   = "10"
+	defaultQualityOutlierThreshold = "3.5"
+	defaultQualityHistoryDays      = "180"
+
+	defaultLogLevel      = "info"
+	defaultLogFormat     = "json"
+	defaultTraceExporter = "none"
+
+	// FileKey names the config file in the environment, its keys are the
+	// ones of the environment.
+	FileKey = "CONFIG_FILE"
+
+	dotEnvFile = ".env"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
 type Config struct {
 	APIURL      string
 	BearerToken string
 	DBURL       string
 	APIKEY      string
+
+	// StocksTable holds the served stocks.
+	StocksTable string
+	// Datasets are the stocks tables the API serves and the sync fills, keyed
+	// by name. They default to a single dataset of StocksTable named default,
+	// DefaultDataset is served when a request names none.
+	Datasets       map[string]Dataset
+	DefaultDataset string
+
+	// DBMaxOpenConns and DBMaxIdleConns size the connection pools, whose
+	// connections are closed after DBConnMaxLifetime or once idle for
+	// DBConnMaxIdleTime, zero keeps them.
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	// DBConnectAttempts pings the database at startup, waiting
+	// DBConnectBackoff after the first failure and twice as long every time.
+	DBConnectAttempts int
+	DBConnectBackoff  time.Duration
+	// DBReadURL is a replica or follower the stock reads of the API go to,
+	// they use the primary when empty. DBFollowerReads lets them be served
+	// slightly stale with AS OF SYSTEM TIME follower_read_timestamp().
+	DBReadURL       string
+	DBFollowerReads bool
+
+	// UpstreamTimeout bounds every call to the ratings and price APIs, zero
+	// waits forever. ServerReadTimeout and ServerWriteTimeout bound the
+	// requests of cmd/server.
+	UpstreamTimeout    time.Duration
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+
+	// PriceProviders lists the chart data providers in fallback order.
+	PriceProviders  []string
+	AlphaVantageURL string
+	PriceCSVDir     string
+	BarsTable       string
+
+	// RatingsSources lists the ratings feeds in priority order, the first one
+	// wins when several feeds report the same rating.
+	RatingsSources     []string
+	RatingsFile        string
+	RatingsRESTMapping string
+
+	// Ingestion pipeline tuning, zero values fall back to the pipeline defaults.
+	IngestWorkers     int
+	IngestBatchSize   int
+	IngestPageBuffer  int
+	IngestSkipInvalid bool
+	IngestDryRun      bool
+
+	// SyncMaxDeletePercent is the largest share of the stocks a sync may
+	// delete unless SyncForce is set, zero disables the check.
+	SyncMaxDeletePercent int
+	SyncForce            bool
+	// SyncRetentionDays is how long deleted stocks are kept before being
+	// purged, zero keeps them forever.
+	SyncRetentionDays int
+
+	// ScoringLimit and ScoringWeights override the default scoring profile,
+	// the weights are keyed by factor, such as perc_change or action.
+	ScoringLimit   int
+	ScoringWeights map[string]float64
+	// BrokeragesFile is a JSON list of brokerages merged into the built-in
+	// registry, to add the aliases of the names reported as unmapped.
+	BrokeragesFile string
+	// TaxonomyFile is a JSON taxonomy of rating and action labels merged
+	// into the built-in one.
+	TaxonomyFile string
+	// ReportingCurrency is the ISO 4217 code the targets are converted to
+	// for the analysis, with the rates of the fx_rates table.
+	ReportingCurrency string
+
+	// QualityRules sets the severity of the data-quality rules of the sync,
+	// reject, warn, flag or off, keyed by rule such as outlier or duplicate.
+	QualityRules map[string]string
+	// QualityMaxTargetRatio is the largest ratio between the targets of a
+	// rating, either way.
+	QualityMaxTargetRatio float64
+	// QualityOutlierThreshold is the robust z-score beyond which a target is
+	// an outlier of the QualityHistoryDays of history of its ticker.
+	QualityOutlierThreshold float64
+	QualityHistoryDays      int
+
+	// SearchBackend is database to match the /search queries with the trigram
+	// indexes of the stocks tables, or memory to index the stocks in process.
+	SearchBackend string
+
+	// AuthJWTSecret verifies the bearer tokens, they're refused when empty.
+	AuthJWTSecret string
+	AuthJWTIssuer string
+	// AuthWebScopes are granted to the short-lived tokens /token issues to the
+	// browsers, valid for AuthWebTokenTTL.
+	AuthWebScopes   []string
+	AuthWebTokenTTL time.Duration
+	// Default limits of the tokens and of the keys issued without their own.
+	AuthRatePerMinute int
+	AuthBurst         int
+	AuthDailyQuota    int
+
+	// CORSAllowedOrigins are matched exactly, * allows any origin.
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSMaxAge           int
+	CORSAllowCredentials bool
+
+	// CacheMaxAge is how many seconds clients may reuse the latest data
+	// without revalidating, CacheHistoricalMaxAge applies to as_of reads that
+	// predate the last sync and can no longer change.
+	CacheMaxAge           int
+	CacheHistoricalMaxAge int
+
+	// LogLevel is one of debug, info, warn or error and LogFormat json or
+	// text. TraceExporter is none, stdout or otlp.
+	LogLevel      string
+	LogFormat     string
+	TraceExporter string
+	// MetricsPushgatewayURL is where the Lambdas push their metrics after
+	// every invocation, they're only kept in memory when empty.
+	MetricsPushgatewayURL string
+
+	// SecretsDir is where the file secret provider resolves relative paths.
+	SecretsDir string
 }
 
-func LoadConfig() (*Config, error) {
-	err := godotenv.Load()
-	if err != nil {
-		return nil, fmt.Errorf("error loading .env file: %w", err)
-	}
+// fields lists every key of the configuration with its default, the value of
+// the field is parsed according to its type.
+var fields = []struct {
+	key   string
+	def   string
+	field func(*Config) any
+}{
+	{"API_URL", "", func(c *Config) any { return &c.APIURL }},
+	{"BEARER_TOKEN", "", func(c *Config) any { return &c.BearerToken }},
+	{"DB_URL", "", func(c *Config) any { return &c.DBURL }},
+	{"API_KEY", "", func(c *Config) any { return &c.APIKEY }},
 
-	config := fullConfig()
+	{"STOCKS_TABLE", defaultStocksTable, func(c *Config) any { return &c.StocksTable }},
+	{"DATASETS", "", func(c *Config) any { return &c.Datasets }},
+	{"DEFAULT_DATASET", "", func(c *Config) any { return &c.DefaultDataset }},
+	{"DB_MAX_OPEN_CONNS", defaultDBMaxOpenConns, func(c *Config) any { return &c.DBMaxOpenConns }},
+	{"DB_MAX_IDLE_CONNS", defaultDBMaxIdleConns, func(c *Config) any { return &c.DBMaxIdleConns }},
+	{"DB_CONN_MAX_LIFETIME", defaultDBConnMaxLifetime, func(c *Config) any { return &c.DBConnMaxLifetime }},
+	{"DB_CONN_MAX_IDLE_TIME", defaultDBConnMaxIdleTime, func(c *Config) any { return &c.DBConnMaxIdleTime }},
+	{"DB_CONNECT_ATTEMPTS", defaultDBConnectAttempts, func(c *Config) any { return &c.DBConnectAttempts }},
+	{"DB_CONNECT_BACKOFF", defaultDBConnectBackoff, func(c *Config) any { return &c.DBConnectBackoff }},
+	{"DB_READ_URL", "", func(c *Config) any { return &c.DBReadURL }},
+	{"DB_FOLLOWER_READS", "false", func(c *Config) any { return &c.DBFollowerReads }},
 
-	return config, nil
+	{"UPSTREAM_TIMEOUT", defaultUpstreamTimeout, func(c *Config) any { return &c.UpstreamTimeout }},
+	{"SERVER_READ_TIMEOUT", defaultServerReadTimeout, func(c *Config) any { return &c.ServerReadTimeout }},
+	{"SERVER_WRITE_TIMEOUT", defaultServerWriteTimeout, func(c *Config) any { return &c.ServerWriteTimeout }},
+
+	{"PRICE_PROVIDERS", defaultPriceProviders, func(c *Config) any { return &c.PriceProviders }},
+	{"ALPHAVANTAGE_URL", defaultAlphaVantageURL, func(c *Config) any { return &c.AlphaVantageURL }},
+	{"PRICE_CSV_DIR", "", func(c *Config) any { return &c.PriceCSVDir }},
+	{"BARS_TABLE", defaultBarsTable, func(c *Config) any { return &c.BarsTable }},
+
+	{"RATINGS_SOURCES", defaultRatingsSources, func(c *Config) any { return &c.RatingsSources }},
+	{"RATINGS_FILE", "", func(c *Config) any { return &c.RatingsFile }},
+	{"RATINGS_REST_MAPPING", "", func(c *Config) any { return &c.RatingsRESTMapping }},
+	{"INGEST_WORKERS", "0", func(c *Config) any { return &c.IngestWorkers }},
+	{"INGEST_BATCH_SIZE", "0", func(c *Config) any { return &c.IngestBatchSize }},
+	{"INGEST_PAGE_BUFFER", "0", func(c *Config) any { return &c.IngestPageBuffer }},
+	{"INGEST_SKIP_INVALID", "false", func(c *Config) any { return &c.IngestSkipInvalid }},
+	{"INGEST_DRY_RUN", "false", func(c *Config) any { return &c.IngestDryRun }},
+	{"SYNC_MAX_DELETE_PERCENT", defaultSyncMaxDeletePercent, func(c *Config) any { return &c.SyncMaxDeletePercent }},
+	{"SYNC_FORCE", "false", func(c *Config) any { return &c.SyncForce }},
+	{"SYNC_RETENTION_DAYS", defaultSyncRetentionDays, func(c *Config) any { return &c.SyncRetentionDays }},
+
+	{"SCORING_LIMIT", "0", func(c *Config) any { return &c.ScoringLimit }},
+	{"SCORING_WEIGHTS", "", func(c *Config) any { return &c.ScoringWeights }},
+	{"BROKERAGES_FILE", "", func(c *Config) any { return &c.BrokeragesFile }},
+	{"TAXONOMY_FILE", "", func(c *Config) any { return &c.TaxonomyFile }},
+	{"REPORTING_CURRENCY", defaultReportingCurrency, func(c *Config) any { return &c.ReportingCurrency }},
+
+	{"QUALITY_RULES", "", func(c *Config) any { return &c.QualityRules }},
+	{"QUALITY_MAX_TARGET_RATIO", defaultQualityMaxTargetRatio, func(c *Config) any { return &c.QualityMaxTargetRatio }},
+	{"QUALITY_OUTLIER_THRESHOLD", defaultQualityOutlierThreshold, func(c *Config) any { return &c.QualityOutlierThreshold }},
+	{"QUALITY_HISTORY_DAYS", defaultQualityHistoryDays, func(c *Config) any { return &c.QualityHistoryDays }},
+
+	{"SEARCH_BACKEND", defaultSearchBackend, func(c *Config) any { return &c.SearchBackend }},
+
+	{"AUTH_JWT_SECRET", "", func(c *Config) any { return &c.AuthJWTSecret }},
+	{"AUTH_JWT_ISSUER", "", func(c *Config) any { return &c.AuthJWTIssuer }},
+	{"AUTH_WEB_SCOPES", defaultAuthWebScopes, func(c *Config) any { return &c.AuthWebScopes }},
+	{"AUTH_WEB_TOKEN_TTL", defaultAuthWebTokenTTL, func(c *Config) any { return &c.AuthWebTokenTTL }},
+	{"AUTH_RATE_PER_MINUTE", defaultAuthRatePerMinute, func(c *Config) any { return &c.AuthRatePerMinute }},
+	{"AUTH_BURST", defaultAuthBurst, func(c *Config) any { return &c.AuthBurst }},
+	{"AUTH_DAILY_QUOTA", defaultAuthDailyQuota, func(c *Config) any { return &c.AuthDailyQuota }},
+
+	{"CORS_ALLOWED_ORIGINS", defaultCORSAllowedOrigins, func(c *Config) any { return &c.CORSAllowedOrigins }},
+	{"CORS_ALLOWED_METHODS", defaultCORSAllowedMethods, func(c *Config) any { return &c.CORSAllowedMethods }},
+	{"CORS_ALLOWED_HEADERS", defaultCORSAllowedHeaders, func(c *Config) any { return &c.CORSAllowedHeaders }},
+	{"CORS_EXPOSED_HEADERS", defaultCORSExposedHeaders, func(c *Config) any { return &c.CORSExposedHeaders }},
+	{"CORS_MAX_AGE", defaultCORSMaxAge, func(c *Config) any { return &c.CORSMaxAge }},
+	{"CORS_ALLOW_CREDENTIALS", "false", func(c *Config) any { return &c.CORSAllowCredentials }},
+
+	{"CACHE_MAX_AGE", defaultCacheMaxAge, func(c *Config) any { return &c.CacheMaxAge }},
+	{"CACHE_HISTORICAL_MAX_AGE", defaultCacheHistoricalMaxAge, func(c *Config) any { return &c.CacheHistoricalMaxAge }},
+
+	{"LOG_LEVEL", defaultLogLevel, func(c *Config) any { return &c.LogLevel }},
+	{"LOG_FORMAT", defaultLogFormat, func(c *Config) any { return &c.LogFormat }},
+	{"TRACE_EXPORTER", defaultTraceExporter, func(c *Config) any { return &c.TraceExporter }},
+	{"METRICS_PUSHGATEWAY_URL", "", func(c *Config) any { return &c.MetricsPushgatewayURL }},
+
+	{"SECRETS_DIR", "", func(c *Config) any { return &c.SecretsDir }},
 }
 
-func LoadLambdaConfig() *Config {
-	return fullConfig()
+type loader struct {
+	file      string
+	lookupEnv func(string) (string, bool)
+	dotEnv    bool
+	flags     map[string]string
+	secrets   map[string]SecretProvider
 }
 
-func LoadDbConfig() *Config {
-	config := &Config{
-		DBURL: os.Getenv("DB_URL"),
+type Option func(*loader)
+
+// WithFile reads the config file at path instead of the one of CONFIG_FILE.
+func WithFile(path string) Option {
+	return func(l *loader) {
+		l.file = path
 	}
+}
 
-	return config
+// WithEnv replaces the environment and skips the .env file, mostly for tests.
+func WithEnv(env map[string]string) Option {
+	return func(l *loader) {
+		l.lookupEnv = func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		}
+		l.dotEnv = false
+	}
 }
 
-func LoadAPIConfig() *Config {
-	config := &Config{
-		APIKEY: os.Getenv("API_KEY"),
+// WithFlags sets the values with the highest priority, see Flags.
+func WithFlags(flags *Flags) Option {
+	return func(l *loader) {
+		if flags.File != "" {
+			l.file = flags.File
+		}
+		for key, value := range flags.Values {
+			l.flags[key] = value
+		}
 	}
+}
 
-	return config
+// WithSecretProvider resolves the ${scheme:ref} references with the provider.
+func WithSecretProvider(scheme string, provider SecretProvider) Option {
+	return func(l *loader) {
+		l.secrets[scheme] = provider
+	}
 }
 
-func fullConfig() *Config {
-	config := &Config{
-		APIURL:      os.Getenv("API_URL"),
-		BearerToken: os.Getenv("BEARER_TOKEN"),
-		DBURL:       os.Getenv("DB_URL"),
-		APIKEY:      os.Getenv("API_KEY"),
+// Load reads the configuration in layers, each one overriding the previous:
+// the defaults, the config file, the .env file, the environment and the
+// flags. Secret references are then resolved and every invalid value is
+// reported at once.
+func Load(ctx context.Context, opts ...Option) (*Config, error) {
+	l := &loader{
+		lookupEnv: os.LookupEnv,
+		dotEnv:    true,
+		flags:     map[string]string{},
+		secrets:   map[string]SecretProvider{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	values, err := l.values()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := l.secrets[EnvSecrets]; !ok {
+		l.secrets[EnvSecrets] = envProvider{lookupEnv: l.lookupEnv}
+	}
+	// the file provider is only known once SECRETS_DIR is
+	if _, ok := l.secrets[FileSecrets]; !ok {
+		l.secrets[FileSecrets] = NewFileProvider(values["SECRETS_DIR"])
+	}
+
+	config := &Config{}
+
+	var errs []error
+	for _, field := range fields {
+		value, err := l.resolve(ctx, values[field.key])
+		if err == nil {
+			err = parse(field.field(config), value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.key, err))
+		}
 	}
 
+	config.LogLevel = strings.ToLower(config.LogLevel)
+	config.LogFormat = strings.ToLower(config.LogFormat)
+	config.TraceExporter = strings.ToLower(config.TraceExporter)
+	config.SearchBackend = strings.ToLower(config.SearchBackend)
+	config.ReportingCurrency = strings.ToUpper(config.ReportingCurrency)
+	config.normalizeDatasets()
+
+	errs = append(errs, config.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return config, nil
+}
+
+// Defaults is the configuration of the default values alone, for the
+// handlers that must still answer when Load fails.
+func Defaults() *Config {
+	config := &Config{}
+	for _, field := range fields {
+		// the defaults are covered by the tests of Load
+		_ = parse(field.field(config), field.def)
+	}
+	config.normalizeDatasets()
 	return config
 }
+
+// values merges the layers into the raw value of every key.
+func (l *loader) values() (map[string]string, error) {
+	dotEnv := map[string]string{}
+	if l.dotEnv {
+		values, err := godotenv.Read(dotEnvFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error loading %s file: %w", dotEnvFile, err)
+		}
+		if values != nil {
+			dotEnv = values
+		}
+	}
+
+	env := func(key string) (string, bool) {
+		if value, ok := l.lookupEnv(key); ok && strings.TrimSpace(value) != "" {
+			return value, true
+		}
+		value, ok := dotEnv[key]
+		return value, ok && strings.TrimSpace(value) != ""
+	}
+
+	if l.file == "" {
+		l.file, _ = env(FileKey)
+	}
+
+	file := map[string]string{}
+	if l.file != "" {
+		var err error
+		file, err = readFile(l.file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		values[field.key] = field.def
+		if value, ok := file[field.key]; ok {
+			values[field.key] = value
+		}
+		if value, ok := env(field.key); ok {
+			values[field.key] = value
+		}
+		if value, ok := l.flags[field.key]; ok {
+			values[field.key] = value
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := writeFile(t, "config.json", `{
+		"STOCKS_TABLE": "stocks_file",
+		"BARS_TABLE": "bars_file",
+		"DB_MAX_OPEN_CONNS": 8,
+		"PRICE_PROVIDERS": ["csv", "store"],
+		"SCORING_WEIGHTS": {"perc_change": 2, "action": 0.5},
+		"INGEST_SKIP_INVALID": true
+	}`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-config", file, "-set", "stocks_table=stocks_flag"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, err := Load(context.Background(),
+		WithEnv(map[string]string{"BARS_TABLE": "bars_env", "STOCKS_TABLE": "stocks_env", "UPSTREAM_TIMEOUT": "5s"}),
+		WithFlags(flags),
+	)
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Default", cfg.CacheMaxAge, 300},
+		{"File over default", cfg.DBMaxOpenConns, 8},
+		{"File list", len(cfg.PriceProviders), 2},
+		{"File object", cfg.ScoringWeights["action"], 0.5},
+		{"File boolean", cfg.IngestSkipInvalid, true},
+		{"Env over file", cfg.BarsTable, "bars_env"},
+		{"Env duration", cfg.UpstreamTimeout, 5 * time.Second},
+		{"Flag over env", cfg.StocksTable, "stocks_flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db_url"), []byte("postgresql://db\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	vault := SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "vault-" + ref, nil
+	})
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		key     func(*Config) string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "File secret relative to SECRETS_DIR",
+			env:  map[string]string{"SECRETS_DIR": dir, "DB_URL": "${file:db_url}"},
+			key:  func(c *Config) string { return c.DBURL },
+			want: "postgresql://db",
+		},
+		{
+			name: "Env secret",
+			env:  map[string]string{"API_KEY": "${env:VENDOR_KEY}", "VENDOR_KEY": "key"},
+			key:  func(c *Config) string { return c.APIKEY },
+			want: "key",
+		},
+		{
+			name: "Registered provider",
+			env:  map[string]string{"BEARER_TOKEN": "${vault:token}"},
+			key:  func(c *Config) string { return c.BearerToken },
+			want: "vault-token",
+		},
+		{
+			name:    "Missing file secret",
+			env:     map[string]string{"SECRETS_DIR": dir, "DB_URL": "${file:missing}"},
+			wantErr: ErrSecretNotFound,
+		},
+		{
+			name:    "Unknown provider",
+			env:     map[string]string{"DB_URL": "${ssm:db}"},
+			wantErr: ErrUnknownSecretProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(context.Background(), WithEnv(tt.env), WithSecretProvider("vault", vault))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Load error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := tt.key(cfg); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	_, err := Load(context.Background(), WithEnv(map[string]string{
+		"STOCKS_TABLE":            "stocks; DROP TABLE stocks",
+		"DB_MAX_OPEN_CONNS":       "ten",
+		"SYNC_MAX_DELETE_PERCENT": "150",
+		"UPSTREAM_TIMEOUT":        "-1s",
+		"LOG_LEVEL":               "verbose",
+		"REPORTING_CURRENCY":      "dollars",
+	}))
+
+	for _, want := range []error{ErrInvalidConfig, ErrInvalidTable, ErrInvalidNumber, ErrOutOfRange, ErrInvalidValue} {
+		if !errors.Is(err, want) {
+			t.Errorf("Load error = %v, want it to wrap %v", err, want)
+		}
+	}
+}
+
+func TestLoadFileUnknownKey(t *testing.T) {
+	file := writeFile(t, "config.json", `{"STOCK_TABLE": "typo"}`)
+
+	_, err := Load(context.Background(), WithEnv(nil), WithFile(file))
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Load error = %v, want %v", err, ErrUnknownKey)
+	}
+}
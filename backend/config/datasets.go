@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultDatasetName is the dataset of STOCKS_TABLE and RATINGS_SOURCES when
+// DATASETS is empty.
+const DefaultDatasetName = "default"
+
+var (
+	ErrUnknownDataset = errors.New("unknown dataset")
+	ErrInvalidDataset = errors.New("invalid dataset")
+
+	datasetName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+	schedule    = regexp.MustCompile(`^(cron|rate)\(.+\)$`)
+)
+
+// Dataset is a named stocks table synced from its own ratings sources, such
+// as us_equities or backfill_2024.
+type Dataset struct {
+	Name string `json:"-"`
+	// Table holds the stocks of the dataset, no two datasets share one.
+	Table string `json:"table"`
+	// Sources replace RATINGS_SOURCES for the dataset when set.
+	Sources []string `json:"sources,omitempty"`
+	// Schedule is the EventBridge expression its sync runs on, the infra
+	// reads it from DATASETS to create a schedule passing the name of the
+	// dataset in the payload. Empty for the datasets only synced by hand.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// Dataset returns the dataset registered under name, or the default one when
+// name is empty.
+func (c *Config) Dataset(name string) (Dataset, error) {
+	datasets, defaultName := c.registry()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = defaultName
+	}
+
+	dataset, ok := datasets[name]
+	if !ok {
+		return Dataset{}, fmt.Errorf("%w: %q, expected one of %s", ErrUnknownDataset, name,
+			strings.Join(slices.Sorted(maps.Keys(datasets)), ", "))
+	}
+
+	return dataset, nil
+}
+
+// DatasetNames returns the registered datasets in alphabetical order.
+func (c *Config) DatasetNames() []string {
+	datasets, _ := c.registry()
+	return slices.Sorted(maps.Keys(datasets))
+}
+
+// registry returns the datasets and the name of the default one, the single
+// dataset of STOCKS_TABLE when DATASETS is empty, as for the configs built
+// without Load.
+func (c *Config) registry() (map[string]Dataset, string) {
+	if len(c.Datasets) == 0 {
+		return map[string]Dataset{
+			DefaultDatasetName: {Name: DefaultDatasetName, Table: c.StocksTable, Sources: c.RatingsSources},
+		}, DefaultDatasetName
+	}
+
+	if c.DefaultDataset == "" && len(c.Datasets) == 1 {
+		for name := range c.Datasets {
+			return c.Datasets, name
+		}
+	}
+
+	return c.Datasets, c.DefaultDataset
+}
+
+// normalizeDatasets fills the registry from STOCKS_TABLE when DATASETS is
+// empty, and the name and sources of every dataset.
+func (c *Config) normalizeDatasets() {
+	datasets := make(map[string]Dataset, len(c.Datasets))
+	for name, dataset := range c.Datasets {
+		name = strings.ToLower(strings.TrimSpace(name))
+		dataset.Name = name
+
+		if len(dataset.Sources) == 0 {
+			dataset.Sources = slices.Clone(c.RatingsSources)
+		}
+		for i, source := range dataset.Sources {
+			dataset.Sources[i] = strings.ToLower(strings.TrimSpace(source))
+		}
+
+		datasets[name] = dataset
+	}
+	c.Datasets = datasets
+
+	c.DefaultDataset = strings.ToLower(strings.TrimSpace(c.DefaultDataset))
+	c.Datasets, c.DefaultDataset = c.registry()
+}
+
+func (c *Config) validateDatasets() []error {
+	var errs []error
+
+	tables := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(c.Datasets)) {
+		dataset := c.Datasets[name]
+
+		if !datasetName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%w: %q must be lowercase letters, digits and underscores", ErrInvalidDataset, name))
+		}
+
+		if err := validTable(dataset.Table); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		} else if other, ok := tables[dataset.Table]; ok {
+			errs = append(errs, fmt.Errorf("%w: %s and %s share the table %s", ErrInvalidDataset, other, name, dataset.Table))
+		}
+		tables[dataset.Table] = name
+
+		if dataset.Schedule != "" && !schedule.MatchString(dataset.Schedule) {
+			errs = append(errs, fmt.Errorf("%w: %s schedule %q must be a cron() or rate() expression", ErrInvalidDataset,
+				name, dataset.Schedule))
+		}
+	}
+
+	if c.DefaultDataset == "" {
+		errs = append(errs, fmt.Errorf("%w: DEFAULT_DATASET is required with several datasets", ErrInvalidDataset))
+	} else if _, ok := c.Datasets[c.DefaultDataset]; !ok {
+		errs = append(errs, fmt.Errorf("%w: DEFAULT_DATASET %q", ErrUnknownDataset, c.DefaultDataset))
+	}
+
+	return errs
+}
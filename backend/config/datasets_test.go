@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadDatasets(t *testing.T) {
+	file := writeFile(t, "config.json", `{
+		"RATINGS_SOURCES": ["api"],
+		"DEFAULT_DATASET": "us_equities",
+		"DATASETS": {
+			"us_equities": {"table": "stocks", "schedule": "cron(35 19 ? * mon-fri *)"},
+			"Backfill_2024": {"table": "stocks_backfill_2024", "sources": ["File"]}
+		}
+	}`)
+
+	cfg, err := Load(context.Background(), WithEnv(nil), WithFile(file))
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		dataset     string
+		wantName    string
+		wantTable   string
+		wantSources []string
+		wantErr     error
+	}{
+		{"Default", "", "us_equities", "stocks", []string{"api"}, nil},
+		{"Own sources", "backfill_2024", "backfill_2024", "stocks_backfill_2024", []string{"file"}, nil},
+		{"Case insensitive", " US_EQUITIES ", "us_equities", "stocks", []string{"api"}, nil},
+		{"Unknown", "sandbox", "", "", nil, ErrUnknownDataset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataset, err := cfg.Dataset(tt.dataset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Dataset error = %v, want %v", err, tt.wantErr)
+			}
+
+			if dataset.Name != tt.wantName || dataset.Table != tt.wantTable || !slices.Equal(dataset.Sources, tt.wantSources) {
+				t.Errorf("Dataset = %+v, want %s of %s from %v", dataset, tt.wantName, tt.wantTable, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestLoadDatasetsDefault(t *testing.T) {
+	cfg, err := Load(context.Background(), WithEnv(map[string]string{"STOCKS_TABLE": "stocks_env"}))
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	dataset, err := cfg.Dataset("")
+	if err != nil {
+		t.Fatalf("Dataset returned unexpected error: %v", err)
+	}
+
+	if dataset.Name != DefaultDatasetName || dataset.Table != "stocks_env" {
+		t.Errorf("Dataset = %+v, want %s of stocks_env", dataset, DefaultDatasetName)
+	}
+}
+
+func TestLoadDatasetsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		datasets string
+		def      string
+		wantErr  error
+	}{
+		{"Malformed", `{"us": `, "", ErrInvalidDataset},
+		{"Invalid table", `{"us": {"table": "stocks; DROP TABLE stocks"}}`, "", ErrInvalidTable},
+		{"Shared table", `{"us": {"table": "stocks"}, "sandbox": {"table": "stocks"}}`, "us", ErrInvalidDataset},
+		{"Invalid schedule", `{"us": {"table": "stocks", "schedule": "daily"}}`, "", ErrInvalidDataset},
+		{"Missing default", `{"us": {"table": "stocks"}, "sandbox": {"table": "sandbox"}}`, "", ErrInvalidDataset},
+		{"Unknown default", `{"us": {"table": "stocks"}}`, "eu", ErrUnknownDataset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(context.Background(), WithEnv(map[string]string{"DATASETS": tt.datasets, "DEFAULT_DATASET": tt.def}))
+			if !errors.Is(err, ErrInvalidConfig) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
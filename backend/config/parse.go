@@ -0,0 +1,234 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownKey    = errors.New("unknown configuration key")
+	ErrInvalidFile   = errors.New("invalid config file")
+	ErrInvalidFlag   = errors.New("invalid config flag")
+	ErrInvalidNumber = errors.New("invalid number")
+)
+
+// Flags are the command line layer of the configuration, -config names the
+// config file and every -set KEY=VALUE overrides a key.
+type Flags struct {
+	File   string
+	Values map[string]string
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{Values: map[string]string{}}
+
+	fs.StringVar(&flags.File, "config", "", "JSON config file, defaults to "+FileKey)
+	fs.Func("set", "override a configuration key, as KEY=VALUE", func(value string) error {
+		key, value, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("%w: %s must be KEY=VALUE", ErrInvalidFlag, key)
+		}
+
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !isKey(key) {
+			return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+		}
+
+		flags.Values[key] = value
+		return nil
+	})
+
+	return flags
+}
+
+// readFile reads a JSON object keyed like the environment, whose values may
+// also be numbers, booleans, lists or, for SCORING_WEIGHTS and DATASETS,
+// objects.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFile, path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		if !isKey(key) {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownKey, key))
+			continue
+		}
+		values[key] = fileValue(raw[key])
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFile, path, errors.Join(errs...))
+	}
+
+	return values, nil
+}
+
+// fileValue writes a JSON value the way it would be set in the environment.
+func fileValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, fileValue(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		// objects of objects, such as DATASETS, are set as JSON in the
+		// environment too
+		for _, item := range value {
+			if _, ok := item.(map[string]any); ok {
+				data, _ := json.Marshal(value)
+				return string(data)
+			}
+		}
+		pairs := make([]string, 0, len(value))
+		for _, key := range slices.Sorted(maps.Keys(value)) {
+			pairs = append(pairs, key+"="+fileValue(value[key]))
+		}
+		return strings.Join(pairs, ",")
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func isKey(key string) bool {
+	for _, field := range fields {
+		if field.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parse sets the field pointed by target from its raw value.
+func parse(target any, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch target := target.(type) {
+	case *string:
+		*target = value
+
+	case *[]string:
+		*target = splitList(value)
+
+	case *int:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidNumber, value)
+		}
+		*target = parsed
+
+	case *bool:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %q", value)
+		}
+		*target = parsed
+
+	case *float64:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidNumber, value)
+		}
+		*target = parsed
+
+	case *time.Duration:
+		if value == "" {
+			return nil
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %q", value)
+		}
+		*target = parsed
+
+	case *map[string]float64:
+		weights := map[string]float64{}
+		for _, pair := range splitList(value) {
+			key, number, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q must be name=value", pair)
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil {
+				return fmt.Errorf("%w: %q", ErrInvalidNumber, pair)
+			}
+			weights[strings.TrimSpace(key)] = parsed
+		}
+		if len(weights) > 0 {
+			*target = weights
+		}
+
+	case *map[string]string:
+		values := map[string]string{}
+		for _, pair := range splitList(value) {
+			key, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q must be name=value", pair)
+			}
+			values[strings.TrimSpace(key)] = strings.TrimSpace(v)
+		}
+		if len(values) > 0 {
+			*target = values
+		}
+
+	case *map[string]Dataset:
+		if value == "" {
+			return nil
+		}
+		datasets := map[string]Dataset{}
+		if err := json.Unmarshal([]byte(value), &datasets); err != nil {
+			return fmt.Errorf("%w: must be a JSON object of datasets: %w", ErrInvalidDataset, err)
+		}
+		*target = datasets
+
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %T", target))
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// EnvSecrets resolves ${env:NAME} to the environment variable NAME.
+	EnvSecrets = "env"
+	// FileSecrets resolves ${file:path} to the content of the file, relative
+	// paths are read from SECRETS_DIR.
+	FileSecrets = "file"
+)
+
+var (
+	ErrUnknownSecretProvider = errors.New("unknown secret provider")
+	ErrSecretNotFound        = errors.New("secret not found")
+
+	secretRef = regexp.MustCompile(`^\$\{([a-z][a-z0-9_-]*):(.+)\}$`)
+)
+
+// SecretProvider resolves the references of a scheme, so the secrets can live
+// in a vault instead of the config file or the environment.
+type SecretProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+type SecretProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretProviderFunc) Secret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// fileProvider reads secrets from files, like Docker and Kubernetes mount
+// them or a local directory kept out of the repository.
+type fileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) SecretProvider {
+	return fileProvider{dir: dir}
+}
+
+func (fp fileProvider) Secret(ctx context.Context, ref string) (string, error) {
+	path := ref
+	if !filepath.IsAbs(path) && fp.dir != "" {
+		path = filepath.Join(fp.dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+type envProvider struct {
+	lookupEnv func(string) (string, bool)
+}
+
+func (ep envProvider) Secret(ctx context.Context, ref string) (string, error) {
+	value, ok := ep.lookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, ref)
+	}
+	return value, nil
+}
+
+// resolve replaces a ${scheme:ref} value with the secret, other values are
+// returned as they are.
+func (l *loader) resolve(ctx context.Context, value string) (string, error) {
+	match := secretRef.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return value, nil
+	}
+
+	provider, ok := l.secrets[match[1]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSecretProvider, match[1])
+	}
+
+	return provider.Secret(ctx, match[2])
+}
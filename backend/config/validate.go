@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"time"
+)
+
+var (
+	ErrOutOfRange   = errors.New("out of range")
+	ErrInvalidTable = errors.New("invalid table name")
+	ErrInvalidValue = errors.New("invalid value")
+
+	tableName    = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	logLevels      = []string{"debug", "info", "warn", "error"}
+	logFormats     = []string{"json", "text"}
+	traceExporters = []string{"none", "stdout", "otlp"}
+	searchBackends = []string{"database", "memory"}
+)
+
+// validate returns every invalid value, the ones that failed to parse are
+// reported by Load.
+func (c *Config) validate() []error {
+	var errs []error
+
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check("STOCKS_TABLE", validTable(c.StocksTable))
+	check("BARS_TABLE", validTable(c.BarsTable))
+	for _, err := range c.validateDatasets() {
+		check("DATASETS", err)
+	}
+
+	check("DB_MAX_OPEN_CONNS", atLeast(c.DBMaxOpenConns, 0))
+	check("DB_MAX_IDLE_CONNS", atLeast(c.DBMaxIdleConns, 0))
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		check("DB_MAX_IDLE_CONNS", fmt.Errorf("%w: %d idle connections exceed DB_MAX_OPEN_CONNS %d", ErrOutOfRange,
+			c.DBMaxIdleConns, c.DBMaxOpenConns))
+	}
+
+	check("DB_CONN_MAX_LIFETIME", notNegative(c.DBConnMaxLifetime))
+	check("DB_CONN_MAX_IDLE_TIME", notNegative(c.DBConnMaxIdleTime))
+	check("DB_CONNECT_ATTEMPTS", atLeast(c.DBConnectAttempts, 1))
+	check("DB_CONNECT_BACKOFF", notNegative(c.DBConnectBackoff))
+
+	check("UPSTREAM_TIMEOUT", notNegative(c.UpstreamTimeout))
+	check("SERVER_READ_TIMEOUT", notNegative(c.ServerReadTimeout))
+	check("SERVER_WRITE_TIMEOUT", notNegative(c.ServerWriteTimeout))
+
+	check("INGEST_WORKERS", atLeast(c.IngestWorkers, 0))
+	check("INGEST_BATCH_SIZE", atLeast(c.IngestBatchSize, 0))
+	check("INGEST_PAGE_BUFFER", atLeast(c.IngestPageBuffer, 0))
+	check("SYNC_RETENTION_DAYS", atLeast(c.SyncRetentionDays, 0))
+	if c.SyncMaxDeletePercent < 0 || c.SyncMaxDeletePercent > 100 {
+		check("SYNC_MAX_DELETE_PERCENT", fmt.Errorf("%w: %d must be between 0 and 100", ErrOutOfRange, c.SyncMaxDeletePercent))
+	}
+
+	check("SCORING_LIMIT", atLeast(c.ScoringLimit, 0))
+	for name, weight := range c.ScoringWeights {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			check("SCORING_WEIGHTS", fmt.Errorf("%w: %s must be a finite number", ErrInvalidValue, name))
+		}
+	}
+
+	check("SEARCH_BACKEND", oneOf(c.SearchBackend, searchBackends))
+	if !currencyCode.MatchString(c.ReportingCurrency) {
+		check("REPORTING_CURRENCY", fmt.Errorf("%w: %q must be an ISO 4217 code", ErrInvalidValue, c.ReportingCurrency))
+	}
+
+	if c.QualityMaxTargetRatio < 1 {
+		check("QUALITY_MAX_TARGET_RATIO", fmt.Errorf("%w: %v must be at least 1", ErrOutOfRange, c.QualityMaxTargetRatio))
+	}
+	if c.QualityOutlierThreshold <= 0 {
+		check("QUALITY_OUTLIER_THRESHOLD", fmt.Errorf("%w: %v must be positive", ErrOutOfRange, c.QualityOutlierThreshold))
+	}
+	check("QUALITY_HISTORY_DAYS", atLeast(c.QualityHistoryDays, 1))
+
+	if c.AuthWebTokenTTL <= 0 {
+		check("AUTH_WEB_TOKEN_TTL", fmt.Errorf("%w: %s must be positive", ErrOutOfRange, c.AuthWebTokenTTL))
+	}
+
+	check("CORS_MAX_AGE", atLeast(c.CORSMaxAge, 0))
+	check("CACHE_MAX_AGE", atLeast(c.CacheMaxAge, 0))
+	check("CACHE_HISTORICAL_MAX_AGE", atLeast(c.CacheHistoricalMaxAge, 0))
+
+	check("LOG_LEVEL", oneOf(c.LogLevel, logLevels))
+	check("LOG_FORMAT", oneOf(c.LogFormat, logFormats))
+	check("TRACE_EXPORTER", oneOf(c.TraceExporter, traceExporters))
+
+	return errs
+}
+
+func validTable(name string) error {
+	if !tableName.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidTable, name)
+	}
+	return nil
+}
+
+func atLeast(value, minimum int) error {
+	if value < minimum {
+		return fmt.Errorf("%w: %d must be at least %d", ErrOutOfRange, value, minimum)
+	}
+	return nil
+}
+
+func notNegative(value time.Duration) error {
+	if value < 0 {
+		return fmt.Errorf("%w: %s must not be negative", ErrOutOfRange, value)
+	}
+	return nil
+}
+
+func oneOf(value string, allowed []string) error {
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("%w: %q must be one of %v", ErrInvalidValue, value, allowed)
+	}
+	return nil
+}
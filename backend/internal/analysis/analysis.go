@@ -3,34 +3,14 @@ package analysis
 import (
 	"sort"
 	"strings"
+	"time"
 
-	"slices"
-
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/money"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
-var (
-	topBrokerages = []string{
-		"Morgan Stanley",
-		"Barclays",
-		"JPMorgan Chase & Co.",
-		"Wells Fargo & Company",
-		"Citigroup",
-		"Scotiabank",
-		"Raymond James",
-		"Piper Sandler",
-		"The Goldman Sachs Group",
-		"Stifel Nicolaus",
-		"Truist Financial",
-		"BMO Capital Markets",
-		"UBS Group",
-		"Jefferies Financial Group",
-		"Bank of America",
-		"CIBC",
-		"Deutsche Bank Aktiengesellschaft",
-	}
-)
-
 const (
 	limitAnalysis    = 50
 	percChangeWeight = 25.0 / 100
@@ -43,12 +23,15 @@ const (
 )
 
 type Analysis struct {
-	Stocks []*models.FormattedStock
+	Stocks  []*models.FormattedStock
+	History []*models.FormattedStock
+	Profile Profile
 }
 
 type StockAnalysis struct {
 	*models.FormattedStock
-	Score float64 `json:"score"`
+	Score    float64   `json:"score"`
+	Momentum *Momentum `json:"momentum,omitempty"`
 }
 
 type StockAnalysisResponse struct {
@@ -65,31 +48,95 @@ type StockMetrics struct {
 	newestTime    int64
 }
 
+// NewAnalysis scores the latest rating of every ticker, across its sources.
 func NewAnalysis(stocks []*models.FormattedStock) *Analysis {
 	return &Analysis{
-		Stocks: stocks,
+		Stocks:  latestPerTicker(stocks),
+		Profile: DefaultProfile(),
+	}
+}
+
+// NewAnalysisWithHistory loads the rating history of every ticker, which the
+// momentum factors are computed from when the profile weights them.
+func NewAnalysisWithHistory(stocks, history []*models.FormattedStock) *Analysis {
+	return &Analysis{
+		Stocks:  latestPerTicker(stocks),
+		History: history,
+		Profile: DefaultProfile(),
+	}
+}
+
+// latestPerTicker keeps the newest rating of every ticker, the first one on a
+// tie, so a ticker rated by several sources is counted and ranked once.
+func latestPerTicker(stocks []*models.FormattedStock) []*models.FormattedStock {
+	positions := make(map[string]int, len(stocks))
+	latest := make([]*models.FormattedStock, 0, len(stocks))
+
+	for _, stock := range stocks {
+		pos, seen := positions[stock.Ticker]
+		if !seen {
+			positions[stock.Ticker] = len(latest)
+			latest = append(latest, stock)
+			continue
+		}
+
+		if stock.Time.After(latest[pos].Time) {
+			latest[pos] = stock
+		}
 	}
+
+	return latest
+}
+
+// WithProfile scores the stocks with the weights of the profile.
+func (a *Analysis) WithProfile(profile Profile) *Analysis {
+	a.Profile = profile
+	return a
 }
 
 func (a *Analysis) Analyze() *StockAnalysisResponse {
+	return a.AnalyzeTop(a.Profile.Limit)
+}
+
+// AnalyzeTop scores every stock and returns the best limit ones, none when
+// there are no stocks, such as before the first sync or in an empty dataset.
+func (a *Analysis) AnalyzeTop(limit int) *StockAnalysisResponse {
+	if len(a.Stocks) == 0 {
+		return &StockAnalysisResponse{TopStocks: []*StockAnalysis{}}
+	}
 
 	metrics := a.computeStockMetrics()
 
+	// the momentum factors are opt-in, the weights are renormalized when they
+	// are scored so the scores stay between 0 and 1
+	var momentum map[string]*Momentum
+	var momMetrics *momentumMetrics
+	if len(a.History) > 0 && a.Profile.momentumWeight() > 0 {
+		momentum = computeMomentum(a.Profile.taxonomy(), a.History, time.Unix(metrics.newestTime, 0))
+		momMetrics = computeMomentumMetrics(momentum)
+	}
+
 	var stocksAnalysis []*StockAnalysis
 	for _, stock := range a.Stocks {
 
 		score := a.calculateScore(stock, metrics)
 
+		stockMomentum := momentum[stock.Ticker]
+		if momMetrics != nil {
+			score = (score + momentumScore(stockMomentum, momMetrics, a.Profile)) / a.Profile.totalWeight()
+		}
+
 		stocksAnalysis = append(stocksAnalysis, &StockAnalysis{
 			FormattedStock: stock,
-			Score:          score})
+			Score:          score,
+			Momentum:       stockMomentum})
 	}
 
 	sort.Slice(stocksAnalysis, func(i, j int) bool {
 		return stocksAnalysis[i].Score > stocksAnalysis[j].Score
 	})
 
-	resultLimit := min(len(stocksAnalysis), limitAnalysis)
+	resultLimit := min(len(stocksAnalysis), limit)
 
 	return &StockAnalysisResponse{
 		TopStocks: stocksAnalysis[:resultLimit],
@@ -97,24 +144,26 @@ func (a *Analysis) Analyze() *StockAnalysisResponse {
 }
 
 func (a *Analysis) calculateScore(stock *models.FormattedStock, metrics *StockMetrics) float64 {
-	percChange := percentageChange(stock.TargetFrom, stock.TargetTo)
-	absChange := absoluteChange(stock.TargetFrom, stock.TargetTo)
+	percChange := percentageChange(money.Targets(stock))
+	absChange := absoluteChange(money.Targets(stock))
 	timeValue := stock.Time.Unix()
 	percChangeScore := normalizeValue(percChange, metrics.minPercChange, metrics.maxPercChange)
 	absChangeScore := normalizeValue(absChange, metrics.minAbsChange, metrics.maxAbsChange)
 	timeScore := normalizeValue(float64(timeValue), float64(metrics.oldestTime), float64(metrics.newestTime))
 	brokerageScore := a.brokerageScore(metrics.brokerageMap, stock.Brokerage)
-	ratingScore := mapRatingToFloat(stock.RatingTo)
-	ratingDiffScore := ratingDifference(stock.RatingFrom, stock.RatingTo)
-	actionValue := mapActionToFloat(stock.Action)
-
-	overallScore := (percChangeScore * percChangeWeight) +
-		(absChangeScore * absChangeWeight) +
-		(timeScore * timeWeight) +
-		(brokerageScore * brokerageWeight) +
-		(ratingScore * ratingWeight) +
-		(ratingDiffScore * ratingDiffWeight) +
-		(actionValue * actionWeight)
+	t := a.Profile.taxonomy()
+	ratingScore := mapRatingToFloat(t, stock.Source, stock.RatingTo)
+	ratingDiffScore := ratingDifference(t, stock.Source, stock.RatingFrom, stock.RatingTo)
+	actionValue := mapActionToFloat(t, stock.Source, stock.Action)
+
+	profile := a.Profile
+	overallScore := (percChangeScore * profile.PercChangeWeight) +
+		(absChangeScore * profile.AbsChangeWeight) +
+		(timeScore * profile.TimeWeight) +
+		(brokerageScore * profile.BrokerageWeight) +
+		(ratingScore * profile.RatingWeight) +
+		(ratingDiffScore * profile.RatingDiffWeight) +
+		(actionValue * profile.ActionWeight)
 
 	return overallScore
 }
@@ -123,12 +172,11 @@ func (a *Analysis) computeStockMetrics() *StockMetrics {
 	// Initialize variables
 	stock := a.Stocks[0]
 	frequency := make(map[string]int)
-	percChange := percentageChange(stock.TargetFrom, stock.TargetTo)
-	absChange := absoluteChange(stock.TargetFrom, stock.TargetTo)
+	percChange := percentageChange(money.Targets(stock))
+	absChange := absoluteChange(money.Targets(stock))
 	timeValue := stock.Time.Unix()
 
-	normalizedBrokerage := strings.TrimSpace(strings.ToLower(stock.Brokerage))
-	frequency[normalizedBrokerage]++
+	frequency[a.brokerageKey(stock.Brokerage)]++
 
 	minPerc, maxPerc := percChange, percChange
 	minAbs, maxAbs := absChange, absChange
@@ -136,12 +184,11 @@ func (a *Analysis) computeStockMetrics() *StockMetrics {
 
 	for i := 1; i < len(a.Stocks); i++ {
 		stock = a.Stocks[i]
-		percChange = percentageChange(stock.TargetFrom, stock.TargetTo)
-		absChange = absoluteChange(stock.TargetFrom, stock.TargetTo)
+		percChange = percentageChange(money.Targets(stock))
+		absChange = absoluteChange(money.Targets(stock))
 		timeValue = stock.Time.Unix()
 
-		normalizedBrokerage = strings.TrimSpace(strings.ToLower(stock.Brokerage))
-		frequency[normalizedBrokerage]++
+		frequency[a.brokerageKey(stock.Brokerage)]++
 
 		minPerc, maxPerc = setMinMax(percChange, minPerc, maxPerc)
 		minAbs, maxAbs = setMinMax(absChange, minAbs, maxAbs)
@@ -190,54 +237,45 @@ func absoluteChange(targetFrom, targetTo float64) float64 {
 	return targetTo - targetFrom
 }
 
-func isTopBrokerage(brokerage string) bool {
-	return slices.Contains(topBrokerages, brokerage)
+func isTopBrokerage(registry *brokerage.Registry, name string) bool {
+	return registry.IsTop(name)
 }
 
-func brokerageRating(brokerage string) float64 {
-	if isTopBrokerage(brokerage) {
+func brokerageRating(registry *brokerage.Registry, name string) float64 {
+	if isTopBrokerage(registry, name) {
 		return 1.0
 	}
 	return 0.75
 }
 
+// brokerageKey counts the spellings of a brokerage together, the stocks
+// stored before the registry keep the names of the feeds.
+func (a *Analysis) brokerageKey(name string) string {
+	canonical, _ := a.Profile.brokerages().Canonical(name)
+	return strings.ToLower(canonical)
+}
+
 func (a *Analysis) brokerageRelativeFrequency(brokerageFrequencyMap map[string]int, brokerage string) float64 {
-	normalizedBrokerage := strings.TrimSpace(strings.ToLower(brokerage))
-	brokFreq := brokerageFrequencyMap[normalizedBrokerage]
+	brokFreq := brokerageFrequencyMap[a.brokerageKey(brokerage)]
 	return float64(brokFreq) / float64(a.getStocksCount())
 }
 
 func (a *Analysis) brokerageScore(brokerageFrequencyMap map[string]int, brokerage string) float64 {
-	bRating := brokerageRating(brokerage)
+	bRating := brokerageRating(a.Profile.brokerages(), brokerage)
 	bRelFreq := a.brokerageRelativeFrequency(brokerageFrequencyMap, brokerage)
 	return bRating * bRelFreq
 }
 
-func mapRatingToFloat(rating string) float64 {
-	switch rating {
-	case "buy":
-		return 1
-
-	case "outperform":
-		return 0.75
-
-	case "hold":
-		return 0.5
-
-	case "underperform":
-		return 0.25
-
-	case "sell":
-		return 0
-
-	default:
-		return 0.5
-	}
+// mapRatingToFloat scores the category of the rating, the stocks stored
+// before the taxonomy keep the labels of the feeds.
+func mapRatingToFloat(t *taxonomy.Taxonomy, source, rating string) float64 {
+	category, _ := t.Rating(source, rating)
+	return category.Value
 }
 
-func ratingDifference(ratingFrom, ratingTo string) float64 {
-	ratingFromValue := mapRatingToFloat(ratingFrom)
-	ratingToValue := mapRatingToFloat(ratingTo)
+func ratingDifference(t *taxonomy.Taxonomy, source, ratingFrom, ratingTo string) float64 {
+	ratingFromValue := mapRatingToFloat(t, source, ratingFrom)
+	ratingToValue := mapRatingToFloat(t, source, ratingTo)
 
 	ratingDiff := ratingToValue - ratingFromValue
 	if ratingDiff < 0 {
@@ -247,24 +285,7 @@ func ratingDifference(ratingFrom, ratingTo string) float64 {
 	return ratingDiff
 }
 
-func mapActionToFloat(action string) float64 {
-	switch action {
-	case "upgraded by":
-		return 1
-
-	case "target raised by":
-		return 0.75
-
-	case "initiated by", "reiterated by", "target set by":
-		return 0.5
-
-	case "target lowered by":
-		return 0.25
-
-	case "downgraded by":
-		return 0
-
-	default:
-		return 0.5
-	}
+func mapActionToFloat(t *taxonomy.Taxonomy, source, action string) float64 {
+	category, _ := t.Action(source, action)
+	return category.Value
 }
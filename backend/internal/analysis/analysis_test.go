@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
@@ -112,12 +114,15 @@ func TestIsTopBrokerage(t *testing.T) {
 		want      bool
 	}{
 		{"Top brokerage", "JPMorgan Chase & Co.", true},
+		{"Top brokerage alias", "JP Morgan", true},
+		{"Top brokerage without suffix", "Goldman Sachs", true},
+		{"Top brokerage misspelled", "Morgan Stanly", true},
 		{"Not top brokerage", "Small Firm Inc.", false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isTopBrokerage(tt.brokerage); got != tt.want {
+			if got := isTopBrokerage(brokerage.Default(), tt.brokerage); got != tt.want {
 				t.Errorf("isTopBrokerage(%v) = %v, want %v", tt.brokerage, got, tt.want)
 			}
 		})
@@ -135,12 +140,14 @@ func TestMapRatingToFloat(t *testing.T) {
 		{"Hold rating", "hold", 0.5},
 		{"Underperform rating", "underperform", 0.25},
 		{"Sell rating", "sell", 0},
+		{"Label of a category", "Accumulate", 0.75},
+		{"Label stored before the taxonomy", "strong-buy", 1},
 		{"Unknown rating", "unknown", 0.5},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := mapRatingToFloat(tt.rating); got != tt.want {
+			if got := mapRatingToFloat(taxonomy.Default(), "", tt.rating); got != tt.want {
 				t.Errorf("mapRatingToFloat(%v) = %v, want %v", tt.rating, got, tt.want)
 			}
 		})
@@ -158,12 +165,13 @@ func TestMapActionToFloat(t *testing.T) {
 		{"Initiated action", "initiated by", 0.5},
 		{"Target lowered action", "target lowered by", 0.25},
 		{"Downgraded action", "downgraded by", 0},
+		{"Label of a category", "upgraded", 1},
 		{"Unknown action", "unknown", 0.5},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := mapActionToFloat(tt.action); got != tt.want {
+			if got := mapActionToFloat(taxonomy.Default(), "", tt.action); got != tt.want {
 				t.Errorf("mapActionToFloat(%v) = %v, want %v", tt.action, got, tt.want)
 			}
 		})
@@ -184,7 +192,7 @@ func TestRatingDifference(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ratingDifference(tt.ratingFrom, tt.ratingTo); got != tt.want {
+			if got := ratingDifference(taxonomy.Default(), "", tt.ratingFrom, tt.ratingTo); got != tt.want {
 				t.Errorf("ratingDifference(%v, %v) = %v, want %v", tt.ratingFrom, tt.ratingTo, got, tt.want)
 			}
 		})
@@ -197,8 +205,8 @@ func TestAnalyze(t *testing.T) {
 	stocks := []*models.FormattedStock{
 		{
 			Ticker:     "AAPL",
-			TargetFrom: 100,
-			TargetTo:   120,
+			TargetFrom: "100",
+			TargetTo:   "120",
 			Company:    "Apple Inc.",
 			Action:     "upgraded by",
 			Brokerage:  "JPMorgan Chase & Co.",
@@ -208,8 +216,8 @@ func TestAnalyze(t *testing.T) {
 		},
 		{
 			Ticker:     "MSFT",
-			TargetFrom: 200,
-			TargetTo:   180,
+			TargetFrom: "200",
+			TargetTo:   "180",
 			Company:    "Microsoft Corp.",
 			Action:     "downgraded by",
 			Brokerage:  "Citigroup",
@@ -219,8 +227,8 @@ func TestAnalyze(t *testing.T) {
 		},
 		{
 			Ticker:     "GOOG",
-			TargetFrom: 150,
-			TargetTo:   150,
+			TargetFrom: "150",
+			TargetTo:   "150",
 			Company:    "Alphabet Inc.",
 			Action:     "reiterated by",
 			Brokerage:  "Small Firm Inc.",
@@ -252,9 +260,9 @@ func TestAnalyze(t *testing.T) {
 
 func TestBrokerageScore(t *testing.T) {
 	stocks := []*models.FormattedStock{
-		{Brokerage: "JPMorgan Chase & Co."},
-		{Brokerage: "JPMorgan Chase & Co."},
-		{Brokerage: "Small Firm Inc."},
+		{Ticker: "T1", Brokerage: "JPMorgan Chase & Co."},
+		{Ticker: "T2", Brokerage: "JPMorgan Chase & Co."},
+		{Ticker: "T3", Brokerage: "Small Firm Inc."},
 	}
 
 	analysis := NewAnalysis(stocks)
@@ -279,24 +287,68 @@ func TestBrokerageScore(t *testing.T) {
 	}
 }
 
+func TestBrokerageFrequencyAcrossSpellings(t *testing.T) {
+	stocks := []*models.FormattedStock{
+		{Ticker: "T1", Brokerage: "JPMorgan Chase & Co."},
+		{Ticker: "T2", Brokerage: "JP Morgan"},
+		{Ticker: "T3", Brokerage: "J.P. Morgan"},
+		{Ticker: "T4", Brokerage: "Small Firm Inc."},
+	}
+
+	analysis := NewAnalysis(stocks)
+	metrics := analysis.computeStockMetrics()
+
+	if got := metrics.brokerageMap["jpmorgan chase & co."]; got != 3 {
+		t.Errorf("brokerageMap['jpmorgan chase & co.'] = %v, want 3", got)
+	}
+
+	score := analysis.brokerageScore(metrics.brokerageMap, "JP Morgan")
+	if want := 1.0 * (3.0 / 4.0); score != want {
+		t.Errorf("brokerageScore for alias = %v, want %v", score, want)
+	}
+}
+
+func TestAnalyzeTickerOfSeveralSources(t *testing.T) {
+	now := time.Now()
+	stocks := []*models.FormattedStock{
+		{Ticker: "AAPL", Source: "api", TargetFrom: "100", TargetTo: "110", Brokerage: "Barclays", Time: now.Add(-24 * time.Hour)},
+		{Ticker: "MSFT", Source: "api", TargetFrom: "100", TargetTo: "105", Brokerage: "Barclays", Time: now},
+		{Ticker: "AAPL", Source: "csv", TargetFrom: "100", TargetTo: "120", Brokerage: "Citigroup", Time: now},
+	}
+
+	result := NewAnalysis(stocks).Analyze()
+	if len(result.TopStocks) != 2 {
+		t.Fatalf("Expected every ticker ranked once, got %d stocks", len(result.TopStocks))
+	}
+
+	for _, stock := range result.TopStocks {
+		if stock.Ticker == "AAPL" && stock.Source != "csv" {
+			t.Errorf("Expected the newest rating of AAPL, got the one of %s", stock.Source)
+		}
+	}
+}
+
 func TestComputeStockMetrics(t *testing.T) {
 	now := time.Now()
 	stocks := []*models.FormattedStock{
 		{
-			TargetFrom: 100,
-			TargetTo:   120,
+			Ticker:     "T1",
+			TargetFrom: "100",
+			TargetTo:   "120",
 			Brokerage:  "JPMorgan Chase & Co.",
 			Time:       now,
 		},
 		{
-			TargetFrom: 200,
-			TargetTo:   180,
+			Ticker:     "T2",
+			TargetFrom: "200",
+			TargetTo:   "180",
 			Brokerage:  "Citigroup",
 			Time:       now.Add(-24 * time.Hour),
 		},
 		{
-			TargetFrom: 150,
-			TargetTo:   150,
+			Ticker:     "T3",
+			TargetFrom: "150",
+			TargetTo:   "150",
 			Brokerage:  "Small Firm Inc.",
 			Time:       now.Add(-48 * time.Hour),
 		},
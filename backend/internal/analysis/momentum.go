@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/money"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	day = 24 * time.Hour
+
+	netRevisions7dWeight  = 5.0 / 100
+	netRevisions30dWeight = 5.0 / 100
+	netRevisions90dWeight = 5.0 / 100
+	raiseStreakWeight     = 5.0 / 100
+	revisionSpeedWeight   = 10.0 / 100
+
+	revisionSpeedWindow = 30 * day
+
+	// MomentumLookback is how much rating history the momentum factors need:
+	// the widest net revisions window plus the consensus speed window.
+	MomentumLookback = 90*day + revisionSpeedWindow
+)
+
+type Momentum struct {
+	NetRevisions7d    int     `json:"net_revisions_7d"`
+	NetRevisions30d   int     `json:"net_revisions_30d"`
+	NetRevisions90d   int     `json:"net_revisions_90d"`
+	TargetRaiseStreak int     `json:"target_raise_streak"`
+	RevisionSpeed     float64 `json:"revision_speed"`
+}
+
+type momentumMetrics struct {
+	min7d, max7d         float64
+	min30d, max30d       float64
+	min90d, max90d       float64
+	minStreak, maxStreak float64
+	minSpeed, maxSpeed   float64
+}
+
+// computeMomentum groups the rating history by ticker and derives the momentum
+// features of each one as of the given reference time.
+func computeMomentum(t *taxonomy.Taxonomy, history []*models.FormattedStock, asOf time.Time) map[string]*Momentum {
+	byTicker := make(map[string][]*models.FormattedStock)
+	for _, event := range history {
+		if event.Time.After(asOf) {
+			continue
+		}
+		byTicker[event.Ticker] = append(byTicker[event.Ticker], event)
+	}
+
+	momentum := make(map[string]*Momentum, len(byTicker))
+	for ticker, events := range byTicker {
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Time.After(events[j].Time)
+		})
+
+		momentum[ticker] = &Momentum{
+			NetRevisions7d:    netRevisions(t, events, asOf.Add(-7*day)),
+			NetRevisions30d:   netRevisions(t, events, asOf.Add(-30*day)),
+			NetRevisions90d:   netRevisions(t, events, asOf.Add(-90*day)),
+			TargetRaiseStreak: targetRaiseStreak(events),
+			RevisionSpeed:     revisionSpeed(events, asOf),
+		}
+	}
+
+	return momentum
+}
+
+// netRevisions returns upgrades minus downgrades for the events newer than since.
+// Events must be sorted from newest to oldest.
+func netRevisions(t *taxonomy.Taxonomy, events []*models.FormattedStock, since time.Time) int {
+	net := 0
+	for _, event := range events {
+		if event.Time.Before(since) {
+			break
+		}
+		net += revisionDirection(t, event)
+	}
+	return net
+}
+
+func revisionDirection(t *taxonomy.Taxonomy, event *models.FormattedStock) int {
+	action, _ := t.Action(event.Source, event.Action)
+	switch action.Name {
+	case taxonomy.ActionUpgrade:
+		return 1
+	case taxonomy.ActionDowngrade:
+		return -1
+	}
+
+	diff := mapRatingToFloat(t, event.Source, event.RatingTo) - mapRatingToFloat(t, event.Source, event.RatingFrom)
+	switch {
+	case diff > 0:
+		return 1
+	case diff < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// targetRaiseStreak counts the consecutive most recent events that raised the
+// price target. Events must be sorted from newest to oldest.
+func targetRaiseStreak(events []*models.FormattedStock) int {
+	streak := 0
+	for _, event := range events {
+		if from, to := money.Targets(event); to <= from {
+			break
+		}
+		streak++
+	}
+	return streak
+}
+
+// revisionSpeed is the daily percentage change of the consensus target over the
+// last revisionSpeedWindow. The consensus is the mean of the latest target of
+// every brokerage covering the ticker.
+func revisionSpeed(events []*models.FormattedStock, asOf time.Time) float64 {
+	current, ok := consensusTarget(events, asOf)
+	if !ok {
+		return 0
+	}
+
+	previous, ok := consensusTarget(events, asOf.Add(-revisionSpeedWindow))
+	if !ok || previous == 0 {
+		return 0
+	}
+
+	days := revisionSpeedWindow.Hours() / 24
+	return percentageChange(previous, current) / days
+}
+
+// consensusTarget expects events sorted from newest to oldest.
+func consensusTarget(events []*models.FormattedStock, asOf time.Time) (float64, bool) {
+	latest := make(map[string]float64)
+	for _, event := range events {
+		if event.Time.After(asOf) {
+			continue
+		}
+		if _, seen := latest[event.Brokerage]; !seen {
+			_, latest[event.Brokerage] = money.Targets(event)
+		}
+	}
+
+	if len(latest) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, target := range latest {
+		total += target
+	}
+
+	return total / float64(len(latest)), true
+}
+
+func computeMomentumMetrics(momentum map[string]*Momentum) *momentumMetrics {
+	metrics := &momentumMetrics{}
+	first := true
+
+	for _, m := range momentum {
+		v7, v30, v90 := float64(m.NetRevisions7d), float64(m.NetRevisions30d), float64(m.NetRevisions90d)
+		streak := float64(m.TargetRaiseStreak)
+
+		if first {
+			metrics.min7d, metrics.max7d = v7, v7
+			metrics.min30d, metrics.max30d = v30, v30
+			metrics.min90d, metrics.max90d = v90, v90
+			metrics.minStreak, metrics.maxStreak = streak, streak
+			metrics.minSpeed, metrics.maxSpeed = m.RevisionSpeed, m.RevisionSpeed
+			first = false
+			continue
+		}
+
+		metrics.min7d, metrics.max7d = setMinMax(v7, metrics.min7d, metrics.max7d)
+		metrics.min30d, metrics.max30d = setMinMax(v30, metrics.min30d, metrics.max30d)
+		metrics.min90d, metrics.max90d = setMinMax(v90, metrics.min90d, metrics.max90d)
+		metrics.minStreak, metrics.maxStreak = setMinMax(streak, metrics.minStreak, metrics.maxStreak)
+		metrics.minSpeed, metrics.maxSpeed = setMinMax(m.RevisionSpeed, metrics.minSpeed, metrics.maxSpeed)
+	}
+
+	return metrics
+}
+
+// momentumScore returns 0 for tickers without history so that they are not
+// rewarded over tickers with a poor revision trend.
+func momentumScore(m *Momentum, metrics *momentumMetrics, profile Profile) float64 {
+	if m == nil {
+		return 0
+	}
+
+	return (normalizeValue(float64(m.NetRevisions7d), metrics.min7d, metrics.max7d) * profile.NetRevisions7dWeight) +
+		(normalizeValue(float64(m.NetRevisions30d), metrics.min30d, metrics.max30d) * profile.NetRevisions30dWeight) +
+		(normalizeValue(float64(m.NetRevisions90d), metrics.min90d, metrics.max90d) * profile.NetRevisions90dWeight) +
+		(normalizeValue(float64(m.TargetRaiseStreak), metrics.minStreak, metrics.maxStreak) * profile.RaiseStreakWeight) +
+		(normalizeValue(m.RevisionSpeed, metrics.minSpeed, metrics.maxSpeed) * profile.RevisionSpeedWeight)
+}
@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func TestNetRevisions(t *testing.T) {
+	now := time.Now()
+	events := []*models.FormattedStock{
+		{Action: "upgraded by", Time: now.Add(-1 * day)},
+		{Action: "target raised by", RatingFrom: "hold", RatingTo: "buy", Time: now.Add(-3 * day)},
+		{Action: "downgraded by", Time: now.Add(-10 * day)},
+		{Action: "reiterated by", RatingFrom: "buy", RatingTo: "buy", Time: now.Add(-20 * day)},
+		{Action: "upgraded by", Time: now.Add(-60 * day)},
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  int
+	}{
+		{"7 days", now.Add(-7 * day), 2},
+		{"30 days", now.Add(-30 * day), 1},
+		{"90 days", now.Add(-90 * day), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netRevisions(taxonomy.Default(), events, tt.since); got != tt.want {
+				t.Errorf("netRevisions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetRaiseStreak(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*models.FormattedStock
+		want   int
+	}{
+		{
+			name: "Consecutive raises",
+			events: []*models.FormattedStock{
+				{TargetFrom: "110", TargetTo: "120"},
+				{TargetFrom: "100", TargetTo: "110"},
+				{TargetFrom: "120", TargetTo: "100"},
+				{TargetFrom: "90", TargetTo: "120"},
+			},
+			want: 2,
+		},
+		{
+			name: "Latest is a cut",
+			events: []*models.FormattedStock{
+				{TargetFrom: "120", TargetTo: "110"},
+				{TargetFrom: "100", TargetTo: "120"},
+			},
+			want: 0,
+		},
+		{
+			name:   "No events",
+			events: []*models.FormattedStock{},
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetRaiseStreak(tt.events); got != tt.want {
+				t.Errorf("targetRaiseStreak() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevisionSpeed(t *testing.T) {
+	now := time.Now()
+	events := []*models.FormattedStock{
+		{Brokerage: "Barclays", TargetTo: "130", Time: now.Add(-2 * day)},
+		{Brokerage: "Citigroup", TargetTo: "130", Time: now.Add(-5 * day)},
+		{Brokerage: "Barclays", TargetTo: "100", Time: now.Add(-40 * day)},
+		{Brokerage: "Citigroup", TargetTo: "100", Time: now.Add(-50 * day)},
+	}
+
+	want := 30.0 / 30 // 30% consensus change over 30 days
+	if got := revisionSpeed(events, now); got != want {
+		t.Errorf("revisionSpeed() = %v, want %v", got, want)
+	}
+
+	if got := revisionSpeed(events[:2], now); got != 0 {
+		t.Errorf("revisionSpeed() without previous consensus = %v, want 0", got)
+	}
+}
+
+func TestComputeMomentum(t *testing.T) {
+	now := time.Now()
+	history := []*models.FormattedStock{
+		{Ticker: "AAPL", Action: "upgraded by", Brokerage: "Barclays", TargetFrom: "100", TargetTo: "120", Time: now.Add(-1 * day)},
+		{Ticker: "AAPL", Action: "target raised by", Brokerage: "Citigroup", TargetFrom: "90", TargetTo: "100", Time: now.Add(-10 * day)},
+		{Ticker: "MSFT", Action: "downgraded by", Brokerage: "Barclays", TargetFrom: "200", TargetTo: "180", Time: now.Add(-2 * day)},
+		{Ticker: "MSFT", Action: "upgraded by", Brokerage: "Barclays", TargetFrom: "150", TargetTo: "200", Time: now.Add(1 * day)},
+	}
+
+	momentum := computeMomentum(taxonomy.Default(), history, now)
+
+	aapl := momentum["AAPL"]
+	if aapl == nil {
+		t.Fatal("Expected momentum for AAPL")
+	}
+	if aapl.NetRevisions7d != 1 || aapl.NetRevisions30d != 1 {
+		t.Errorf("AAPL net revisions = (%d, %d), want (1, 1)", aapl.NetRevisions7d, aapl.NetRevisions30d)
+	}
+	if aapl.TargetRaiseStreak != 2 {
+		t.Errorf("AAPL target raise streak = %d, want 2", aapl.TargetRaiseStreak)
+	}
+
+	msft := momentum["MSFT"]
+	if msft == nil {
+		t.Fatal("Expected momentum for MSFT")
+	}
+	if msft.NetRevisions90d != -1 {
+		t.Errorf("MSFT net revisions ignoring future events = %d, want -1", msft.NetRevisions90d)
+	}
+}
+
+func TestAnalyzeWithHistory(t *testing.T) {
+	now := time.Now()
+	stocks := []*models.FormattedStock{
+		{Ticker: "AAPL", TargetFrom: "100", TargetTo: "110", Brokerage: "Barclays", RatingFrom: "hold", RatingTo: "buy", Action: "target raised by", Time: now},
+		{Ticker: "MSFT", TargetFrom: "100", TargetTo: "110", Brokerage: "Barclays", RatingFrom: "hold", RatingTo: "buy", Action: "target raised by", Time: now},
+	}
+	history := []*models.FormattedStock{
+		{Ticker: "AAPL", Action: "upgraded by", Brokerage: "Barclays", TargetFrom: "90", TargetTo: "110", Time: now.Add(-1 * day)},
+		{Ticker: "AAPL", Action: "upgraded by", Brokerage: "Citigroup", TargetFrom: "80", TargetTo: "90", Time: now.Add(-5 * day)},
+		{Ticker: "MSFT", Action: "downgraded by", Brokerage: "Barclays", TargetFrom: "120", TargetTo: "110", Time: now.Add(-1 * day)},
+	}
+
+	withoutHistory := NewAnalysis(stocks).Analyze()
+	if withoutHistory.TopStocks[0].Score != withoutHistory.TopStocks[1].Score {
+		t.Fatalf("Expected equal scores without history, got %v and %v",
+			withoutHistory.TopStocks[0].Score, withoutHistory.TopStocks[1].Score)
+	}
+
+	defaultRanking := NewAnalysisWithHistory(stocks, history).Analyze()
+	for i, stock := range defaultRanking.TopStocks {
+		if want := withoutHistory.TopStocks[i]; stock.Ticker != want.Ticker || stock.Score != want.Score || stock.Momentum != nil {
+			t.Errorf("Expected the default ranking to ignore the history, got %s %v %+v, want %s %v",
+				stock.Ticker, stock.Score, stock.Momentum, want.Ticker, want.Score)
+		}
+	}
+
+	withHistory := NewAnalysisWithHistory(stocks, history).WithProfile(DefaultProfile().WithMomentum()).Analyze()
+	if withHistory.TopStocks[0].Ticker != "AAPL" {
+		t.Errorf("Expected AAPL to rank first with positive momentum, got %s", withHistory.TopStocks[0].Ticker)
+	}
+	if withHistory.TopStocks[0].Momentum == nil {
+		t.Error("Expected momentum to be reported in the analysis")
+	}
+	for _, stock := range withHistory.TopStocks {
+		if stock.Score < 0 || stock.Score > 1 {
+			t.Errorf("Expected the renormalized score of %s between 0 and 1, got %v", stock.Ticker, stock.Score)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+)
+
+var (
+	ErrUnknownFactor = errors.New("unknown scoring factor")
+)
+
+// Profile holds the weights of the scoring factors and how many stocks
+// Analyze returns, so different scorings can be served and compared without
+// changing the code.
+type Profile struct {
+	Limit int `json:"limit"`
+
+	PercChangeWeight float64 `json:"perc_change_weight"`
+	AbsChangeWeight  float64 `json:"abs_change_weight"`
+	TimeWeight       float64 `json:"time_weight"`
+	BrokerageWeight  float64 `json:"brokerage_weight"`
+	RatingWeight     float64 `json:"rating_weight"`
+	RatingDiffWeight float64 `json:"rating_diff_weight"`
+	ActionWeight     float64 `json:"action_weight"`
+
+	// momentum factors, off by default, only scored when one of them is
+	// weighted and the rating history is loaded
+	NetRevisions7dWeight  float64 `json:"net_revisions_7d_weight"`
+	NetRevisions30dWeight float64 `json:"net_revisions_30d_weight"`
+	NetRevisions90dWeight float64 `json:"net_revisions_90d_weight"`
+	RaiseStreakWeight     float64 `json:"raise_streak_weight"`
+	RevisionSpeedWeight   float64 `json:"revision_speed_weight"`
+
+	// Brokerages tells the top brokerages and the spellings of each one, the
+	// default registry when nil.
+	Brokerages *brokerage.Registry `json:"-"`
+	// Taxonomy values the ratings and actions, the default one when nil.
+	Taxonomy *taxonomy.Taxonomy `json:"-"`
+}
+
+func DefaultProfile() Profile {
+	return Profile{
+		Limit:            limitAnalysis,
+		PercChangeWeight: percChangeWeight,
+		AbsChangeWeight:  absChangeWeight,
+		TimeWeight:       timeWeight,
+		BrokerageWeight:  brokerageWeight,
+		RatingWeight:     ratingWeight,
+		RatingDiffWeight: ratingDiffWeight,
+		ActionWeight:     actionWeight,
+	}
+}
+
+// WithMomentum returns the profile with the momentum factors weighted with
+// their suggested weights.
+func (p Profile) WithMomentum() Profile {
+	p.NetRevisions7dWeight = netRevisions7dWeight
+	p.NetRevisions30dWeight = netRevisions30dWeight
+	p.NetRevisions90dWeight = netRevisions90dWeight
+	p.RaiseStreakWeight = raiseStreakWeight
+	p.RevisionSpeedWeight = revisionSpeedWeight
+	return p
+}
+
+// momentumWeight is the sum of the weights of the momentum factors.
+func (p Profile) momentumWeight() float64 {
+	return p.NetRevisions7dWeight + p.NetRevisions30dWeight + p.NetRevisions90dWeight + p.RaiseStreakWeight +
+		p.RevisionSpeedWeight
+}
+
+// totalWeight is the sum of the weights of every factor.
+func (p Profile) totalWeight() float64 {
+	return p.PercChangeWeight + p.AbsChangeWeight + p.TimeWeight + p.BrokerageWeight + p.RatingWeight +
+		p.RatingDiffWeight + p.ActionWeight + p.momentumWeight()
+}
+
+func (p Profile) brokerages() *brokerage.Registry {
+	if p.Brokerages == nil {
+		return brokerage.Default()
+	}
+	return p.Brokerages
+}
+
+func (p Profile) taxonomy() *taxonomy.Taxonomy {
+	if p.Taxonomy == nil {
+		return taxonomy.Default()
+	}
+	return p.Taxonomy
+}
+
+// Override returns the profile with the limit, unless zero, and the weights of
+// the named factors replaced. The factors are named like the json fields
+// without the _weight suffix, such as perc_change or net_revisions_30d.
+func (p Profile) Override(limit int, weights map[string]float64) (Profile, error) {
+	if limit > 0 {
+		p.Limit = limit
+	}
+
+	factors := p.factors()
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(weights)) {
+		factor, ok := factors[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownFactor, name))
+			continue
+		}
+		*factor = weights[name]
+	}
+
+	return p, errors.Join(errs...)
+}
+
+func (p *Profile) factors() map[string]*float64 {
+	return map[string]*float64{
+		"perc_change":       &p.PercChangeWeight,
+		"abs_change":        &p.AbsChangeWeight,
+		"time":              &p.TimeWeight,
+		"brokerage":         &p.BrokerageWeight,
+		"rating":            &p.RatingWeight,
+		"rating_diff":       &p.RatingDiffWeight,
+		"action":            &p.ActionWeight,
+		"net_revisions_7d":  &p.NetRevisions7dWeight,
+		"net_revisions_30d": &p.NetRevisions30dWeight,
+		"net_revisions_90d": &p.NetRevisions90dWeight,
+		"raise_streak":      &p.RaiseStreakWeight,
+		"revision_speed":    &p.RevisionSpeedWeight,
+	}
+}
@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProfileOverride(t *testing.T) {
+	defaults := DefaultProfile()
+
+	tests := []struct {
+		name      string
+		limit     int
+		weights   map[string]float64
+		wantLimit int
+		wantPerc  float64
+		wantErr   error
+	}{
+		{"No overrides", 0, nil, defaults.Limit, defaults.PercChangeWeight, nil},
+		{"Limit and weight", 10, map[string]float64{"perc_change": 2.5}, 10, 2.5, nil},
+		{"Unknown factor", 0, map[string]float64{"perc": 1}, defaults.Limit, defaults.PercChangeWeight, ErrUnknownFactor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := defaults.Override(tt.limit, tt.weights)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Override error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got.Limit != tt.wantLimit || got.PercChangeWeight != tt.wantPerc {
+				t.Errorf("Override = limit %d, perc_change %v, want %d, %v", got.Limit, got.PercChangeWeight, tt.wantLimit, tt.wantPerc)
+			}
+		})
+	}
+
+	if DefaultProfile() != defaults {
+		t.Error("Override modified the original profile")
+	}
+}
@@ -1,5 +1,7 @@
 package analysis
 
+import "github.com/CorreaJose13/StockAPI/internal/money"
+
 type StockSummary struct {
 	TotalStocks    int `json:"total_stocks"`
 	PositiveChange int `json:"positive_change"`
@@ -26,7 +28,7 @@ func (a *Analysis) countStocksByChangeTrend() (countPositive, countNegative, cou
 	countNegative = 0
 	countNeutral = 0
 	for _, stock := range a.Stocks {
-		change := percentageChange(stock.TargetFrom, stock.TargetTo)
+		change := percentageChange(money.Targets(stock))
 		if change > 0 {
 			countPositive++
 		}
@@ -9,11 +9,11 @@ import (
 func TestGetSummary(t *testing.T) {
 	analysis := &Analysis{
 		Stocks: []*models.FormattedStock{
-			{Ticker: "AAPL", TargetFrom: 100.0, TargetTo: 110.0}, // Positivo
-			{Ticker: "GOOG", TargetFrom: 200.0, TargetTo: 180.0}, // Negativo
-			{Ticker: "MSFT", TargetFrom: 150.0, TargetTo: 150.0}, // Sin cambio
-			{Ticker: "AMZN", TargetFrom: 300.0, TargetTo: 320.0}, // Positivo
-			{Ticker: "META", TargetFrom: 250.0, TargetTo: 225.0}, // Negativo
+			{Ticker: "AAPL", TargetFrom: "100.0", TargetTo: "110.0"}, // Positivo
+			{Ticker: "GOOG", TargetFrom: "200.0", TargetTo: "180.0"}, // Negativo
+			{Ticker: "MSFT", TargetFrom: "150.0", TargetTo: "150.0"}, // Sin cambio
+			{Ticker: "AMZN", TargetFrom: "300.0", TargetTo: "320.0"}, // Positivo
+			{Ticker: "META", TargetFrom: "250.0", TargetTo: "225.0"}, // Negativo
 		},
 	}
 
@@ -94,8 +94,8 @@ func TestCountStocksByChangeTrend(t *testing.T) {
 		{
 			name: "Only positive changes",
 			stocks: []*models.FormattedStock{
-				{TargetFrom: 100, TargetTo: 110},
-				{TargetFrom: 200, TargetTo: 250},
+				{TargetFrom: "100", TargetTo: "110"},
+				{TargetFrom: "200", TargetTo: "250"},
 			},
 			wantPositive: 2,
 			wantNegative: 0,
@@ -104,8 +104,8 @@ func TestCountStocksByChangeTrend(t *testing.T) {
 		{
 			name: "Only negative changes",
 			stocks: []*models.FormattedStock{
-				{TargetFrom: 100, TargetTo: 90},
-				{TargetFrom: 200, TargetTo: 150},
+				{TargetFrom: "100", TargetTo: "90"},
+				{TargetFrom: "200", TargetTo: "150"},
 			},
 			wantPositive: 0,
 			wantNegative: 2,
@@ -114,8 +114,8 @@ func TestCountStocksByChangeTrend(t *testing.T) {
 		{
 			name: "Only neutral changes",
 			stocks: []*models.FormattedStock{
-				{TargetFrom: 100, TargetTo: 100},
-				{TargetFrom: 200, TargetTo: 200},
+				{TargetFrom: "100", TargetTo: "100"},
+				{TargetFrom: "200", TargetTo: "200"},
 			},
 			wantPositive: 0,
 			wantNegative: 0,
@@ -124,10 +124,10 @@ func TestCountStocksByChangeTrend(t *testing.T) {
 		{
 			name: "Mixed changes",
 			stocks: []*models.FormattedStock{
-				{TargetFrom: 100, TargetTo: 120},
-				{TargetFrom: 200, TargetTo: 180},
-				{TargetFrom: 300, TargetTo: 300},
-				{TargetFrom: 400, TargetTo: 450},
+				{TargetFrom: "100", TargetTo: "120"},
+				{TargetFrom: "200", TargetTo: "180"},
+				{TargetFrom: "300", TargetTo: "300"},
+				{TargetFrom: "400", TargetTo: "450"},
 			},
 			wantPositive: 2,
 			wantNegative: 1,
@@ -1,15 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
+// apiConsumer is the ratings source for the original upstream API, which pages
+// its items with a next_page cursor and authenticates with a bearer token.
 type apiConsumer struct {
 	client    *http.Client
 	apiURL    string
@@ -18,14 +22,23 @@ type apiConsumer struct {
 
 func NewAPIConsumer(cfg *config.Config) *apiConsumer {
 	return &apiConsumer{
-		client:    &http.Client{},
+		client:    telemetry.NewHTTPClient(cfg.UpstreamTimeout),
 		apiURL:    cfg.APIURL,
 		authToken: "Bearer " + cfg.BearerToken,
 	}
 }
 
-func (ac *apiConsumer) FetchStocks() ([]models.Stock, error) {
-	var stocks []models.Stock
+func (ac *apiConsumer) Name() string {
+	return APISource
+}
+
+func (ac *apiConsumer) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	return collectPages(ctx, ac)
+}
+
+// StreamPages follows the next_page cursor, so pages can only be fetched one
+// after another, but each one is handed over as soon as it arrives.
+func (ac *apiConsumer) StreamPages(ctx context.Context, pages chan<- []models.Stock) error {
 	nextPage := ""
 
 	for {
@@ -34,26 +47,26 @@ func (ac *apiConsumer) FetchStocks() ([]models.Stock, error) {
 			url += "?next_page=" + nextPage
 		}
 
-		body, err := ac.doRequest(url)
+		body, err := ac.doRequest(ctx, url)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching stocks: %w", err)
+			return fmt.Errorf("error fetching stocks: %w", err)
 		}
 
-		stocks = append(stocks, body.Items...)
+		if err := sendPage(ctx, pages, stampSource(body.Items, APISource)); err != nil {
+			return err
+		}
 
 		if body.NextPage == "" {
-			break
+			return nil
 		}
 
 		nextPage = body.NextPage
 	}
-
-	return stocks, nil
 }
 
-func (ac *apiConsumer) doRequest(url string) (*models.Response, error) {
+func (ac *apiConsumer) doRequest(ctx context.Context, url string) (*models.Response, error) {
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -66,7 +67,7 @@ func TestDoRequest(t *testing.T) {
 	}
 	consumer := NewAPIConsumer(cfg)
 
-	response, err := consumer.doRequest(server.URL)
+	response, err := consumer.doRequest(context.Background(), server.URL)
 	if err != nil {
 		t.Fatalf("doRequest returned unexpected error: %v", err)
 	}
@@ -154,7 +155,7 @@ func TestFetchStocks(t *testing.T) {
 	}
 	consumer := NewAPIConsumer(cfg)
 
-	stocks, err := consumer.FetchStocks()
+	stocks, err := consumer.FetchStocks(context.Background())
 	if err != nil {
 		t.Fatalf("FetchStocks returned unexpected error: %v", err)
 	}
@@ -178,7 +179,7 @@ func TestErrorHandling(t *testing.T) {
 	}
 	consumer := NewAPIConsumer(cfg)
 
-	_, err := consumer.FetchStocks()
+	_, err := consumer.FetchStocks(context.Background())
 	if err == nil {
 		t.Error("Expected error for unauthorized request, got nil")
 	}
@@ -198,7 +199,7 @@ func TestInvalidJSON(t *testing.T) {
 	}
 	consumer := NewAPIConsumer(cfg)
 
-	_, err := consumer.FetchStocks()
+	_, err := consumer.FetchStocks(context.Background())
 	if err == nil {
 		t.Error("Expected error for invalid JSON, got nil")
 	}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported ratings file format")
+)
+
+// fileSource reads ratings from a local .json or .csv file. JSON files hold
+// either an array of items or the upstream {"items": [...]} shape, CSV files
+// a header row with the same field names.
+type fileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *fileSource {
+	return &fileSource{
+		path: path,
+	}
+}
+
+func (fs *fileSource) Name() string {
+	return FileSource
+}
+
+func (fs *fileSource) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	file, err := os.Open(fs.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ratings file: %w", err)
+	}
+
+	defer file.Close()
+
+	var stocks []models.Stock
+	switch strings.ToLower(filepath.Ext(fs.path)) {
+	case ".json":
+		stocks, err = parseJSONStocks(file)
+	case ".csv":
+		stocks, err = parseCSVStocks(file)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, fs.path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stampSource(stocks, FileSource), nil
+}
+
+func parseJSONStocks(r io.Reader) ([]models.Stock, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ratings file: %w", err)
+	}
+
+	var stocks []models.Stock
+	if err := json.Unmarshal(body, &stocks); err == nil {
+		return stocks, nil
+	}
+
+	var response models.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+
+	return response.Items, nil
+}
+
+func parseCSVStocks(r io.Reader) ([]models.Stock, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var stocks []models.Stock
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv record: %w", err)
+		}
+
+		fields := make(map[string]string, len(header))
+		for i, column := range header {
+			fields[column] = record[i]
+		}
+
+		stocks = append(stocks, stockFromFields(fields))
+	}
+
+	return stocks, nil
+}
+
+func stockFromFields(fields map[string]string) models.Stock {
+	return models.Stock{
+		Ticker:     fields["ticker"],
+		TargetFrom: fields["target_from"],
+		TargetTo:   fields["target_to"],
+		Company:    fields["company"],
+		Action:     fields["action"],
+		Brokerage:  fields["brokerage"],
+		RatingFrom: fields["rating_from"],
+		RatingTo:   fields["rating_to"],
+		Time:       fields["time"],
+		Source:     fields["source"],
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	challengeHeaders = map[string]string{
+		"WWW-Authenticate": `Bearer realm="StockAPI"`,
+	}
+)
+
+// Authenticated requires the caller to hold the scope of the endpoint and to
+// be within its limits before running the handler. The rate limit headers are
+// added to every response. A nil authenticator, left by a failed setup,
+// refuses every request.
+func Authenticated(authenticator *auth.Authenticator, endpoint *spec.Endpoint, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if authenticator == nil {
+			return response.ErrorContext(ctx, auth.ErrNotConfigured)
+		}
+
+		principal, err := authenticator.Authenticate(ctx, req.Headers)
+		if err != nil {
+			resp, respErr := response.ErrorContext(ctx, err)
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				resp = response.WithHeaders(resp, challengeHeaders)
+			}
+			return resp, respErr
+		}
+
+		decision, err := authenticator.Authorize(ctx, principal, endpoint.Scope)
+		if err != nil {
+			resp, respErr := response.ErrorContext(ctx, err)
+			return response.WithHeaders(resp, decision.Headers()), respErr
+		}
+
+		resp, err := handler(ctx, req)
+
+		return response.WithHeaders(resp, decision.Headers()), err
+	}
+}
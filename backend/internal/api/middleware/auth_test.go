@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type fakeStore struct {
+	keys  map[string]*models.APIKey
+	usage map[string]*models.Usage
+}
+
+func (s *fakeStore) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, db.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *fakeStore) UpdateUsage(ctx context.Context, clientID string, fn func(*models.Usage)) error {
+	usage, ok := s.usage[clientID]
+	if !ok {
+		usage = &models.Usage{ClientID: clientID}
+		s.usage[clientID] = usage
+	}
+	fn(usage)
+	return nil
+}
+
+func TestAuthenticated(t *testing.T) {
+	apiKey, key, err := auth.NewAPIKey("dashboard", []string{auth.ScopeStocks}, models.RateLimits{RatePerMinute: 1, Burst: 1, DailyQuota: 10})
+	if err != nil {
+		t.Fatalf("NewAPIKey returned unexpected error: %v", err)
+	}
+
+	store := &fakeStore{keys: map[string]*models.APIKey{apiKey.Hash: apiKey}, usage: map[string]*models.Usage{}}
+	authenticator := auth.New(store, auth.Config{})
+
+	ok := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return response.Success(map[string]string{})
+	}
+
+	tests := []struct {
+		name          string
+		authenticator *auth.Authenticator
+		endpoint      *spec.Endpoint
+		headers       map[string]string
+		wantStatus    int
+		wantHeader    string
+	}{
+		{"Missing credentials", authenticator, spec.Stocks, nil, http.StatusUnauthorized, "WWW-Authenticate"},
+		{"Missing scope", authenticator, spec.Chart, map[string]string{"X-API-Key": key}, http.StatusForbidden, ""},
+		{"Allowed", authenticator, spec.Stocks, map[string]string{"X-API-Key": key}, http.StatusOK, "X-RateLimit-Remaining"},
+		{"Rate limited", authenticator, spec.Stocks, map[string]string{"X-API-Key": key}, http.StatusTooManyRequests, "Retry-After"},
+		{"Not configured", nil, spec.Stocks, map[string]string{"X-API-Key": key}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Authenticated(tt.authenticator, tt.endpoint, ok)
+
+			resp, err := handler(context.Background(), events.APIGatewayProxyRequest{Headers: tt.headers})
+			if err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, resp.Body)
+			}
+
+			if _, found := resp.Headers[tt.wantHeader]; tt.wantHeader != "" && !found {
+				t.Errorf("Expected the %s header, got %v", tt.wantHeader, resp.Headers)
+			}
+		})
+	}
+}
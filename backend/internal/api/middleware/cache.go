@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/functions"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	cacheControlHeader    = "Cache-Control"
+
+	asOfParam = "as_of"
+)
+
+// SyncRunStore finds the sync run the served data comes from.
+type SyncRunStore interface {
+	LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error)
+}
+
+// CachePolicy sets the validators and freshness of the responses built from a
+// stocks table, the one of the dataset of the request when resolved by
+// Datasets. The zero value disables caching.
+type CachePolicy struct {
+	store            SyncRunStore
+	tableName        string
+	maxAge           int
+	historicalMaxAge int
+	// revision changes the validators on every deploy since the same data
+	// may be rendered differently.
+	revision string
+}
+
+func NewCachePolicy(store SyncRunStore, tableName string, cfg *config.Config) CachePolicy {
+	return CachePolicy{
+		store:            store,
+		tableName:        tableName,
+		maxAge:           cfg.CacheMaxAge,
+		historicalMaxAge: cfg.CacheHistoricalMaxAge,
+		revision:         buildRevision(),
+	}
+}
+
+// Cached answers conditional requests with 304 Not Modified. The ETag is
+// derived from the latest sync run of the table, so the handler doesn't run
+// for unchanged data, or from the response body before the first recorded
+// run. Shortly after a run the handler reads the primary so the body is the
+// data of the run.
+func Cached(policy CachePolicy, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if policy.store == nil {
+			return handler(ctx, req)
+		}
+
+		tableName := policy.tableName
+		if dataset, ok := DatasetFrom(ctx); ok {
+			tableName = dataset.Table
+		}
+
+		run, err := policy.store.LatestSyncRun(ctx, tableName)
+		if err != nil && !errors.Is(err, db.ErrSyncRunNotFound) {
+			return response.ErrorContext(ctx, err)
+		}
+
+		headers := map[string]string{
+			cacheControlHeader: fmt.Sprintf("private, max-age=%d", policy.maxAgeFor(run, req)),
+		}
+
+		if run != nil {
+			headers[etagHeader] = policy.runETag(run, req)
+			headers[lastModifiedHeader] = run.FinishedAt.UTC().Format(http.TimeFormat)
+
+			if notModified(req.Headers, headers[etagHeader], run.FinishedAt) {
+				return notModifiedResponse(headers), nil
+			}
+
+			// the run is read from the primary, a follower or the replica may
+			// not have it yet and the body would be cached under its ETag
+			if db.MayLag(run.FinishedAt) {
+				ctx = db.ReadPrimary(ctx)
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+
+		if run == nil {
+			headers[etagHeader] = contentETag(resp.Body)
+
+			if notModified(req.Headers, headers[etagHeader], time.Time{}) {
+				return notModifiedResponse(headers), nil
+			}
+		}
+
+		return response.WithHeaders(resp, headers), nil
+	}
+}
+
+// maxAgeFor lets the reads of instants before the last sync be cached longer
+// since the versions they come from are never rewritten.
+func (p CachePolicy) maxAgeFor(run *models.SyncRun, req events.APIGatewayProxyRequest) int {
+	asOf, err := functions.ParseAsOf(req.QueryStringParameters[asOfParam])
+	if err != nil || asOf.IsZero() || run == nil || !asOf.Before(run.FinishedAt) {
+		return p.maxAge
+	}
+
+	return p.historicalMaxAge
+}
+
+// runETag identifies the response of the request for the data of the run.
+func (p CachePolicy) runETag(run *models.SyncRun, req events.APIGatewayProxyRequest) string {
+	keys := make([]string, 0, len(req.QueryStringParameters))
+	for key := range req.QueryStringParameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", run.ID, p.revision, req.Path)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, req.QueryStringParameters[key])
+	}
+
+	return quoteETag(hash.Sum(nil))
+}
+
+func contentETag(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return quoteETag(sum[:])
+}
+
+func quoteETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when it's absent
+// and the modification time is known.
+func notModified(headers map[string]string, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := auth.Header(headers, ifNoneMatchHeader); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			// weak comparison, as required for If-None-Match
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(auth.Header(headers, ifModifiedSinceHeader))
+	if err != nil {
+		return false
+	}
+
+	// the header only has second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func notModifiedResponse(headers map[string]string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNotModified,
+		Headers:    headers,
+	}
+}
+
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision []string
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+			revision = append(revision, setting.Value)
+		}
+	}
+
+	return strings.Join(revision, "-")
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type fakeSyncRunStore struct {
+	run   *models.SyncRun
+	err   error
+	table string
+}
+
+func (s *fakeSyncRunStore) LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error) {
+	s.table = tableName
+	return s.run, s.err
+}
+
+func TestCached(t *testing.T) {
+	finishedAt := time.Date(2024, 3, 5, 19, 40, 0, 0, time.UTC)
+	cfg := &config.Config{CacheMaxAge: 300, CacheHistoricalMaxAge: 86400}
+
+	withRun := NewCachePolicy(&fakeSyncRunStore{run: &models.SyncRun{ID: "run-1", FinishedAt: finishedAt}}, "stocks", cfg)
+	withoutRun := NewCachePolicy(&fakeSyncRunStore{err: db.ErrSyncRunNotFound}, "stocks", cfg)
+
+	query := map[string]string{"page": "1", "limit": "10"}
+	runETag := withRun.runETag(withRun.store.(*fakeSyncRunStore).run, events.APIGatewayProxyRequest{Path: "/stocks", QueryStringParameters: query})
+	bodyETag := contentETag(`{"ok":true}`)
+
+	tests := []struct {
+		name             string
+		policy           CachePolicy
+		query            map[string]string
+		headers          map[string]string
+		wantStatus       int
+		wantCalled       bool
+		wantETag         string
+		wantCacheControl string
+	}{
+		{"First request", withRun, query, nil, http.StatusOK, true, runETag, "private, max-age=300"},
+		{"Matching ETag skips the handler", withRun, query, map[string]string{"if-none-match": `"other", W/` + runETag}, http.StatusNotModified, false, runETag, "private, max-age=300"},
+		{"Other query", withRun, map[string]string{"page": "2", "limit": "10"}, map[string]string{"If-None-Match": runETag}, http.StatusOK, true, "", "private, max-age=300"},
+		{"Not modified since", withRun, query, map[string]string{"If-Modified-Since": finishedAt.Add(time.Second).Format(http.TimeFormat)}, http.StatusNotModified, false, runETag, "private, max-age=300"},
+		{"Modified since", withRun, query, map[string]string{"If-Modified-Since": finishedAt.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK, true, runETag, "private, max-age=300"},
+		{"Historical read", withRun, map[string]string{"as_of": "2024-01-01"}, nil, http.StatusOK, true, "", "private, max-age=86400"},
+		{"Content hash before the first run", withoutRun, query, nil, http.StatusOK, true, bodyETag, "private, max-age=300"},
+		{"Matching content hash", withoutRun, query, map[string]string{"If-None-Match": bodyETag}, http.StatusNotModified, true, bodyETag, "private, max-age=300"},
+		{"Disabled", CachePolicy{}, query, map[string]string{"If-None-Match": "*"}, http.StatusOK, true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := Cached(tt.policy, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				called = true
+				return response.Success(map[string]bool{"ok": true})
+			})
+
+			req := events.APIGatewayProxyRequest{Path: "/stocks", QueryStringParameters: tt.query, Headers: tt.headers}
+			resp, err := handler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus || called != tt.wantCalled {
+				t.Errorf("Expected status %d with the handler called: %v, got %d and %v", tt.wantStatus, tt.wantCalled, resp.StatusCode, called)
+			}
+
+			if tt.wantETag != "" && resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("Expected ETag %s, got %s", tt.wantETag, resp.Headers["ETag"])
+			}
+
+			if resp.Headers["Cache-Control"] != tt.wantCacheControl {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.wantCacheControl, resp.Headers["Cache-Control"])
+			}
+		})
+	}
+}
+
+func TestCachedSkipsErrors(t *testing.T) {
+	policy := NewCachePolicy(&fakeSyncRunStore{err: db.ErrSyncRunNotFound}, "stocks", &config.Config{CacheMaxAge: 300})
+	handler := Cached(policy, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return response.Error(db.ErrInvalidField)
+	})
+
+	resp, _ := handler(context.Background(), events.APIGatewayProxyRequest{})
+	if _, found := resp.Headers["ETag"]; found || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected errors to be returned without validators, got %d with %v", resp.StatusCode, resp.Headers)
+	}
+}
+
+func TestCachedStoreFailure(t *testing.T) {
+	policy := NewCachePolicy(&fakeSyncRunStore{err: errors.New("connection refused")}, "stocks", &config.Config{})
+	handler := Cached(policy, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		t.Error("Expected the handler not to run")
+		return response.Success(nil)
+	})
+
+	resp, _ := handler(context.Background(), events.APIGatewayProxyRequest{})
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+}
+
+func TestCachedDatasetTable(t *testing.T) {
+	store := &fakeSyncRunStore{err: db.ErrSyncRunNotFound}
+	cfg := &config.Config{
+		Datasets:       map[string]config.Dataset{"us_equities": {Table: "stocks"}, "sandbox": {Table: "stocks_sandbox"}},
+		DefaultDataset: "us_equities",
+	}
+	handler := Datasets(cfg, Cached(NewCachePolicy(store, "stocks", cfg), func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return response.Success(nil)
+	}))
+
+	handler(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"dataset": "sandbox"}})
+	if store.table != "stocks_sandbox" {
+		t.Errorf("Expected the sync runs of stocks_sandbox, got %q", store.table)
+	}
+}
+
+func TestCachedReadsPrimaryAfterRun(t *testing.T) {
+	cfg := &config.Config{CacheMaxAge: 300}
+
+	tests := []struct {
+		name        string
+		finishedAt  time.Time
+		wantPrimary bool
+	}{
+		{"Recent run", time.Now(), true},
+		{"Settled run", time.Now().Add(-time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewCachePolicy(&fakeSyncRunStore{run: &models.SyncRun{ID: "run-1", FinishedAt: tt.finishedAt}}, "stocks", cfg)
+
+			var primary bool
+			handler := Cached(policy, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				primary = db.ReadsPrimary(ctx)
+				return response.Success(map[string]bool{"ok": true})
+			})
+
+			if _, err := handler(context.Background(), events.APIGatewayProxyRequest{Path: "/stocks"}); err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if primary != tt.wantPrimary {
+				t.Errorf("Expected primary reads: %v, got %v", tt.wantPrimary, primary)
+			}
+		})
+	}
+}
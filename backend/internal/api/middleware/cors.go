@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	originHeader           = "Origin"
+	anyOrigin              = "*"
+	preflightMethod        = http.MethodOptions
+	varyHeader             = "Vary"
+	allowOriginHeader      = "Access-Control-Allow-Origin"
+	allowCredentialsHeader = "Access-Control-Allow-Credentials"
+	allowMethodsHeader     = "Access-Control-Allow-Methods"
+	allowHeadersHeader     = "Access-Control-Allow-Headers"
+	exposeHeadersHeader    = "Access-Control-Expose-Headers"
+	maxAgeHeader           = "Access-Control-Max-Age"
+)
+
+var (
+	ErrWildcardCredentials = errors.New("credentials can't be allowed for any origin")
+)
+
+// CORSPolicy decides which cross-origin requests the browsers may make. The
+// zero value allows none.
+type CORSPolicy struct {
+	origins        []string
+	anyOrigin      bool
+	methods        string
+	headers        string
+	exposedHeaders string
+	maxAge         string
+	credentials    bool
+}
+
+func NewCORSPolicy(cfg *config.Config) (CORSPolicy, error) {
+	anyOriginAllowed := slices.Contains(cfg.CORSAllowedOrigins, anyOrigin)
+
+	// browsers reject credentialed responses to *, and echoing every origin
+	// instead would let any site act with the user's credentials
+	if anyOriginAllowed && cfg.CORSAllowCredentials {
+		return CORSPolicy{}, ErrWildcardCredentials
+	}
+
+	methods := make([]string, 0, len(cfg.CORSAllowedMethods))
+	for _, method := range cfg.CORSAllowedMethods {
+		methods = append(methods, strings.ToUpper(method))
+	}
+
+	return CORSPolicy{
+		origins:        cfg.CORSAllowedOrigins,
+		anyOrigin:      anyOriginAllowed,
+		methods:        strings.Join(methods, ","),
+		headers:        canonicalHeaders(cfg.CORSAllowedHeaders),
+		exposedHeaders: canonicalHeaders(cfg.CORSExposedHeaders),
+		maxAge:         strconv.Itoa(cfg.CORSMaxAge),
+		credentials:    cfg.CORSAllowCredentials,
+	}, nil
+}
+
+// CORS answers the preflight requests and adds the CORS headers to the
+// responses of the allowed origins, errors included.
+func CORS(policy CORSPolicy, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		origin := auth.Header(req.Headers, originHeader)
+
+		if req.HTTPMethod == preflightMethod {
+			return policy.preflight(origin), nil
+		}
+
+		resp, err := handler(ctx, req)
+
+		return response.WithHeaders(resp, policy.headersFor(origin)), err
+	}
+}
+
+func (p CORSPolicy) preflight(origin string) events.APIGatewayProxyResponse {
+	headers := p.headersFor(origin)
+
+	// without the allow origin header the browser refuses the request
+	if _, allowed := headers[allowOriginHeader]; allowed {
+		headers[allowMethodsHeader] = p.methods
+		headers[allowHeadersHeader] = p.headers
+		headers[maxAgeHeader] = p.maxAge
+		delete(headers, exposeHeadersHeader)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+		Headers:    headers,
+	}
+}
+
+func (p CORSPolicy) headersFor(origin string) map[string]string {
+	headers := map[string]string{}
+
+	if !p.anyOrigin {
+		// the response depends on the origin so caches must key on it
+		headers[varyHeader] = originHeader
+	}
+
+	if origin == "" || !p.allows(origin) {
+		return headers
+	}
+
+	if p.anyOrigin {
+		headers[allowOriginHeader] = anyOrigin
+	} else {
+		headers[allowOriginHeader] = origin
+	}
+
+	if p.credentials {
+		headers[allowCredentialsHeader] = "true"
+	}
+
+	if p.exposedHeaders != "" {
+		headers[exposeHeadersHeader] = p.exposedHeaders
+	}
+
+	return headers
+}
+
+func (p CORSPolicy) allows(origin string) bool {
+	return p.anyOrigin || slices.Contains(p.origins, strings.ToLower(origin))
+}
+
+func canonicalHeaders(names []string) string {
+	canonical := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical = append(canonical, http.CanonicalHeaderKey(name))
+	}
+	return strings.Join(canonical, ",")
+}
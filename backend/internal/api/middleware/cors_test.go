@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCORS(t *testing.T) {
+	allowlist, err := NewCORSPolicy(&config.Config{
+		CORSAllowedOrigins:   []string{"https://stockwise.app", "https://admin.stockwise.app"},
+		CORSAllowedMethods:   []string{"get", "options"},
+		CORSAllowedHeaders:   []string{"content-type", "x-api-key"},
+		CORSExposedHeaders:   []string{"retry-after"},
+		CORSMaxAge:           600,
+		CORSAllowCredentials: true,
+	})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy returned unexpected error: %v", err)
+	}
+
+	wildcard, err := NewCORSPolicy(&config.Config{CORSAllowedOrigins: []string{"*"}})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      CORSPolicy
+		method      string
+		origin      string
+		handlerErr  error
+		wantStatus  int
+		wantHeaders map[string]string
+		wantMissing []string
+	}{
+		{
+			name:       "Allowlisted origin is echoed",
+			policy:     allowlist,
+			method:     http.MethodGet,
+			origin:     "https://admin.stockwise.app",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://admin.stockwise.app",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "Retry-After",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:        "Unknown origin",
+			policy:      allowlist,
+			method:      http.MethodGet,
+			origin:      "https://evil.example",
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Vary": "Origin"},
+			wantMissing: []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials"},
+		},
+		{
+			name:        "Errors get the headers too",
+			policy:      allowlist,
+			method:      http.MethodGet,
+			origin:      "https://stockwise.app",
+			handlerErr:  db.ErrInvalidField,
+			wantStatus:  http.StatusBadRequest,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "https://stockwise.app"},
+		},
+		{
+			name:       "Preflight",
+			policy:     allowlist,
+			method:     http.MethodOptions,
+			origin:     "https://stockwise.app",
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://stockwise.app",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+				"Access-Control-Allow-Headers": "Content-Type,X-Api-Key",
+				"Access-Control-Max-Age":       "600",
+			},
+			wantMissing: []string{"Access-Control-Expose-Headers"},
+		},
+		{
+			name:        "Preflight from an unknown origin",
+			policy:      allowlist,
+			method:      http.MethodOptions,
+			origin:      "https://evil.example",
+			wantStatus:  http.StatusNoContent,
+			wantMissing: []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods"},
+		},
+		{
+			name:        "Wildcard",
+			policy:      wildcard,
+			method:      http.MethodGet,
+			origin:      "https://anyone.example",
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "*"},
+			wantMissing: []string{"Access-Control-Allow-Credentials", "Vary"},
+		},
+		{
+			name:        "Zero policy allows nothing",
+			policy:      CORSPolicy{},
+			method:      http.MethodGet,
+			origin:      "https://stockwise.app",
+			wantStatus:  http.StatusOK,
+			wantMissing: []string{"Access-Control-Allow-Origin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := CORS(tt.policy, func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				called = true
+				if tt.handlerErr != nil {
+					return response.Error(tt.handlerErr)
+				}
+				return response.Success(map[string]string{})
+			})
+
+			req := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Headers: map[string]string{"origin": tt.origin}}
+			resp, err := handler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+
+			if called == (tt.method == http.MethodOptions) {
+				t.Errorf("Expected the handler to run only for non preflight requests, called: %v", called)
+			}
+
+			for name, value := range tt.wantHeaders {
+				if resp.Headers[name] != value {
+					t.Errorf("Expected %s: %s, got %q", name, value, resp.Headers[name])
+				}
+			}
+
+			for _, name := range tt.wantMissing {
+				if _, found := resp.Headers[name]; found {
+					t.Errorf("Expected no %s header, got %q", name, resp.Headers[name])
+				}
+			}
+		})
+	}
+}
+
+func TestNewCORSPolicyWildcardCredentials(t *testing.T) {
+	_, err := NewCORSPolicy(&config.Config{CORSAllowedOrigins: []string{"*"}, CORSAllowCredentials: true})
+	if !errors.Is(err, ErrWildcardCredentials) {
+		t.Errorf("Expected %v, got %v", ErrWildcardCredentials, err)
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const datasetParam = "dataset"
+
+type datasetKey struct{}
+
+// Datasets resolves the dataset query parameter against the datasets of the
+// config, the default one when it's missing, and refuses the unknown ones
+// before the handler, which finds it with DatasetFrom.
+func Datasets(cfg *config.Config, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		dataset, err := cfg.Dataset(req.QueryStringParameters[datasetParam])
+		if err != nil {
+			return response.ErrorContext(ctx, spec.InvalidParameter(datasetParam, err.Error()))
+		}
+
+		return handler(WithDataset(ctx, dataset), req)
+	}
+}
+
+func WithDataset(ctx context.Context, dataset config.Dataset) context.Context {
+	return context.WithValue(ctx, datasetKey{}, dataset)
+}
+
+// DatasetFrom returns the dataset resolved by Datasets.
+func DatasetFrom(ctx context.Context) (config.Dataset, bool) {
+	dataset, ok := ctx.Value(datasetKey{}).(config.Dataset)
+	return dataset, ok
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Traced gives every request an ID, the one of API Gateway or a generated one,
+// and a span continuing the trace of the caller. The ID is returned in the
+// X-Request-ID header and added to the logs of the request, which ends with a
+// log of its status and duration, also observed in the route latency metric.
+// It must wrap the other middlewares so their responses are measured too.
+func Traced(endpoint *spec.Endpoint, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+
+		requestID := req.RequestContext.RequestID
+		if requestID == "" {
+			requestID = telemetry.NewRequestID()
+		}
+
+		ctx = telemetry.WithRequestID(telemetry.Extract(ctx, req.Headers), requestID)
+		ctx, span := telemetry.StartServer(ctx, endpoint.Method+" "+endpoint.Path,
+			attribute.String("http.request.method", req.HTTPMethod),
+			attribute.String("http.route", endpoint.Path),
+			attribute.String("url.path", req.Path),
+			attribute.String("request.id", requestID),
+		)
+		defer telemetry.PushInBackground(ctx)
+		defer telemetry.Flush(ctx)
+
+		resp, err := handler(ctx, req)
+
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+		telemetry.End(span, err)
+
+		duration := time.Since(start)
+		telemetry.ObserveRequest(endpoint.Path, req.HTTPMethod, resp.StatusCode, duration)
+
+		level := slog.LevelInfo
+		if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.Log(ctx, level, "request completed",
+			"method", req.HTTPMethod,
+			"path", req.Path,
+			"status", resp.StatusCode,
+			"duration_ms", duration.Milliseconds(),
+		)
+
+		return response.WithHeaders(resp, map[string]string{telemetry.RequestIDHeader: requestID}), err
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestTraced(t *testing.T) {
+	tests := []struct {
+		name          string
+		gatewayID     string
+		handlerErr    error
+		wantStatus    int
+		wantRequestID string
+	}{
+		{
+			name:          "API Gateway request ID",
+			gatewayID:     "gw-1",
+			wantStatus:    http.StatusOK,
+			wantRequestID: "gw-1",
+		},
+		{
+			name:       "Generated request ID",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "Error responses carry the ID",
+			gatewayID:     "gw-2",
+			handlerErr:    errors.New("boom"),
+			wantStatus:    http.StatusInternalServerError,
+			wantRequestID: "gw-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handlerID string
+			handler := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				handlerID = telemetry.RequestID(ctx)
+				if tt.handlerErr != nil {
+					return response.ErrorContext(ctx, tt.handlerErr)
+				}
+				return response.Success(map[string]string{})
+			}
+
+			req := events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodGet,
+				Path:           "/stocks",
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: tt.gatewayID},
+			}
+
+			resp, err := Traced(spec.Stocks, handler)(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			got := resp.Headers[telemetry.RequestIDHeader]
+			if tt.wantRequestID != "" && got != tt.wantRequestID {
+				t.Errorf("X-Request-ID = %q, want %q", got, tt.wantRequestID)
+			}
+			if got == "" || got != handlerID {
+				t.Errorf("X-Request-ID = %q, handler saw %q", got, handlerID)
+			}
+		})
+	}
+}
@@ -1,27 +1,27 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
 
+	"github.com/CorreaJose13/StockAPI/internal/apierror"
 	"github.com/aws/aws-lambda-go/events"
 )
 
 var (
+	// the CORS headers are added by middleware.CORS
 	responseHeaders = map[string]string{
-		"Content-Type":                     "application/json",
-		"Access-Control-Allow-Origin":      "*",
-		"Access-Control-Allow-Credentials": "true",
-		"Access-Control-Allow-Headers":     "Content-Type",
-		"Access-Control-Allow-Methods":     "OPTIONS,POST,GET",
+		"Content-Type": "application/json",
 	}
 )
 
 func Success(data any) (events.APIGatewayProxyResponse, error) {
 	responseBytes, err := json.Marshal(data)
 	if err != nil {
-		return Error(http.StatusInternalServerError, "Failed to serialize response data")
+		return Error(err)
 	}
 
 	return events.APIGatewayProxyResponse{
@@ -31,10 +31,43 @@ func Success(data any) (events.APIGatewayProxyResponse, error) {
 	}, nil
 }
 
-func Error(statusCode int, message string) (events.APIGatewayProxyResponse, error) {
+// Error writes the typed error body matching err, see apierror.From. The cause
+// of server errors is logged since it isn't part of the body.
+func Error(err error) (events.APIGatewayProxyResponse, error) {
+	return ErrorContext(context.Background(), err)
+}
+
+// ErrorContext is Error logging with the request ID of ctx.
+func ErrorContext(ctx context.Context, err error) (events.APIGatewayProxyResponse, error) {
+	apiErr := apierror.From(err)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "request failed", "code", apiErr.Code, "error", err)
+	}
+
+	body, marshalErr := json.Marshal(apiErr)
+	if marshalErr != nil {
+		// details are the only part that may not serialize
+		body, _ = json.Marshal(apiErr.WithDetails(nil))
+	}
+
 	return events.APIGatewayProxyResponse{
-		StatusCode: statusCode,
+		StatusCode: apiErr.Status,
 		Headers:    responseHeaders,
-		Body:       fmt.Sprintf(`{"message":"%s"}`, message),
+		Body:       string(body),
 	}, nil
 }
+
+// WithHeaders returns the response with the given headers added, the shared
+// default headers are copied rather than modified.
+func WithHeaders(resp events.APIGatewayProxyResponse, headers map[string]string) events.APIGatewayProxyResponse {
+	merged := maps.Clone(resp.Headers)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+
+	maps.Copy(merged, headers)
+	resp.Headers = merged
+
+	return resp
+}
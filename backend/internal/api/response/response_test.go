@@ -0,0 +1,60 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/apierror"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{"Message with quotes", fmt.Errorf(`field "price\": %w`, db.ErrInvalidField), http.StatusBadRequest, apierror.CodeInvalidField},
+		{"Details", apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, "bad page").WithDetails(map[string]any{"parameter": "page"}), http.StatusBadRequest, apierror.CodeInvalidParameter},
+		{"Unserializable details", apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, "bad").WithDetails(map[string]any{"fn": func() {}}), http.StatusBadRequest, apierror.CodeInvalidParameter},
+		{"Internal", fmt.Errorf("boom"), http.StatusInternalServerError, apierror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := Error(tt.err)
+			if err != nil {
+				t.Fatalf("Error returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+
+			var body apierror.Error
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("Expected a valid JSON body, got %q: %v", resp.Body, err)
+			}
+
+			if body.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, body.Code)
+			}
+		})
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	resp, _ := Success(map[string]string{})
+	withHeaders := WithHeaders(resp, map[string]string{"Retry-After": "3"})
+
+	if withHeaders.Headers["Retry-After"] != "3" || withHeaders.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected the default and the added headers, got %v", withHeaders.Headers)
+	}
+
+	if _, ok := responseHeaders["Retry-After"]; ok {
+		t.Error("Expected the shared default headers to be left untouched")
+	}
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	defaultPageConcurrency = 4
+)
+
+var (
+	ErrInvalidMapping = errors.New("invalid rest mapping")
+)
+
+// RESTMapping describes how to page through a generic JSON ratings API and
+// where every rating field lives inside its items. Paths are dot separated.
+//
+// Feeds paged with a cursor set NextPagePath and NextPageParam and are fetched
+// sequentially. Feeds paged by number set PageParam and, when they report
+// their page count at TotalPagesPath, are fetched with up to Concurrency
+// requests in flight.
+type RESTMapping struct {
+	Name           string            `json:"name"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	ItemsPath      string            `json:"items_path"`
+	NextPagePath   string            `json:"next_page_path"`
+	NextPageParam  string            `json:"next_page_param"`
+	PageParam      string            `json:"page_param"`
+	TotalPagesPath string            `json:"total_pages_path"`
+	Concurrency    int               `json:"concurrency"`
+	Fields         map[string]string `json:"fields"`
+}
+
+type restSource struct {
+	client  *http.Client
+	mapping *RESTMapping
+}
+
+func LoadRESTMapping(path string) (*RESTMapping, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rest mapping: %w", err)
+	}
+
+	var mapping RESTMapping
+	if err := json.Unmarshal(body, &mapping); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMapping, err)
+	}
+
+	if mapping.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidMapping)
+	}
+
+	if mapping.Fields["ticker"] == "" {
+		return nil, fmt.Errorf("%w: ticker field is required", ErrInvalidMapping)
+	}
+
+	if mapping.NextPagePath != "" && mapping.NextPageParam == "" {
+		return nil, fmt.Errorf("%w: next_page_param is required with next_page_path", ErrInvalidMapping)
+	}
+
+	if mapping.NextPagePath != "" && mapping.PageParam != "" {
+		return nil, fmt.Errorf("%w: next_page_path and page_param are mutually exclusive", ErrInvalidMapping)
+	}
+
+	if mapping.Name == "" {
+		mapping.Name = RESTSource
+	}
+
+	if mapping.Concurrency <= 0 {
+		mapping.Concurrency = defaultPageConcurrency
+	}
+
+	return &mapping, nil
+}
+
+func NewRESTSource(mapping *RESTMapping, timeout time.Duration) *restSource {
+	return &restSource{
+		client:  telemetry.NewHTTPClient(timeout),
+		mapping: mapping,
+	}
+}
+
+func (rs *restSource) Name() string {
+	return rs.mapping.Name
+}
+
+func (rs *restSource) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	return collectPages(ctx, rs)
+}
+
+func (rs *restSource) StreamPages(ctx context.Context, pages chan<- []models.Stock) error {
+	if rs.mapping.PageParam != "" {
+		return rs.streamNumberedPages(ctx, pages)
+	}
+	return rs.streamCursorPages(ctx, pages)
+}
+
+func (rs *restSource) streamCursorPages(ctx context.Context, pages chan<- []models.Stock) error {
+	nextPage := ""
+
+	for {
+		pageURL, err := rs.pageURL(rs.mapping.NextPageParam, nextPage)
+		if err != nil {
+			return err
+		}
+
+		body, items, err := rs.fetchPage(ctx, pageURL)
+		if err != nil {
+			return err
+		}
+
+		if err := sendPage(ctx, pages, items); err != nil {
+			return err
+		}
+
+		if rs.mapping.NextPagePath == "" {
+			return nil
+		}
+
+		nextPage = stringValue(lookupPath(body, rs.mapping.NextPagePath))
+		if nextPage == "" {
+			return nil
+		}
+	}
+}
+
+// streamNumberedPages reads the page count from the first page and fetches the
+// remaining ones concurrently. Without a page count it walks the pages in
+// order until an empty one is returned.
+func (rs *restSource) streamNumberedPages(ctx context.Context, pages chan<- []models.Stock) error {
+	body, items, err := rs.fetchNumberedPage(ctx, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := sendPage(ctx, pages, items); err != nil {
+		return err
+	}
+
+	if rs.mapping.TotalPagesPath == "" {
+		for page := 2; len(items) > 0; page++ {
+			_, items, err = rs.fetchNumberedPage(ctx, page)
+			if err != nil {
+				return err
+			}
+
+			if len(items) == 0 {
+				return nil
+			}
+
+			if err := sendPage(ctx, pages, items); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	totalPages, err := strconv.Atoi(stringValue(lookupPath(body, rs.mapping.TotalPagesPath)))
+	if err != nil {
+		return fmt.Errorf("%w: total pages path '%s' is not a number", ErrInvalidMapping, rs.mapping.TotalPagesPath)
+	}
+
+	return rs.fetchPagesConcurrently(ctx, 2, totalPages, pages)
+}
+
+func (rs *restSource) fetchPagesConcurrently(ctx context.Context, first, last int, pages chan<- []models.Stock) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	semaphore := make(chan struct{}, rs.mapping.Concurrency)
+
+	for page := first; page <= last; page++ {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			_, items, err := rs.fetchNumberedPage(ctx, page)
+			if err == nil {
+				err = sendPage(ctx, pages, items)
+			}
+
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(page)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+func (rs *restSource) fetchNumberedPage(ctx context.Context, page int) (any, []models.Stock, error) {
+	pageURL, err := rs.pageURL(rs.mapping.PageParam, strconv.Itoa(page))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rs.fetchPage(ctx, pageURL)
+}
+
+func (rs *restSource) fetchPage(ctx context.Context, pageURL string) (any, []models.Stock, error) {
+	body, err := rs.doRequest(ctx, pageURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching stocks: %w", err)
+	}
+
+	items, ok := lookupPath(body, rs.mapping.ItemsPath).([]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: items path '%s' is not a list", ErrInvalidMapping, rs.mapping.ItemsPath)
+	}
+
+	stocks := make([]models.Stock, 0, len(items))
+	for _, item := range items {
+		stocks = append(stocks, rs.mapItem(item))
+	}
+
+	return body, stampSource(stocks, rs.mapping.Name), nil
+}
+
+func (rs *restSource) pageURL(param, value string) (string, error) {
+	if value == "" {
+		return rs.mapping.URL, nil
+	}
+
+	parsed, err := url.Parse(rs.mapping.URL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidMapping, err)
+	}
+
+	query := parsed.Query()
+	query.Set(param, value)
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+func (rs *restSource) mapItem(item any) models.Stock {
+	fields := make(map[string]string, len(rs.mapping.Fields))
+	for field, path := range rs.mapping.Fields {
+		fields[field] = stringValue(lookupPath(item, path))
+	}
+	return stockFromFields(fields)
+}
+
+func (rs *restSource) doRequest(ctx context.Context, url string) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	for header, value := range rs.mapping.Headers {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := rs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api returned status code: %d and body: %s", resp.StatusCode, string(body))
+	}
+
+	var response any
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+
+	return response, nil
+}
+
+// lookupPath walks a decoded JSON document following a dot separated path. An
+// empty path returns the document itself.
+func lookupPath(document any, path string) any {
+	if path == "" {
+		return document
+	}
+
+	current := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+
+	return current
+}
+
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	APISource  = models.DefaultSource
+	FileSource = "file"
+	RESTSource = "rest"
+)
+
+var (
+	ErrUnknownSource      = errors.New("unknown ratings source")
+	ErrNoSources          = errors.New("no ratings sources configured")
+	ErrMissingAPIURL      = errors.New("api url cannot be empty")
+	ErrMissingBearerToken = errors.New("bearer token cannot be empty")
+	ErrMissingFile        = errors.New("ratings file cannot be empty")
+	ErrMissingMapping     = errors.New("rest mapping cannot be empty")
+)
+
+// RatingsSource is an upstream feed of analyst ratings. Every record it returns
+// carries the name of the source in its Source field.
+type RatingsSource interface {
+	Name() string
+	FetchStocks(ctx context.Context) ([]models.Stock, error)
+}
+
+type multiSource struct {
+	sources []RatingsSource
+}
+
+// NewRatingsSource builds the sources listed in cfg.RatingsSources. When more
+// than one is configured their records are merged and de-duplicated.
+func NewRatingsSource(cfg *config.Config) (RatingsSource, error) {
+	if len(cfg.RatingsSources) == 0 {
+		return nil, ErrNoSources
+	}
+
+	var sources []RatingsSource
+	for _, name := range cfg.RatingsSources {
+		source, err := newSource(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+
+	return NewMultiSource(sources...), nil
+}
+
+func newSource(name string, cfg *config.Config) (RatingsSource, error) {
+	switch name {
+	case APISource:
+		if cfg.APIURL == "" {
+			return nil, ErrMissingAPIURL
+		}
+		if cfg.BearerToken == "" {
+			return nil, ErrMissingBearerToken
+		}
+		return NewAPIConsumer(cfg), nil
+
+	case FileSource:
+		if cfg.RatingsFile == "" {
+			return nil, ErrMissingFile
+		}
+		return NewFileSource(cfg.RatingsFile), nil
+
+	case RESTSource:
+		if cfg.RatingsRESTMapping == "" {
+			return nil, ErrMissingMapping
+		}
+		mapping, err := LoadRESTMapping(cfg.RatingsRESTMapping)
+		if err != nil {
+			return nil, err
+		}
+		return NewRESTSource(mapping, cfg.UpstreamTimeout), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+}
+
+func NewMultiSource(sources ...RatingsSource) *multiSource {
+	return &multiSource{
+		sources: sources,
+	}
+}
+
+func (ms *multiSource) Name() string {
+	names := make([]string, 0, len(ms.sources))
+	for _, source := range ms.sources {
+		names = append(names, source.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (ms *multiSource) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	var stocks []models.Stock
+	for _, source := range ms.sources {
+		sourceStocks, err := source.FetchStocks(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching from source %s: %w", source.Name(), err)
+		}
+
+		slog.InfoContext(ctx, "fetched stocks", "source", source.Name(), "stocks", len(sourceStocks))
+
+		stocks = append(stocks, sourceStocks...)
+	}
+
+	return Deduplicate(stocks), nil
+}
+
+type sourceKey struct {
+	ticker string
+	source string
+}
+
+// Deduplicate keeps a single record per ticker and source, since the stocks
+// table holds the latest rating of every ticker in every source. Records are
+// expected in source priority order: the newest rating wins and, on a tie, the
+// record seen first wins.
+func Deduplicate(stocks []models.Stock) []models.Stock {
+	positions := make(map[sourceKey]int, len(stocks))
+	deduplicated := make([]models.Stock, 0, len(stocks))
+
+	for _, stock := range stocks {
+		key := sourceKey{ticker: strings.ToUpper(strings.TrimSpace(stock.Ticker)), source: stock.Source}
+
+		pos, seen := positions[key]
+		if !seen {
+			positions[key] = len(deduplicated)
+			deduplicated = append(deduplicated, stock)
+			continue
+		}
+
+		if isNewer(stock.Time, deduplicated[pos].Time) {
+			deduplicated[pos] = stock
+		}
+	}
+
+	return deduplicated
+}
+
+func isNewer(candidate, current string) bool {
+	candidateTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(candidate))
+	if err != nil {
+		return false
+	}
+
+	currentTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(current))
+	if err != nil {
+		return true
+	}
+
+	return candidateTime.After(currentTime)
+}
+
+func stampSource(stocks []models.Stock, source string) []models.Stock {
+	for i := range stocks {
+		if stocks[i].Source == "" {
+			stocks[i].Source = source
+		}
+	}
+	return stocks
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type stubSource struct {
+	name   string
+	stocks []models.Stock
+	err    error
+}
+
+func (ss *stubSource) Name() string {
+	return ss.name
+}
+
+func (ss *stubSource) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	return ss.stocks, ss.err
+}
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestFileSourceJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Array of items", `[{"ticker": "AAPL", "target_to": "$170"}]`},
+		{"Upstream shape", `{"items": [{"ticker": "AAPL", "target_to": "$170"}], "next_page": ""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewFileSource(writeFixture(t, "ratings.json", tt.content))
+
+			stocks, err := source.FetchStocks(context.Background())
+			if err != nil {
+				t.Fatalf("FetchStocks returned unexpected error: %v", err)
+			}
+
+			if len(stocks) != 1 || stocks[0].Ticker != "AAPL" || stocks[0].TargetTo != "$170" {
+				t.Errorf("Unexpected stocks: %+v", stocks)
+			}
+
+			if stocks[0].Source != FileSource {
+				t.Errorf("Expected source %s, got %s", FileSource, stocks[0].Source)
+			}
+		})
+	}
+}
+
+func TestFileSourceCSV(t *testing.T) {
+	content := "ticker,target_from,target_to,company,action,brokerage,rating_from,rating_to,time\n" +
+		"AAPL,$150,$170,Apple Inc.,upgraded by,Barclays,hold,buy,2024-01-08T00:00:00Z\n"
+
+	source := NewFileSource(writeFixture(t, "ratings.csv", content))
+
+	stocks, err := source.FetchStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchStocks returned unexpected error: %v", err)
+	}
+
+	if len(stocks) != 1 || stocks[0].Company != "Apple Inc." || stocks[0].RatingTo != "buy" {
+		t.Errorf("Unexpected stocks: %+v", stocks)
+	}
+}
+
+func TestFileSourceUnsupportedFormat(t *testing.T) {
+	source := NewFileSource(writeFixture(t, "ratings.xml", "<items/>"))
+
+	if _, err := source.FetchStocks(context.Background()); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestRESTSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("Expected X-Api-Key header 'secret', got %s", r.Header.Get("X-Api-Key"))
+		}
+
+		body := map[string]any{
+			"data": []any{map[string]any{"symbol": "AAPL", "pt": map[string]any{"new": 170.5}}},
+			"meta": map[string]any{"cursor": "page2"},
+		}
+		if r.URL.Query().Get("cursor") == "page2" {
+			body = map[string]any{
+				"data": []any{map[string]any{"symbol": "MSFT", "pt": map[string]any{"new": 310}}},
+				"meta": map[string]any{},
+			}
+		}
+
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	source := NewRESTSource(&RESTMapping{
+		Name:          "vendor",
+		URL:           server.URL,
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		ItemsPath:     "data",
+		NextPagePath:  "meta.cursor",
+		NextPageParam: "cursor",
+		Fields:        map[string]string{"ticker": "symbol", "target_to": "pt.new"},
+	}, time.Second)
+
+	stocks, err := source.FetchStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchStocks returned unexpected error: %v", err)
+	}
+
+	if len(stocks) != 2 {
+		t.Fatalf("Expected 2 stocks (from 2 pages), got %d", len(stocks))
+	}
+
+	if stocks[0].TargetTo != "170.5" || stocks[1].Ticker != "MSFT" || stocks[1].Source != "vendor" {
+		t.Errorf("Unexpected stocks: %+v", stocks)
+	}
+}
+
+func TestRESTSourceNumberedPages(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": []any{map[string]any{"symbol": "T" + strconv.Itoa(page)}},
+			"pages": 6,
+		})
+	}))
+	defer server.Close()
+
+	source := NewRESTSource(&RESTMapping{
+		Name:           "vendor",
+		URL:            server.URL,
+		ItemsPath:      "items",
+		PageParam:      "page",
+		TotalPagesPath: "pages",
+		Concurrency:    2,
+		Fields:         map[string]string{"ticker": "symbol"},
+	}, time.Second)
+
+	stocks, err := source.FetchStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchStocks returned unexpected error: %v", err)
+	}
+
+	if len(stocks) != 6 {
+		t.Errorf("Expected 6 stocks (from 6 pages), got %d", len(stocks))
+	}
+
+	if maxInFlight.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight.Load())
+	}
+}
+
+func TestLoadRESTMapping(t *testing.T) {
+	path := writeFixture(t, "mapping.json", `{"url": "https://example.com", "fields": {"ticker": "symbol"}}`)
+
+	mapping, err := LoadRESTMapping(path)
+	if err != nil {
+		t.Fatalf("LoadRESTMapping returned unexpected error: %v", err)
+	}
+	if mapping.Name != RESTSource {
+		t.Errorf("Expected default name %s, got %s", RESTSource, mapping.Name)
+	}
+
+	path = writeFixture(t, "mapping.json", `{"url": "https://example.com", "fields": {}}`)
+	if _, err := LoadRESTMapping(path); !errors.Is(err, ErrInvalidMapping) {
+		t.Errorf("Expected ErrInvalidMapping, got %v", err)
+	}
+}
+
+func TestMultiSourceDeduplicates(t *testing.T) {
+	source := NewMultiSource(
+		&stubSource{name: "api", stocks: []models.Stock{
+			{Ticker: "AAPL", Time: "2024-01-08T00:00:00Z", Source: "api"},
+			{Ticker: "MSFT", Time: "2024-01-08T00:00:00Z", Source: "api"},
+			{Ticker: "msft", Time: "2024-01-09T00:00:00Z", Source: "api", Brokerage: "newest"},
+			{Ticker: "NVDA", Time: "2024-01-09T00:00:00Z", Source: "api", Brokerage: "first"},
+			{Ticker: "NVDA", Time: "2024-01-09T00:00:00Z", Source: "api"},
+		}},
+		&stubSource{name: "file", stocks: []models.Stock{
+			{Ticker: "aapl", Time: "2024-01-08T00:00:00Z", Source: "file"},
+			{Ticker: "GOOG", Time: "2024-01-08T00:00:00Z", Source: "file"},
+		}},
+	)
+
+	stocks, err := source.FetchStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchStocks returned unexpected error: %v", err)
+	}
+
+	if len(stocks) != 5 {
+		t.Fatalf("Expected 5 deduplicated stocks, got %d", len(stocks))
+	}
+
+	brokerages := map[string]string{}
+	var aaplSources []string
+	for _, stock := range stocks {
+		brokerages[strings.ToUpper(stock.Ticker)] = stock.Brokerage
+		if strings.ToUpper(stock.Ticker) == "AAPL" {
+			aaplSources = append(aaplSources, stock.Source)
+		}
+	}
+
+	if len(aaplSources) != 2 {
+		t.Errorf("Expected the ratings of both sources to be kept, got %v", aaplSources)
+	}
+	if brokerages["MSFT"] != "newest" {
+		t.Errorf("Expected the newest rating of a source to win, got %s", brokerages["MSFT"])
+	}
+	if brokerages["NVDA"] != "first" {
+		t.Errorf("Expected the first rating of a source to win a tie, got %s", brokerages["NVDA"])
+	}
+}
+
+func TestMultiSourceError(t *testing.T) {
+	source := NewMultiSource(&stubSource{name: "broken", err: errors.New("boom")})
+
+	if _, err := source.FetchStocks(context.Background()); err == nil {
+		t.Error("Expected error from a failing source, got nil")
+	}
+}
+
+func TestNewRatingsSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr error
+	}{
+		{"API source", &config.Config{RatingsSources: []string{"api"}, APIURL: "https://api.example.com", BearerToken: "token"}, nil},
+		{"Missing API URL", &config.Config{RatingsSources: []string{"api"}, BearerToken: "token"}, ErrMissingAPIURL},
+		{"Missing bearer token", &config.Config{RatingsSources: []string{"api"}, APIURL: "https://api.example.com"}, ErrMissingBearerToken},
+		{"Missing file", &config.Config{RatingsSources: []string{"file"}}, ErrMissingFile},
+		{"Missing mapping", &config.Config{RatingsSources: []string{"rest"}}, ErrMissingMapping},
+		{"Unknown source", &config.Config{RatingsSources: []string{"ftp"}}, ErrUnknownSource},
+		{"No sources", &config.Config{}, ErrNoSources},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRatingsSource(tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewRatingsSource() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
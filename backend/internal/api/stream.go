@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// PagedSource is a RatingsSource able to hand over its pages as they are
+// fetched instead of holding the whole feed in memory. StreamPages blocks on
+// the channel when the consumer falls behind and never closes it.
+type PagedSource interface {
+	RatingsSource
+	StreamPages(ctx context.Context, pages chan<- []models.Stock) error
+}
+
+func collectPages(ctx context.Context, source PagedSource) ([]models.Stock, error) {
+	pages := make(chan []models.Stock)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- source.StreamPages(ctx, pages)
+		close(pages)
+	}()
+
+	var stocks []models.Stock
+	for page := range pages {
+		stocks = append(stocks, page...)
+	}
+
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+func sendPage(ctx context.Context, pages chan<- []models.Stock, page []models.Stock) error {
+	select {
+	case pages <- page:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
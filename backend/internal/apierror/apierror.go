@@ -0,0 +1,145 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/functions"
+	"github.com/CorreaJose13/StockAPI/internal/ingest"
+	"github.com/CorreaJose13/StockAPI/utils"
+)
+
+// Code identifies the kind of error in a machine-readable way.
+type Code string
+
+const (
+	CodeInvalidField        Code = "INVALID_FIELD"
+	CodeInvalidOrder        Code = "INVALID_ORDER"
+	CodeInvalidParameter    Code = "INVALID_PARAMETER"
+	CodeInvalidTicker       Code = "INVALID_TICKER"
+	CodeInvalidStock        Code = "INVALID_STOCK"
+	CodeUnauthenticated     Code = "UNAUTHENTICATED"
+	CodeForbidden           Code = "FORBIDDEN"
+	CodeRateLimited         Code = "RATE_LIMITED"
+	CodeQuotaExceeded       Code = "QUOTA_EXCEEDED"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeUpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	CodeTimeout             Code = "TIMEOUT"
+	CodeUnavailable         Code = "SERVICE_UNAVAILABLE"
+	CodeInternal            Code = "INTERNAL_ERROR"
+)
+
+const (
+	internalMessage = "internal server error"
+	upstreamMessage = "upstream service unavailable"
+	timeoutMessage  = "request timed out"
+	databaseMessage = "database unavailable"
+)
+
+// Error is the body returned by every endpoint when a request fails.
+type Error struct {
+	Status  int            `json:"-"`
+	Code    Code           `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+
+	err error
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// WithDetails returns a copy of the error carrying the given details.
+func (e *Error) WithDetails(details map[string]any) *Error {
+	withDetails := *e
+	withDetails.Details = details
+	return &withDetails
+}
+
+type mapping struct {
+	target error
+	status int
+	code   Code
+	// message replaces the error message when it may leak internals, such as
+	// upstream URLs.
+	message string
+}
+
+// mappings translates the sentinel errors of the other packages, the first
+// match wins.
+var mappings = []mapping{
+	{auth.ErrUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated, ""},
+	{auth.ErrForbidden, http.StatusForbidden, CodeForbidden, ""},
+	{auth.ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited, ""},
+	{auth.ErrQuotaExceeded, http.StatusTooManyRequests, CodeQuotaExceeded, ""},
+	{db.ErrInvalidField, http.StatusBadRequest, CodeInvalidField, ""},
+	{db.ErrInvalidOrder, http.StatusBadRequest, CodeInvalidOrder, ""},
+	{functions.ErrInvalidType, http.StatusBadRequest, CodeInvalidParameter, ""},
+	{functions.ErrInvalidParameter, http.StatusBadRequest, CodeInvalidParameter, ""},
+	{functions.ErrInvalidAsOf, http.StatusBadRequest, CodeInvalidParameter, ""},
+	{utils.ErrEmptyTickerString, http.StatusBadRequest, CodeInvalidTicker, ""},
+	{chart.ErrInvalidTicker, http.StatusBadRequest, CodeInvalidTicker, ""},
+	{ingest.ErrInvalidStock, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrEmptyCompanyString, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrEmptyBrokerageString, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrEmptyTargetString, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrNegativeTarget, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrEmptyTimeString, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{utils.ErrInvalidTimeFormat, http.StatusUnprocessableEntity, CodeInvalidStock, ""},
+	{chart.ErrUpstreamUnavailable, http.StatusBadGateway, CodeUpstreamUnavailable, upstreamMessage},
+	{db.ErrUnreachable, http.StatusServiceUnavailable, CodeUnavailable, databaseMessage},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, timeoutMessage},
+	{chart.ErrNoData, http.StatusNotFound, CodeNotFound, ""},
+}
+
+// From converts any error into an Error. Errors that already are one are
+// returned as is and known sentinels get their code and status, keeping the
+// message unless it may leak internals. Anything else becomes an internal
+// error whose message isn't exposed, the cause stays available through
+// errors.Unwrap.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.target) {
+			message := m.message
+			if message == "" {
+				message = err.Error()
+			}
+
+			return &Error{
+				Status:  m.status,
+				Code:    m.code,
+				Message: message,
+				err:     err,
+			}
+		}
+	}
+
+	return &Error{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: internalMessage,
+		err:     err,
+	}
+}
@@ -0,0 +1,89 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/utils"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    Code
+		wantMessage string
+	}{
+		{
+			"Invalid field",
+			fmt.Errorf("error building statement: %w", db.ErrInvalidField),
+			http.StatusBadRequest, CodeInvalidField, "error building statement: invalid field",
+		},
+		{
+			"Empty ticker",
+			fmt.Errorf("%w: ticker query parameter is required", utils.ErrEmptyTickerString),
+			http.StatusBadRequest, CodeInvalidTicker, "empty ticker: ticker query parameter is required",
+		},
+		{
+			"Upstream joined with other provider errors",
+			errors.Join(fmt.Errorf("%w: status 503", chart.ErrUpstreamUnavailable), chart.ErrNoData),
+			http.StatusBadGateway, CodeUpstreamUnavailable, upstreamMessage,
+		},
+		{
+			"Timeout",
+			fmt.Errorf("failed to query stocks: %w", context.DeadlineExceeded),
+			http.StatusGatewayTimeout, CodeTimeout, timeoutMessage,
+		},
+		{
+			"Database unreachable at cold start",
+			fmt.Errorf("%w: cockroachdb: connection refused", db.ErrUnreachable),
+			http.StatusServiceUnavailable, CodeUnavailable, databaseMessage,
+		},
+		{
+			"Rate limited",
+			auth.ErrRateLimited,
+			http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded",
+		},
+		{
+			"Missing scope",
+			fmt.Errorf("%w: chart:read scope required", auth.ErrForbidden),
+			http.StatusForbidden, CodeForbidden, "forbidden: chart:read scope required",
+		},
+		{
+			"Already typed",
+			New(http.StatusTooManyRequests, "RATE_LIMITED", "slow down"),
+			http.StatusTooManyRequests, "RATE_LIMITED", "slow down",
+		},
+		{
+			"Unknown error is not exposed",
+			errors.New(`pq: relation "stocks" does not exist`),
+			http.StatusInternalServerError, CodeInternal, internalMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode || got.Message != tt.wantMessage {
+				t.Errorf("From() = {%d %s %q}, want {%d %s %q}",
+					got.Status, got.Code, got.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestFromKeepsCause(t *testing.T) {
+	cause := errors.New("connection refused")
+
+	if got := From(cause); !errors.Is(got, cause) {
+		t.Errorf("Expected the internal error to wrap its cause")
+	}
+}
@@ -0,0 +1,243 @@
+// Package app wires the dependencies of the API and of the sync into a single
+// App, built once per Lambda, server or test instead of package globals.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/analysis"
+	"github.com/CorreaJose13/StockAPI/internal/api"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/quality"
+	"github.com/CorreaJose13/StockAPI/internal/repository"
+	"github.com/CorreaJose13/StockAPI/internal/search"
+	"github.com/CorreaJose13/StockAPI/internal/snapshot"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+)
+
+var (
+	ErrMissingDBURL = errors.New("db url cannot be empty")
+)
+
+type App struct {
+	Config    *config.Config
+	Repo      repository.StockRepository
+	Logger    *slog.Logger
+	Profile   analysis.Profile
+	Auth      *auth.Authenticator
+	Snapshots *snapshot.Service
+	// Brokerages names the brokerages of the feeds canonically, the profile
+	// scores with it unless it has its own.
+	Brokerages *brokerage.Registry
+	// Taxonomy maps the rating and action labels to categories, likewise.
+	Taxonomy *taxonomy.Taxonomy
+	// Quality is the data-quality rules the syncs check the stocks with.
+	Quality quality.Rules
+
+	authStore auth.Store
+
+	// the consumers are built on first use since most handlers never call
+	// them and their configuration is only required by the ones that do.
+	pricesOnce sync.Once
+	prices     chart.PriceProvider
+	pricesErr  error
+	searchOnce sync.Once
+	search     search.Backend
+	searchErr  error
+	// sources are keyed by dataset, source replaces all of them.
+	sourcesMu sync.Mutex
+	sources   map[string]api.RatingsSource
+	source    api.RatingsSource
+}
+
+type Option func(*App)
+
+// WithRepository replaces the database of the config, the repository is also
+// the auth store when it implements it.
+func WithRepository(repo repository.StockRepository) Option {
+	return func(a *App) {
+		a.Repo = repo
+		if store, ok := repo.(auth.Store); ok {
+			a.authStore = store
+		}
+	}
+}
+
+func WithAuthStore(store auth.Store) Option {
+	return func(a *App) {
+		a.authStore = store
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *App) {
+		a.Logger = logger
+	}
+}
+
+func WithProfile(profile analysis.Profile) Option {
+	return func(a *App) {
+		a.Profile = profile
+	}
+}
+
+func WithPrices(provider chart.PriceProvider) Option {
+	return func(a *App) {
+		a.pricesOnce.Do(func() { a.prices = provider })
+	}
+}
+
+func WithSearch(backend search.Backend) Option {
+	return func(a *App) {
+		a.searchOnce.Do(func() { a.search = backend })
+	}
+}
+
+// WithSources replaces the ratings feeds of every dataset.
+func WithSources(source api.RatingsSource) Option {
+	return func(a *App) {
+		a.source = source
+	}
+}
+
+// New connects to the database of the config unless a repository is given,
+// and creates the auth tables on cold start so the first request doesn't fail.
+func New(ctx context.Context, cfg *config.Config, opts ...Option) (*App, error) {
+	profile, err := analysis.DefaultProfile().Override(cfg.ScoringLimit, cfg.ScoringWeights)
+	if err != nil {
+		return nil, err
+	}
+
+	brokerages := brokerage.Default()
+	if cfg.BrokeragesFile != "" {
+		if brokerages, err = brokerage.Load(cfg.BrokeragesFile); err != nil {
+			return nil, err
+		}
+	}
+
+	labels := taxonomy.Default()
+	if cfg.TaxonomyFile != "" {
+		if labels, err = taxonomy.Load(cfg.TaxonomyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := auth.ValidateScopes(cfg.AuthWebScopes); err != nil {
+		return nil, fmt.Errorf("AUTH_WEB_SCOPES: %w", err)
+	}
+
+	rules, err := quality.DefaultRules().Override(cfg.QualityRules)
+	if err != nil {
+		return nil, err
+	}
+	rules.MaxTargetRatio = cfg.QualityMaxTargetRatio
+	rules.OutlierThreshold = cfg.QualityOutlierThreshold
+	rules.HistoryLookback = time.Duration(cfg.QualityHistoryDays) * 24 * time.Hour
+
+	a := &App{
+		Config:     cfg,
+		Logger:     slog.Default(),
+		Profile:    profile,
+		Brokerages: brokerages,
+		Taxonomy:   labels,
+		Quality:    rules,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.Profile.Brokerages == nil {
+		a.Profile.Brokerages = a.Brokerages
+	}
+	if a.Profile.Taxonomy == nil {
+		a.Profile.Taxonomy = a.Taxonomy
+	}
+
+	if a.Repo == nil {
+		if cfg.DBURL == "" {
+			return nil, ErrMissingDBURL
+		}
+
+		repo, err := db.ConnectCockRoachDB(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := repo.CreateAuthTables(ctx); err != nil {
+			repo.Close()
+			return nil, err
+		}
+
+		a.Repo = repo
+		a.authStore = repo
+	}
+
+	a.Repo = repository.Instrumented(a.Repo)
+
+	if a.authStore != nil {
+		a.Auth = auth.New(a.authStore, auth.NewConfig(cfg))
+	}
+
+	a.Snapshots = snapshot.NewService(a.Repo, a.Profile, cfg.ReportingCurrency)
+
+	return a, nil
+}
+
+// Prices returns the chart providers of the config, in fallback order.
+func (a *App) Prices() (chart.PriceProvider, error) {
+	a.pricesOnce.Do(func() {
+		a.prices, a.pricesErr = chart.NewPriceProvider(a.Config, a.Repo)
+	})
+	return a.prices, a.pricesErr
+}
+
+// Search returns the search backend of the config.
+func (a *App) Search() (search.Backend, error) {
+	a.searchOnce.Do(func() {
+		a.search, a.searchErr = search.New(a.Config.SearchBackend, a.Repo)
+	})
+	return a.search, a.searchErr
+}
+
+// Sources returns the ratings feeds of the dataset, in priority order.
+func (a *App) Sources(dataset config.Dataset) (api.RatingsSource, error) {
+	if a.source != nil {
+		return a.source, nil
+	}
+
+	a.sourcesMu.Lock()
+	defer a.sourcesMu.Unlock()
+
+	if source, ok := a.sources[dataset.Name]; ok {
+		return source, nil
+	}
+
+	cfg := *a.Config
+	cfg.RatingsSources = dataset.Sources
+
+	source, err := api.NewRatingsSource(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", dataset.Name, err)
+	}
+
+	if a.sources == nil {
+		a.sources = map[string]api.RatingsSource{}
+	}
+	a.sources[dataset.Name] = source
+
+	return source, nil
+}
+
+func (a *App) Close() error {
+	return a.Repo.Close()
+}
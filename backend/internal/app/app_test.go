@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/internal/repository"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type fakeRepo struct {
+	repository.StockRepository
+}
+
+type fakeAuthRepo struct {
+	fakeRepo
+}
+
+func (r *fakeAuthRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) UpdateUsage(ctx context.Context, clientID string, fn func(*models.Usage)) error {
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		opts     []Option
+		wantErr  error
+		wantAuth bool
+	}{
+		{
+			name:    "Missing database URL",
+			cfg:     &config.Config{},
+			wantErr: ErrMissingDBURL,
+		},
+		{
+			name: "Repository without auth store",
+			cfg:  &config.Config{},
+			opts: []Option{WithRepository(&fakeRepo{})},
+		},
+		{
+			name:     "Repository implementing the auth store",
+			cfg:      &config.Config{},
+			opts:     []Option{WithRepository(&fakeAuthRepo{})},
+			wantAuth: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(context.Background(), tt.cfg, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if (a.Auth != nil) != tt.wantAuth {
+				t.Errorf("Auth = %v, want configured %v", a.Auth, tt.wantAuth)
+			}
+			if a.Snapshots == nil || a.Logger == nil {
+				t.Errorf("New left dependencies unset: %+v", a)
+			}
+		})
+	}
+}
+
+func TestPricesBuiltOnFirstUse(t *testing.T) {
+	cfg := &config.Config{PriceProviders: []string{chart.AlphaVantageProvider}}
+
+	a, err := New(context.Background(), cfg, WithRepository(&fakeRepo{}))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	// the chart config is only checked by the handlers that need it
+	if _, err := a.Prices(); !errors.Is(err, chart.ErrMissingAPIKey) {
+		t.Errorf("Prices error = %v, want %v", err, chart.ErrMissingAPIKey)
+	}
+}
+
+func TestSourcesPerDataset(t *testing.T) {
+	cfg := &config.Config{RatingsFile: "ratings.json"}
+
+	a, err := New(context.Background(), cfg, WithRepository(&fakeRepo{}))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	file := config.Dataset{Name: "backfill_2024", Sources: []string{api.FileSource}}
+	source, err := a.Sources(file)
+	if err != nil {
+		t.Fatalf("Sources returned unexpected error: %v", err)
+	}
+
+	if again, _ := a.Sources(file); again != source {
+		t.Errorf("Sources built the feeds of %s twice", file.Name)
+	}
+
+	// the API credentials are only required by the datasets reading the API
+	if _, err := a.Sources(config.Dataset{Name: "us_equities", Sources: []string{api.APISource}}); !errors.Is(err, api.ErrMissingAPIURL) {
+		t.Errorf("Sources error = %v, want %v", err, api.ErrMissingAPIURL)
+	}
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	APIKeyHeader        = "X-API-Key"
+	AuthorizationHeader = "Authorization"
+
+	bearerPrefix = "bearer "
+
+	// webClientPrefix keys the usage of the browsers by their address, for the
+	// tokens issued to them and for the issuing itself.
+	webClientPrefix = "web:"
+)
+
+// Scopes granted to keys and tokens, each endpoint requires one of them.
+const (
+	ScopeAll      = "*"
+	ScopeStocks   = "stocks:read"
+	ScopeAnalysis = "analysis:read"
+	ScopeMetrics  = "metrics:read"
+	ScopeChart    = "chart:read"
+)
+
+var (
+	Scopes = []string{ScopeAll, ScopeStocks, ScopeAnalysis, ScopeMetrics, ScopeChart}
+
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrQuotaExceeded   = errors.New("daily quota exceeded")
+	ErrNotConfigured   = errors.New("authentication is not configured")
+	ErrInvalidScope    = errors.New("invalid scope")
+)
+
+// Store persists the api keys and the usage of the clients.
+type Store interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	UpdateUsage(ctx context.Context, clientID string, fn func(*models.Usage)) error
+}
+
+type Config struct {
+	// JWTSecret verifies the HS256 bearer tokens, they're rejected when empty.
+	JWTSecret []byte
+	// JWTIssuer is the required iss claim when set.
+	JWTIssuer string
+	// WebScopes are granted to the tokens issued to the browsers, none are
+	// issued when empty.
+	WebScopes []string
+	// WebTokenTTL is how long the tokens issued to the browsers are valid.
+	WebTokenTTL time.Duration
+	// Defaults apply to the tokens and to the unset limits of the keys.
+	Defaults models.RateLimits
+}
+
+func NewConfig(cfg *config.Config) Config {
+	return Config{
+		JWTSecret:   []byte(cfg.AuthJWTSecret),
+		JWTIssuer:   cfg.AuthJWTIssuer,
+		WebScopes:   cfg.AuthWebScopes,
+		WebTokenTTL: cfg.AuthWebTokenTTL,
+		Defaults: models.RateLimits{
+			RatePerMinute: cfg.AuthRatePerMinute,
+			Burst:         cfg.AuthBurst,
+			DailyQuota:    cfg.AuthDailyQuota,
+		},
+	}
+}
+
+// Principal is the authenticated client of a request.
+type Principal struct {
+	// ClientID keys the usage of the client, prefixed by the credential kind.
+	ClientID string
+	Scopes   []string
+	Limits   models.RateLimits
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, ScopeAll) || slices.Contains(p.Scopes, scope)
+}
+
+type Authenticator struct {
+	store Store
+	cfg   Config
+	now   func() time.Time
+}
+
+func New(store Store, cfg Config) *Authenticator {
+	return &Authenticator{
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Authenticate resolves the api key of the X-API-Key header, or the key or
+// JWT of the Authorization bearer header.
+func (a *Authenticator) Authenticate(ctx context.Context, headers map[string]string) (*Principal, error) {
+	if key := Header(headers, APIKeyHeader); key != "" {
+		return a.authenticateKey(ctx, key)
+	}
+
+	authorization := Header(headers, AuthorizationHeader)
+	if authorization == "" {
+		return nil, fmt.Errorf("%w: missing api key or bearer token", ErrUnauthenticated)
+	}
+
+	if !strings.HasPrefix(strings.ToLower(authorization), bearerPrefix) {
+		return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrUnauthenticated)
+	}
+
+	token := strings.TrimSpace(authorization[len(bearerPrefix):])
+	if IsAPIKey(token) {
+		return a.authenticateKey(ctx, token)
+	}
+
+	return a.authenticateJWT(token)
+}
+
+func (a *Authenticator) authenticateKey(ctx context.Context, key string) (*Principal, error) {
+	apiKey, err := a.store.GetAPIKeyByHash(ctx, HashKey(key))
+	if errors.Is(err, db.ErrAPIKeyNotFound) {
+		return nil, fmt.Errorf("%w: invalid api key", ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		ClientID: "key:" + apiKey.ID,
+		Scopes:   apiKey.Scopes,
+		Limits:   withDefaults(apiKey.Limits, a.cfg.Defaults),
+	}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (*Principal, error) {
+	if len(a.cfg.JWTSecret) == 0 {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrUnauthenticated)
+	}
+
+	claims, err := parseJWT(token, a.cfg.JWTSecret, a.cfg.JWTIssuer, a.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		ClientID: "jwt:" + claims.Subject,
+		Scopes:   strings.Fields(claims.Scope),
+		Limits:   a.cfg.Defaults,
+	}, nil
+}
+
+// Authorize checks the principal holds the scope and takes a request from its
+// rate limit and daily quota. The decision is returned even when the request
+// is refused so the limits can be reported.
+func (a *Authenticator) Authorize(ctx context.Context, principal *Principal, scope string) (Decision, error) {
+	if !principal.HasScope(scope) {
+		return Decision{}, fmt.Errorf("%w: %s scope required", ErrForbidden, scope)
+	}
+
+	return a.take(ctx, principal.ClientID, principal.Limits)
+}
+
+// Token is a JWT issued to a browser.
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+	Scopes    []string
+}
+
+// IssueToken issues a short-lived JWT holding the web scopes to the browser at
+// the source address, so that the frontend never holds an api key. The tokens
+// of an address share its limits, and issuing one takes from the limits of the
+// address as well. The decision is returned even when the issuing is refused.
+func (a *Authenticator) IssueToken(ctx context.Context, sourceIP string) (Token, Decision, error) {
+	if len(a.cfg.JWTSecret) == 0 || len(a.cfg.WebScopes) == 0 {
+		return Token{}, Decision{}, fmt.Errorf("%w: no tokens are issued to the browsers", ErrNotConfigured)
+	}
+
+	if sourceIP == "" {
+		return Token{}, Decision{}, fmt.Errorf("%w: missing source address", ErrUnauthenticated)
+	}
+
+	subject := webClientPrefix + sourceIP
+
+	decision, err := a.take(ctx, subject, a.cfg.Defaults)
+	if err != nil {
+		return Token{}, decision, err
+	}
+
+	now := a.now()
+	expiresAt := now.Add(a.cfg.WebTokenTTL)
+
+	value, err := encodeJWT(claims{
+		Subject:   subject,
+		Issuer:    a.cfg.JWTIssuer,
+		Scope:     strings.Join(a.cfg.WebScopes, " "),
+		ExpiresAt: expiresAt.Unix(),
+		NotBefore: now.Unix(),
+	}, a.cfg.JWTSecret)
+	if err != nil {
+		return Token{}, decision, err
+	}
+
+	return Token{Value: value, ExpiresAt: expiresAt, Scopes: a.cfg.WebScopes}, decision, nil
+}
+
+// take spends a request of the limits of the client.
+func (a *Authenticator) take(ctx context.Context, clientID string, limits models.RateLimits) (Decision, error) {
+	var decision Decision
+	var refused error
+
+	err := a.store.UpdateUsage(ctx, clientID, func(usage *models.Usage) {
+		decision, refused = take(usage, limits, a.now())
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return decision, refused
+}
+
+// ValidateScopes checks every scope is a known one.
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// Header looks the name up case-insensitively since API Gateway keeps the
+// casing sent by the client.
+func Header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func withDefaults(limits, defaults models.RateLimits) models.RateLimits {
+	if limits.RatePerMinute == 0 {
+		limits.RatePerMinute = defaults.RatePerMinute
+	}
+	if limits.Burst == 0 {
+		limits.Burst = defaults.Burst
+	}
+	if limits.DailyQuota == 0 {
+		limits.DailyQuota = defaults.DailyQuota
+	}
+	return limits
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type fakeStore struct {
+	keys  map[string]*models.APIKey
+	usage map[string]*models.Usage
+}
+
+func newFakeStore(keys ...*models.APIKey) *fakeStore {
+	store := &fakeStore{keys: map[string]*models.APIKey{}, usage: map[string]*models.Usage{}}
+	for _, key := range keys {
+		store.keys[key.Hash] = key
+	}
+	return store
+}
+
+func (s *fakeStore) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, db.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *fakeStore) UpdateUsage(ctx context.Context, clientID string, fn func(*models.Usage)) error {
+	usage, ok := s.usage[clientID]
+	if !ok {
+		usage = &models.Usage{ClientID: clientID}
+		s.usage[clientID] = usage
+	}
+	fn(usage)
+	return nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	apiKey, key, err := NewAPIKey("dashboard", []string{ScopeStocks}, models.RateLimits{Burst: 5})
+	if err != nil {
+		t.Fatalf("NewAPIKey returned unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	token := signJWT(t, map[string]any{"alg": "HS256"}, claims{Subject: "reports", Scope: "metrics:read", ExpiresAt: now.Add(time.Hour).Unix()}, testSecret)
+
+	defaults := models.RateLimits{RatePerMinute: 60, Burst: 20, DailyQuota: 1000}
+	authenticator := New(newFakeStore(apiKey), Config{JWTSecret: testSecret, Defaults: defaults})
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		wantClientID string
+		wantLimits   models.RateLimits
+		wantErr      error
+	}{
+		{"API key header", map[string]string{"x-api-key": key}, "key:" + apiKey.ID, models.RateLimits{RatePerMinute: 60, Burst: 5, DailyQuota: 1000}, nil},
+		{"API key as bearer", map[string]string{"Authorization": "Bearer " + key}, "key:" + apiKey.ID, models.RateLimits{RatePerMinute: 60, Burst: 5, DailyQuota: 1000}, nil},
+		{"JWT", map[string]string{"authorization": "bearer " + token}, "jwt:reports", defaults, nil},
+		{"Unknown key", map[string]string{"X-API-Key": key + "x"}, "", models.RateLimits{}, ErrUnauthenticated},
+		{"Basic auth", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, "", models.RateLimits{}, ErrUnauthenticated},
+		{"No credentials", map[string]string{}, "", models.RateLimits{}, ErrUnauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), tt.headers)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate returned unexpected error: %v", err)
+			}
+
+			if principal.ClientID != tt.wantClientID || principal.Limits != tt.wantLimits {
+				t.Errorf("Expected %s with %+v, got %s with %+v", tt.wantClientID, tt.wantLimits, principal.ClientID, principal.Limits)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutJWTSecret(t *testing.T) {
+	authenticator := New(newFakeStore(), Config{})
+	token := signJWT(t, map[string]any{"alg": "HS256"}, claims{Subject: "reports", ExpiresAt: time.Now().Add(time.Hour).Unix()}, nil)
+
+	_, err := authenticator.Authenticate(context.Background(), map[string]string{"Authorization": "Bearer " + token})
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected tokens to be refused without a secret, got %v", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	authenticator := New(newFakeStore(), Config{})
+	principal := &Principal{ClientID: "key:abc", Scopes: []string{ScopeStocks}, Limits: models.RateLimits{RatePerMinute: 1, Burst: 1}}
+
+	if _, err := authenticator.Authorize(context.Background(), principal, ScopeChart); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected a missing scope to be forbidden, got %v", err)
+	}
+
+	if _, err := authenticator.Authorize(context.Background(), principal, ScopeStocks); err != nil {
+		t.Fatalf("Expected the first request to be allowed, got %v", err)
+	}
+
+	decision, err := authenticator.Authorize(context.Background(), principal, ScopeStocks)
+	if !errors.Is(err, ErrRateLimited) || decision.RetryAfter <= 0 {
+		t.Errorf("Expected the second request to be rate limited with a retry delay, got %v after %v", err, decision.RetryAfter)
+	}
+
+	admin := &Principal{ClientID: "key:admin", Scopes: []string{ScopeAll}}
+	if _, err := authenticator.Authorize(context.Background(), admin, ScopeChart); err != nil {
+		t.Errorf("Expected the wildcard scope to grant every scope, got %v", err)
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	apiKey, key, err := NewAPIKey("dashboard", []string{ScopeStocks, ScopeChart}, models.RateLimits{})
+	if err != nil {
+		t.Fatalf("NewAPIKey returned unexpected error: %v", err)
+	}
+
+	if !IsAPIKey(key) || apiKey.Hash != HashKey(key) || apiKey.Hash == key {
+		t.Errorf("Expected only the hash of %s to be stored, got %s", key, apiKey.Hash)
+	}
+
+	if _, _, err := NewAPIKey("dashboard", []string{"stocks:write"}, models.RateLimits{}); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("Expected unknown scopes to be refused, got %v", err)
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	store := newFakeStore()
+	authenticator := New(store, Config{
+		JWTSecret:   testSecret,
+		WebScopes:   []string{ScopeStocks, ScopeChart},
+		WebTokenTTL: 15 * time.Minute,
+		Defaults:    models.RateLimits{RatePerMinute: 60, Burst: 1, DailyQuota: 1000},
+	})
+
+	token, decision, err := authenticator.IssueToken(context.Background(), "203.0.113.7")
+	if err != nil {
+		t.Fatalf("IssueToken returned unexpected error: %v", err)
+	}
+	if decision.QuotaRemaining != 999 {
+		t.Errorf("Expected the decision of the address, got %+v", decision)
+	}
+
+	principal, err := authenticator.Authenticate(context.Background(), map[string]string{"Authorization": "Bearer " + token.Value})
+	if err != nil {
+		t.Fatalf("Authenticate returned unexpected error for the issued token: %v", err)
+	}
+	if principal.ClientID != "jwt:web:203.0.113.7" || !principal.HasScope(ScopeChart) || principal.HasScope(ScopeAnalysis) {
+		t.Errorf("Expected a principal of the address with the web scopes, got %+v", principal)
+	}
+
+	// the burst of the address is spent by the first token
+	if _, _, err := authenticator.IssueToken(context.Background(), "203.0.113.7"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected %v, got %v", ErrRateLimited, err)
+	}
+	if _, _, err := authenticator.IssueToken(context.Background(), "198.51.100.1"); err != nil {
+		t.Errorf("Expected another address to get a token, got %v", err)
+	}
+
+	disabled := New(store, Config{JWTSecret: testSecret})
+	if _, _, err := disabled.IssueToken(context.Background(), "203.0.113.7"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Expected %v without web scopes, got %v", ErrNotConfigured, err)
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+func signJWT(t *testing.T, header map[string]any, c claims, secret []byte) string {
+	t.Helper()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("failed to marshal header: %v", err)
+	}
+
+	claimsJSON, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %v", err)
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input, secret))
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	valid := claims{Subject: "dashboard", Issuer: "stockwise", Scope: "stocks:read chart:read", ExpiresAt: now.Add(time.Hour).Unix()}
+
+	expired := valid
+	expired.ExpiresAt = now.Unix()
+
+	noExpiry := valid
+	noExpiry.ExpiresAt = 0
+
+	notYetValid := valid
+	notYetValid.NotBefore = now.Add(time.Minute).Unix()
+
+	otherIssuer := valid
+	otherIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Valid", signJWT(t, hs256, valid, testSecret), false},
+		{"Wrong secret", signJWT(t, hs256, valid, []byte("other")), true},
+		{"Unsigned", signJWT(t, map[string]any{"alg": "none"}, valid, testSecret), true},
+		{"Expired", signJWT(t, hs256, expired, testSecret), true},
+		{"Without expiry", signJWT(t, hs256, noExpiry, testSecret), true},
+		{"Not valid yet", signJWT(t, hs256, notYetValid, testSecret), true},
+		{"Other issuer", signJWT(t, hs256, otherIssuer, testSecret), true},
+		{"Malformed", "not.a-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseJWT(tt.token, testSecret, "stockwise", now)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Expected an unauthenticated error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseJWT returned unexpected error: %v", err)
+			}
+
+			if c.Subject != valid.Subject || c.Scope != valid.Scope {
+				t.Errorf("Expected claims %+v, got %+v", valid, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	keyPrefix = "sk_"
+
+	keyIDBytes     = 6
+	keySecretBytes = 32
+)
+
+// NewAPIKey generates a key with the given scopes and limits. The returned
+// plaintext key is the only way to use it since only its hash is stored.
+func NewAPIKey(name string, scopes []string, limits models.RateLimits) (*models.APIKey, string, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomBytes(keyIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomBytes(keySecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyID := hex.EncodeToString(id)
+	key := keyPrefix + keyID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := &models.APIKey{
+		ID:     keyID,
+		Name:   name,
+		Hash:   HashKey(key),
+		Scopes: scopes,
+		Limits: limits,
+	}
+
+	return apiKey, key, nil
+}
+
+// HashKey is the stored form of a key. The keys are random so a fast hash is
+// enough to make a leaked table useless.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey tells the keys apart from the JWTs sent as bearer tokens.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, keyPrefix)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("error generating api key: %w", err)
+	}
+	return b, nil
+}
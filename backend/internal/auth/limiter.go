@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const quotaDayLayout = "2006-01-02"
+
+// Decision reports the limits of a client after a request, zero limits are
+// disabled ones.
+type Decision struct {
+	Limit          int
+	Remaining      int
+	QuotaLimit     int
+	QuotaRemaining int
+	// RetryAfter is set when the request is refused.
+	RetryAfter time.Duration
+}
+
+// Headers are the rate limit response headers of the decision.
+func (d Decision) Headers() map[string]string {
+	headers := map[string]string{}
+
+	if d.Limit > 0 {
+		headers["X-RateLimit-Limit"] = strconv.Itoa(d.Limit)
+		headers["X-RateLimit-Remaining"] = strconv.Itoa(d.Remaining)
+	}
+
+	if d.QuotaLimit > 0 {
+		headers["X-Quota-Limit"] = strconv.Itoa(d.QuotaLimit)
+		headers["X-Quota-Remaining"] = strconv.Itoa(d.QuotaRemaining)
+	}
+
+	if d.RetryAfter > 0 {
+		headers["Retry-After"] = strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds())))
+	}
+
+	return headers
+}
+
+// take refills the token bucket of the usage for the time elapsed since the
+// last request and spends a token and a request of the daily quota. Limits
+// that aren't positive are disabled.
+func take(usage *models.Usage, limits models.RateLimits, now time.Time) (Decision, error) {
+	now = now.UTC()
+
+	day := now.Format(quotaDayLayout)
+	if usage.QuotaDay != day {
+		usage.QuotaDay = day
+		usage.QuotaUsed = 0
+	}
+
+	bucket := limits.RatePerMinute > 0 && limits.Burst > 0
+	burst := float64(limits.Burst)
+	ratePerMinute := float64(limits.RatePerMinute)
+
+	switch {
+	case usage.RefilledAt.IsZero():
+		usage.Tokens = burst
+		usage.RefilledAt = now
+	case now.After(usage.RefilledAt):
+		usage.Tokens = min(burst, usage.Tokens+now.Sub(usage.RefilledAt).Minutes()*ratePerMinute)
+		usage.RefilledAt = now
+	}
+
+	decision := Decision{}
+	if bucket {
+		decision.Limit = limits.Burst
+	}
+	if limits.DailyQuota > 0 {
+		decision.QuotaLimit = limits.DailyQuota
+	}
+
+	report := func() {
+		if bucket {
+			decision.Remaining = int(usage.Tokens)
+		}
+		if limits.DailyQuota > 0 {
+			decision.QuotaRemaining = max(limits.DailyQuota-usage.QuotaUsed, 0)
+		}
+	}
+
+	if limits.DailyQuota > 0 && usage.QuotaUsed >= limits.DailyQuota {
+		report()
+		nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		decision.RetryAfter = nextDay.Sub(now)
+		return decision, ErrQuotaExceeded
+	}
+
+	if bucket && usage.Tokens < 1 {
+		report()
+		decision.RetryAfter = time.Duration((1 - usage.Tokens) / ratePerMinute * float64(time.Minute))
+		return decision, ErrRateLimited
+	}
+
+	if bucket {
+		usage.Tokens--
+	}
+	usage.QuotaUsed++
+
+	report()
+
+	return decision, nil
+}
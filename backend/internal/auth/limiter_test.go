@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	limits := models.RateLimits{RatePerMinute: 60, Burst: 2, DailyQuota: 10}
+
+	tests := []struct {
+		name           string
+		usage          models.Usage
+		limits         models.RateLimits
+		wantErr        error
+		wantRemaining  int
+		wantQuotaLeft  int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "New client starts with a full bucket",
+			usage:         models.Usage{},
+			limits:        limits,
+			wantRemaining: 1,
+			wantQuotaLeft: 9,
+		},
+		{
+			name:           "Empty bucket",
+			usage:          models.Usage{Tokens: 0.5, RefilledAt: now, QuotaDay: "2024-03-05", QuotaUsed: 3},
+			limits:         limits,
+			wantErr:        ErrRateLimited,
+			wantQuotaLeft:  7,
+			wantRetryAfter: 500 * time.Millisecond,
+		},
+		{
+			name:          "Bucket refilled by the elapsed time",
+			usage:         models.Usage{Tokens: 0, RefilledAt: now.Add(-1500 * time.Millisecond), QuotaDay: "2024-03-05", QuotaUsed: 3},
+			limits:        limits,
+			wantRemaining: 0,
+			wantQuotaLeft: 6,
+		},
+		{
+			name:          "Refill capped by the burst",
+			usage:         models.Usage{Tokens: 1, RefilledAt: now.Add(-time.Hour), QuotaDay: "2024-03-05"},
+			limits:        limits,
+			wantRemaining: 1,
+			wantQuotaLeft: 9,
+		},
+		{
+			name:           "Quota exhausted",
+			usage:          models.Usage{Tokens: 2, RefilledAt: now, QuotaDay: "2024-03-05", QuotaUsed: 10},
+			limits:         limits,
+			wantErr:        ErrQuotaExceeded,
+			wantRemaining:  2,
+			wantRetryAfter: 12 * time.Hour,
+		},
+		{
+			name:          "Quota reset on a new day",
+			usage:         models.Usage{Tokens: 2, RefilledAt: now, QuotaDay: "2024-03-04", QuotaUsed: 10},
+			limits:        limits,
+			wantRemaining: 1,
+			wantQuotaLeft: 9,
+		},
+		{
+			name:   "Disabled limits",
+			usage:  models.Usage{RefilledAt: now, QuotaDay: "2024-03-05", QuotaUsed: 100},
+			limits: models.RateLimits{RatePerMinute: -1, Burst: 2, DailyQuota: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := tt.usage
+			decision, err := take(&usage, tt.limits, now)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if decision.Remaining != tt.wantRemaining || decision.QuotaRemaining != tt.wantQuotaLeft {
+				t.Errorf("Expected %d tokens and %d requests left, got %d and %d",
+					tt.wantRemaining, tt.wantQuotaLeft, decision.Remaining, decision.QuotaRemaining)
+			}
+
+			if decision.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("Expected to retry after %v, got %v", tt.wantRetryAfter, decision.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestDecisionHeaders(t *testing.T) {
+	headers := Decision{Limit: 20, Remaining: 0, QuotaLimit: 100, QuotaRemaining: 5, RetryAfter: 1200 * time.Millisecond}.Headers()
+
+	want := map[string]string{
+		"X-RateLimit-Limit":     "20",
+		"X-RateLimit-Remaining": "0",
+		"X-Quota-Limit":         "100",
+		"X-Quota-Remaining":     "5",
+		"Retry-After":           "2",
+	}
+
+	for name, value := range want {
+		if headers[name] != value {
+			t.Errorf("Expected %s: %s, got %q", name, value, headers[name])
+		}
+	}
+
+	if len(Decision{}.Headers()) != 0 {
+		t.Error("Expected no headers for disabled limits")
+	}
+}
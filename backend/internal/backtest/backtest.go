@@ -0,0 +1,339 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/analysis"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	defaultTopN      = 50
+	defaultRebalance = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidPeriod  = errors.New("invalid backtest period")
+	ErrInvalidBarDate = errors.New("invalid bar date")
+)
+
+type Config struct {
+	Start     time.Time
+	End       time.Time
+	Rebalance time.Duration
+	Horizon   time.Duration
+	TopN      int
+	// Profile scores the portfolio, the default one when empty.
+	Profile analysis.Profile
+}
+
+type PeriodResult struct {
+	Date            string   `json:"date"`
+	Picks           []string `json:"picks"`
+	Priced          int      `json:"priced"`
+	Hits            int      `json:"hits"`
+	Return          float64  `json:"return"`
+	BenchmarkReturn float64  `json:"benchmark_return"`
+	HitRate         float64  `json:"hit_rate"`
+	Turnover        float64  `json:"turnover"`
+}
+
+type Report struct {
+	Periods           []*PeriodResult `json:"periods"`
+	HitRate           float64         `json:"hit_rate"`
+	AverageReturn     float64         `json:"average_return"`
+	BenchmarkReturn   float64         `json:"benchmark_return"`
+	ExcessReturn      float64         `json:"excess_return"`
+	MaxDrawdown       float64         `json:"max_drawdown"`
+	BenchmarkDrawdown float64         `json:"benchmark_drawdown"`
+	Turnover          float64         `json:"turnover"`
+}
+
+type priceSeries struct {
+	dates  []time.Time
+	closes []float64
+}
+
+// Run replays the rating history at every rebalance date between cfg.Start and
+// cfg.End, builds the top-N portfolio with the production scoring and measures
+// its forward return against an equal-weight benchmark of every rated ticker.
+func Run(cfg Config, history []*models.FormattedStock, bars []*models.DailyBar) (*Report, error) {
+	cfg = withDefaults(cfg)
+	if !cfg.End.After(cfg.Start) {
+		return nil, fmt.Errorf("%w: end %s must be after start %s", ErrInvalidPeriod,
+			cfg.End.Format(dateLayout), cfg.Start.Format(dateLayout))
+	}
+
+	prices, err := buildPriceSeries(bars)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	var previousPicks []string
+	totalHits, totalPriced := 0, 0
+
+	for date := cfg.Start; !date.After(cfg.End); date = date.Add(cfg.Rebalance) {
+		stocks, window := snapshotAsOf(history, date)
+		if len(stocks) == 0 {
+			continue
+		}
+
+		top := analysis.NewAnalysisWithHistory(stocks, window).WithProfile(cfg.Profile).AnalyzeTop(cfg.TopN)
+
+		picks := make([]string, 0, len(top.TopStocks))
+		for _, stock := range top.TopStocks {
+			picks = append(picks, stock.Ticker)
+		}
+
+		period := evaluatePeriod(date, date.Add(cfg.Horizon), picks, stocks, prices)
+		if previousPicks != nil {
+			period.Turnover = turnover(previousPicks, picks)
+		}
+		previousPicks = picks
+
+		totalHits += period.Hits
+		totalPriced += period.Priced
+
+		report.Periods = append(report.Periods, period)
+	}
+
+	summarize(report, totalHits, totalPriced)
+
+	return report, nil
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.TopN <= 0 {
+		cfg.TopN = defaultTopN
+	}
+	if cfg.Rebalance <= 0 {
+		cfg.Rebalance = defaultRebalance
+	}
+	if cfg.Horizon <= 0 {
+		cfg.Horizon = cfg.Rebalance
+	}
+	if cfg.Profile == (analysis.Profile{}) {
+		cfg.Profile = analysis.DefaultProfile()
+	}
+	return cfg
+}
+
+// snapshotAsOf rebuilds what the stocks table held at the given date, the
+// latest rating of every ticker already recorded then, along with the history
+// the momentum factors would have seen. A rating recorded after the date is
+// left out even when its time is earlier, as it wasn't known yet.
+func snapshotAsOf(history []*models.FormattedStock, date time.Time) ([]*models.FormattedStock, []*models.FormattedStock) {
+	latest := make(map[string]*models.FormattedStock)
+	var window []*models.FormattedStock
+	lookback := date.Add(-analysis.MomentumLookback)
+
+	for _, event := range history {
+		if event.Time.After(date) || (event.RecordedAt != nil && event.RecordedAt.After(date)) {
+			continue
+		}
+
+		if current, ok := latest[event.Ticker]; !ok || event.Time.After(current.Time) {
+			latest[event.Ticker] = event
+		}
+
+		if !event.Time.Before(lookback) {
+			window = append(window, event)
+		}
+	}
+
+	stocks := make([]*models.FormattedStock, 0, len(latest))
+	for _, stock := range latest {
+		stocks = append(stocks, stock)
+	}
+
+	sort.Slice(stocks, func(i, j int) bool {
+		return stocks[i].Ticker < stocks[j].Ticker
+	})
+
+	return stocks, window
+}
+
+func evaluatePeriod(from, to time.Time, picks []string, universe []*models.FormattedStock, prices map[string]*priceSeries) *PeriodResult {
+	period := &PeriodResult{
+		Date:  from.Format(dateLayout),
+		Picks: picks,
+	}
+
+	benchmarkTotal, benchmarkCount := 0.0, 0
+	for _, stock := range universe {
+		if ret, ok := forwardReturn(prices[stock.Ticker], from, to); ok {
+			benchmarkTotal += ret
+			benchmarkCount++
+		}
+	}
+
+	if benchmarkCount > 0 {
+		period.BenchmarkReturn = benchmarkTotal / float64(benchmarkCount)
+	}
+
+	total := 0.0
+	for _, ticker := range picks {
+		ret, ok := forwardReturn(prices[ticker], from, to)
+		if !ok {
+			continue
+		}
+
+		total += ret
+		period.Priced++
+		if ret > period.BenchmarkReturn {
+			period.Hits++
+		}
+	}
+
+	if period.Priced > 0 {
+		period.Return = total / float64(period.Priced)
+		period.HitRate = float64(period.Hits) / float64(period.Priced)
+	}
+
+	return period
+}
+
+func forwardReturn(series *priceSeries, from, to time.Time) (float64, bool) {
+	if series == nil {
+		return 0, false
+	}
+
+	start, ok := series.closeAsOf(from)
+	if !ok || start == 0 {
+		return 0, false
+	}
+
+	end, ok := series.closeAsOf(to)
+	if !ok {
+		return 0, false
+	}
+
+	return end/start - 1, true
+}
+
+// closeAsOf returns the close of the last bar on or before the given date.
+func (ps *priceSeries) closeAsOf(date time.Time) (float64, bool) {
+	idx := sort.Search(len(ps.dates), func(i int) bool {
+		return ps.dates[i].After(date)
+	})
+	if idx == 0 {
+		return 0, false
+	}
+	return ps.closes[idx-1], true
+}
+
+func buildPriceSeries(bars []*models.DailyBar) (map[string]*priceSeries, error) {
+	sorted := make([]*models.DailyBar, len(bars))
+	copy(sorted, bars)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Ticker != sorted[j].Ticker {
+			return sorted[i].Ticker < sorted[j].Ticker
+		}
+		return sorted[i].Date < sorted[j].Date
+	})
+
+	prices := make(map[string]*priceSeries)
+	for _, bar := range sorted {
+		date, err := time.Parse(dateLayout, bar.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s' for %s: %v", ErrInvalidBarDate, bar.Date, bar.Ticker, err)
+		}
+
+		series, ok := prices[bar.Ticker]
+		if !ok {
+			series = &priceSeries{}
+			prices[bar.Ticker] = series
+		}
+
+		series.dates = append(series.dates, date)
+		series.closes = append(series.closes, bar.Close)
+	}
+
+	return prices, nil
+}
+
+// turnover is the fraction of the new portfolio that was not held before.
+func turnover(previous, current []string) float64 {
+	if len(current) == 0 {
+		return 0
+	}
+
+	held := make(map[string]bool, len(previous))
+	for _, ticker := range previous {
+		held[ticker] = true
+	}
+
+	changed := 0
+	for _, ticker := range current {
+		if !held[ticker] {
+			changed++
+		}
+	}
+
+	return float64(changed) / float64(len(current))
+}
+
+func summarize(report *Report, totalHits, totalPriced int) {
+	if len(report.Periods) == 0 {
+		return
+	}
+
+	returns := make([]float64, 0, len(report.Periods))
+	benchmark := make([]float64, 0, len(report.Periods))
+	turnoverTotal := 0.0
+
+	for _, period := range report.Periods {
+		returns = append(returns, period.Return)
+		benchmark = append(benchmark, period.BenchmarkReturn)
+		turnoverTotal += period.Turnover
+	}
+
+	report.AverageReturn = mean(returns)
+	report.BenchmarkReturn = mean(benchmark)
+	report.ExcessReturn = report.AverageReturn - report.BenchmarkReturn
+	report.MaxDrawdown = maxDrawdown(returns)
+	report.BenchmarkDrawdown = maxDrawdown(benchmark)
+
+	if len(report.Periods) > 1 {
+		report.Turnover = turnoverTotal / float64(len(report.Periods)-1)
+	}
+
+	if totalPriced > 0 {
+		report.HitRate = float64(totalHits) / float64(totalPriced)
+	}
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total / float64(len(values))
+}
+
+// maxDrawdown compounds the period returns into an equity curve and returns its
+// largest peak-to-trough decline as a positive fraction.
+func maxDrawdown(returns []float64) float64 {
+	equity, peak, drawdown := 1.0, 1.0, 0.0
+	for _, ret := range returns {
+		equity *= 1 + ret
+		if equity > peak {
+			peak = equity
+		}
+		if dd := (peak - equity) / peak; dd > drawdown {
+			drawdown = dd
+		}
+	}
+	return drawdown
+}
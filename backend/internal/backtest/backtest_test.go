@@ -0,0 +1,169 @@
+package backtest
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func bar(ticker, date string, close float64) *models.DailyBar {
+	return &models.DailyBar{
+		Ticker:    ticker,
+		DailyData: models.DailyData{Date: date, Close: close},
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+
+	history := []*models.FormattedStock{
+		{Ticker: "AAPL", TargetFrom: "100", TargetTo: "150", Action: "upgraded by", Brokerage: "Barclays", RatingFrom: "hold", RatingTo: "buy", Time: start.Add(-24 * time.Hour)},
+		{Ticker: "MSFT", TargetFrom: "200", TargetTo: "150", Action: "downgraded by", Brokerage: "Citigroup", RatingFrom: "buy", RatingTo: "sell", Time: start.Add(-48 * time.Hour)},
+		{Ticker: "GOOG", TargetFrom: "100", TargetTo: "100", Action: "reiterated by", Brokerage: "Small Firm", RatingFrom: "hold", RatingTo: "hold", Time: start.Add(-72 * time.Hour)},
+		{Ticker: "AAPL", TargetFrom: "150", TargetTo: "100", Action: "downgraded by", Brokerage: "Barclays", RatingFrom: "buy", RatingTo: "sell", Time: start.Add(24 * time.Hour)},
+	}
+
+	bars := []*models.DailyBar{
+		bar("AAPL", "2024-01-08", 100), bar("AAPL", "2024-01-15", 110), bar("AAPL", "2024-01-22", 99),
+		bar("MSFT", "2024-01-08", 100), bar("MSFT", "2024-01-15", 90), bar("MSFT", "2024-01-22", 90),
+		bar("GOOG", "2024-01-08", 100), bar("GOOG", "2024-01-15", 100), bar("GOOG", "2024-01-22", 110),
+	}
+
+	report, err := Run(Config{Start: start, End: end, TopN: 1}, history, bars)
+	if err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	if len(report.Periods) != 2 {
+		t.Fatalf("Expected 2 periods, got %d", len(report.Periods))
+	}
+
+	first := report.Periods[0]
+	if len(first.Picks) != 1 || first.Picks[0] != "AAPL" {
+		t.Errorf("Expected AAPL to be picked first, got %v", first.Picks)
+	}
+	if math.Abs(first.Return-0.10) > 1e-9 {
+		t.Errorf("Expected first period return 0.10, got %v", first.Return)
+	}
+	if math.Abs(first.BenchmarkReturn-0.0) > 1e-9 {
+		t.Errorf("Expected first period benchmark return 0, got %v", first.BenchmarkReturn)
+	}
+	if first.HitRate != 1 {
+		t.Errorf("Expected first period hit rate 1, got %v", first.HitRate)
+	}
+
+	second := report.Periods[1]
+	if len(second.Picks) != 1 || second.Picks[0] == "AAPL" {
+		t.Errorf("Expected AAPL to be dropped after its downgrade, got %v", second.Picks)
+	}
+	if second.Turnover != 1 {
+		t.Errorf("Expected full turnover on the second period, got %v", second.Turnover)
+	}
+
+	if report.Turnover != 1 {
+		t.Errorf("Expected average turnover 1, got %v", report.Turnover)
+	}
+}
+
+func TestRunInvalidPeriod(t *testing.T) {
+	now := time.Now()
+	_, err := Run(Config{Start: now, End: now.Add(-time.Hour)}, nil, nil)
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("Expected ErrInvalidPeriod, got %v", err)
+	}
+}
+
+func TestSnapshotAsOf(t *testing.T) {
+	date := time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)
+	backfilled := date.Add(30 * 24 * time.Hour)
+	recorded := date.Add(-24 * time.Hour)
+
+	history := []*models.FormattedStock{
+		{Ticker: "AAPL", RatingTo: "buy", Time: date.Add(-48 * time.Hour), RecordedAt: &recorded},
+		{Ticker: "AAPL", RatingTo: "sell", Time: date.Add(-24 * time.Hour), RecordedAt: &backfilled},
+		{Ticker: "MSFT", RatingTo: "buy", Time: date.Add(-24 * time.Hour), RecordedAt: &backfilled},
+		{Ticker: "GOOG", RatingTo: "hold", Time: date.Add(-365 * 24 * time.Hour)},
+	}
+
+	stocks, window := snapshotAsOf(history, date)
+
+	if len(stocks) != 2 || stocks[0].Ticker != "AAPL" || stocks[1].Ticker != "GOOG" {
+		t.Fatalf("Expected the stocks recorded by the date, AAPL and GOOG, got %v", stocks)
+	}
+	if stocks[0].RatingTo != "buy" {
+		t.Errorf("Expected the rating of AAPL recorded by the date, got %s", stocks[0].RatingTo)
+	}
+	if len(window) != 1 {
+		t.Errorf("Expected the momentum window to hold the one recent rating recorded by the date, got %d", len(window))
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []float64
+		want    float64
+	}{
+		{"No periods", []float64{}, 0},
+		{"Only gains", []float64{0.1, 0.2}, 0},
+		{"Single loss", []float64{0.25, -0.2}, 0.2},
+		{"Recovery after loss", []float64{-0.5, 1.0, -0.1}, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxDrawdown(tt.returns); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("maxDrawdown(%v) = %v, want %v", tt.returns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTurnover(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		current  []string
+		want     float64
+	}{
+		{"Same portfolio", []string{"AAPL", "MSFT"}, []string{"MSFT", "AAPL"}, 0},
+		{"Half replaced", []string{"AAPL", "MSFT"}, []string{"AAPL", "GOOG"}, 0.5},
+		{"Empty portfolio", []string{"AAPL"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := turnover(tt.previous, tt.current); got != tt.want {
+				t.Errorf("turnover() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloseAsOf(t *testing.T) {
+	prices, err := buildPriceSeries([]*models.DailyBar{
+		bar("AAPL", "2024-01-10", 102),
+		bar("AAPL", "2024-01-08", 100),
+	})
+	if err != nil {
+		t.Fatalf("buildPriceSeries returned unexpected error: %v", err)
+	}
+
+	series := prices["AAPL"]
+
+	if _, ok := series.closeAsOf(time.Date(2024, time.January, 7, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("Expected no close before the first bar")
+	}
+
+	if got, _ := series.closeAsOf(time.Date(2024, time.January, 9, 0, 0, 0, 0, time.UTC)); got != 100 {
+		t.Errorf("Expected close 100 carried forward, got %v", got)
+	}
+
+	if got, _ := series.closeAsOf(time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC)); got != 102 {
+		t.Errorf("Expected close 102 on the bar date, got %v", got)
+	}
+}
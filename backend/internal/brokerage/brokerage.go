@@ -0,0 +1,286 @@
+// Package brokerage names the brokerages of the feeds canonically, so that
+// "JP Morgan", "J.P. Morgan" and "JPMorgan Chase & Co." count as one.
+package brokerage
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// minSimilarity is how close a name must be to an alias to be matched as a
+// misspelling of it.
+const minSimilarity = 0.85
+
+// minFuzzyLength keeps the short keys such as "ubs" or "rbc" to exact
+// matches, a single typo in them is another brokerage as likely as not.
+const minFuzzyLength = 5
+
+var (
+	ErrInvalidBrokerage = errors.New("invalid brokerage")
+	ErrDuplicateAlias   = errors.New("duplicate brokerage alias")
+
+	//go:embed brokerages.json
+	brokeragesJSON []byte
+
+	// suffixes are the corporate words dropped from the end of the names.
+	suffixes = []string{
+		"ag", "aktiengesellschaft", "and", "co", "companies", "company", "corp", "corporation", "group", "holdings",
+		"inc", "incorporated", "llc", "lp", "ltd", "limited", "plc", "sa",
+	}
+
+	defaultRegistry = sync.OnceValue(func() *Registry {
+		var brokerages []Brokerage
+		if err := json.Unmarshal(brokeragesJSON, &brokerages); err != nil {
+			panic(fmt.Sprintf("brokerages.json: %v", err))
+		}
+
+		registry, err := NewRegistry(brokerages)
+		if err != nil {
+			panic(fmt.Sprintf("brokerages.json: %v", err))
+		}
+
+		return registry
+	})
+)
+
+// Brokerage is a canonical brokerage and the other names the feeds use for it.
+type Brokerage struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Top brokerages weigh more in the stock scores.
+	Top bool `json:"top,omitempty"`
+}
+
+// Registry maps the names of the feeds to their canonical brokerage. It is
+// safe for concurrent use.
+type Registry struct {
+	brokerages map[string]Brokerage
+	keys       map[string]string
+
+	mu    sync.Mutex
+	fuzzy map[string]string
+}
+
+// Default returns the registry of the brokerages.json embedded in the binary.
+func Default() *Registry {
+	return defaultRegistry()
+}
+
+// NewRegistry indexes the brokerages by their name and aliases, an alias may
+// not name two brokerages.
+func NewRegistry(brokerages []Brokerage) (*Registry, error) {
+	r := &Registry{
+		brokerages: make(map[string]Brokerage, len(brokerages)),
+		keys:       map[string]string{},
+		fuzzy:      map[string]string{},
+	}
+
+	for _, b := range brokerages {
+		b.Name = strings.TrimSpace(b.Name)
+		if key(b.Name) == "" {
+			return nil, fmt.Errorf("%w: empty name", ErrInvalidBrokerage)
+		}
+		if _, ok := r.brokerages[b.Name]; ok {
+			return nil, fmt.Errorf("%w: %q is listed twice", ErrInvalidBrokerage, b.Name)
+		}
+		r.brokerages[b.Name] = b
+
+		for _, name := range append([]string{b.Name}, b.Aliases...) {
+			k := key(name)
+			if k == "" {
+				continue
+			}
+			if other, ok := r.keys[k]; ok && other != b.Name {
+				return nil, fmt.Errorf("%w: %q names both %s and %s", ErrDuplicateAlias, name, other, b.Name)
+			}
+			r.keys[k] = b.Name
+		}
+	}
+
+	return r, nil
+}
+
+// Load reads a JSON list of brokerages and merges it into the default ones,
+// an entry replaces the default brokerage of the same name.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read brokerages: %w", err)
+	}
+
+	var overrides []Brokerage
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBrokerage, path, err)
+	}
+
+	brokerages := maps.Clone(Default().brokerages)
+	for _, b := range overrides {
+		brokerages[strings.TrimSpace(b.Name)] = b
+	}
+
+	return NewRegistry(slices.SortedFunc(maps.Values(brokerages), func(a, b Brokerage) int {
+		return strings.Compare(a.Name, b.Name)
+	}))
+}
+
+// Canonical returns the canonical name of the brokerage, matching the aliases
+// regardless of case, punctuation and corporate suffixes, then misspellings
+// of them. Unknown names are returned trimmed with false.
+func (r *Registry) Canonical(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+
+	k := key(name)
+	if k == "" {
+		return name, false
+	}
+
+	if canonical, ok := r.keys[k]; ok {
+		return canonical, true
+	}
+
+	if canonical := r.closest(k); canonical != "" {
+		return canonical, true
+	}
+
+	return name, false
+}
+
+// IsTop reports whether the brokerage is one of the top ones, under any of its
+// names.
+func (r *Registry) IsTop(name string) bool {
+	canonical, ok := r.Canonical(name)
+	return ok && r.brokerages[canonical].Top
+}
+
+// Known reports whether name is the canonical name of a brokerage.
+func (r *Registry) Known(name string) bool {
+	_, ok := r.brokerages[name]
+	return ok
+}
+
+// Names returns the canonical names in alphabetical order.
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.brokerages))
+}
+
+// closest returns the brokerage of the alias most similar to the key, or ""
+// when none is similar enough or two are equally so. The lookups are
+// memoized, the feeds repeat the same few names on every page.
+func (r *Registry) closest(k string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if canonical, ok := r.fuzzy[k]; ok {
+		return canonical
+	}
+
+	var best string
+	if len(k) >= minFuzzyLength {
+		bestSimilarity := minSimilarity
+		for alias, canonical := range r.keys {
+			if len(alias) < minFuzzyLength {
+				continue
+			}
+
+			switch similarity := similarity(k, alias); {
+			case similarity > bestSimilarity:
+				best, bestSimilarity = canonical, similarity
+			case similarity == bestSimilarity && best != "" && best != canonical:
+				best = ""
+			}
+		}
+	}
+
+	r.fuzzy[k] = best
+	return best
+}
+
+// key lowercases the name, spells out "&", drops the punctuation, a leading
+// "the" and the corporate suffixes, and joins the words, so "J.P. Morgan" and
+// "JPMorgan" share a key.
+func key(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+
+	var joined []string
+	for _, word := range words {
+		if word = strings.ReplaceAll(word, ".", ""); word != "" {
+			joined = append(joined, word)
+		}
+	}
+
+	if len(joined) > 1 && joined[0] == "the" {
+		joined = joined[1:]
+	}
+	for len(joined) > 1 && slices.Contains(suffixes, joined[len(joined)-1]) {
+		joined = joined[:len(joined)-1]
+	}
+
+	return strings.Join(joined, "")
+}
+
+// similarity is one minus the edit distance of a and b relative to the longer
+// of them.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// Unmapped counts the names of a sync missing from the registry, to be added
+// as aliases.
+type Unmapped map[string]int
+
+// Add counts the name when the registry has no brokerage for it.
+func (u Unmapped) Add(r *Registry, name string) {
+	if name != "" && !r.Known(name) {
+		u[name]++
+	}
+}
+
+// Names returns the names from the most to the least frequent.
+func (u Unmapped) Names() []string {
+	return slices.SortedFunc(maps.Keys(u), func(a, b string) int {
+		if u[a] != u[b] {
+			return u[b] - u[a]
+		}
+		return strings.Compare(a, b)
+	})
+}
@@ -0,0 +1,124 @@
+package brokerage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	registry := Default()
+
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"Canonical name", "JPMorgan Chase & Co.", "JPMorgan Chase & Co.", true},
+		{"Alias", "JP Morgan", "JPMorgan Chase & Co.", true},
+		{"Alias with punctuation", "J.P. Morgan", "JPMorgan Chase & Co.", true},
+		{"Corporate suffixes", "Goldman Sachs", "The Goldman Sachs Group", true},
+		{"Spelled out ampersand", "Wells Fargo and Company", "Wells Fargo & Company", true},
+		{"Case and spaces", "  morgan STANLEY ", "Morgan Stanley", true},
+		{"Misspelling", "Goldmann Sachs", "The Goldman Sachs Group", true},
+		{"Short keys match exactly", "UBX", "UBX", false},
+		{"Unknown", "  Acme Research ", "Acme Research", false},
+		{"Empty", " ", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := registry.Canonical(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Canonical(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsTop(t *testing.T) {
+	registry := Default()
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"Morgan Stanley", true},
+		{"Goldman Sachs", true},
+		{"BofA Securities", true},
+		{"Needham & Company LLC", false},
+		{"Acme Research", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := registry.IsTop(tt.input); got != tt.want {
+				t.Errorf("IsTop(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name       string
+		brokerages []Brokerage
+		wantErr    error
+	}{
+		{"Valid", []Brokerage{{Name: "Acme"}, {Name: "Globex", Aliases: []string{"Globex Capital"}}}, nil},
+		{"Empty name", []Brokerage{{Name: " "}}, ErrInvalidBrokerage},
+		{"Listed twice", []Brokerage{{Name: "Acme"}, {Name: "Acme"}}, ErrInvalidBrokerage},
+		{"Alias of two brokerages", []Brokerage{{Name: "Acme", Aliases: []string{"Globex Inc."}}, {Name: "Globex"}},
+			ErrDuplicateAlias},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(tt.brokerages); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewRegistry error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brokerages.json")
+	overrides := `[
+		{"name": "Needham & Company LLC", "aliases": ["Needham"], "top": true},
+		{"name": "Acme Research", "aliases": ["Acme"]}
+	]`
+	if err := os.WriteFile(path, []byte(overrides), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+
+	if !registry.IsTop("Needham") {
+		t.Error("overridden brokerage is not top")
+	}
+	if got, _ := registry.Canonical("acme"); got != "Acme Research" {
+		t.Errorf("Canonical(acme) = %q, want Acme Research", got)
+	}
+	if !registry.IsTop("JP Morgan") {
+		t.Error("default brokerage missing after Load")
+	}
+}
+
+func TestUnmapped(t *testing.T) {
+	registry := Default()
+
+	unmapped := Unmapped{}
+	for _, name := range []string{"Acme", "Morgan Stanley", "Globex", "Acme", ""} {
+		unmapped.Add(registry, name)
+	}
+
+	got := unmapped.Names()
+	if len(got) != 2 || got[0] != "Acme" || got[1] != "Globex" {
+		t.Errorf("Names = %v, want [Acme Globex]", got)
+	}
+}
@@ -1,37 +1,51 @@
 package chart
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 
 	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
+// chartConsumer is the Alpha Vantage price provider.
 type chartConsumer struct {
 	client *http.Client
+	apiURL string
 	apiKey string
 }
 
 func NewChartConsumer(cfg *config.Config) *chartConsumer {
 	return &chartConsumer{
-		client: &http.Client{},
+		client: telemetry.NewHTTPClient(cfg.UpstreamTimeout),
+		apiURL: cfg.AlphaVantageURL,
 		apiKey: cfg.APIKEY,
 	}
 }
 
-func (ac *chartConsumer) FetchData(ticker string) ([]models.DailyData, error) {
+func (ac *chartConsumer) Name() string {
+	return AlphaVantageProvider
+}
+
+func (ac *chartConsumer) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
 	var timeSeries []models.DailyData
 
-	stockData, err := ac.doRequest(ticker)
+	stockData, err := ac.doRequest(ctx, ticker)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching stocks: %w", err)
 	}
 
+	if len(stockData.TimeSeriesDaily) == 0 {
+		return nil, fmt.Errorf("%w: alpha vantage returned no time series for %s", ErrNoData, ticker)
+	}
+
 	for date, data := range stockData.TimeSeriesDaily {
 		open, _ := strconv.ParseFloat(data.Open, 64)
 		high, _ := strconv.ParseFloat(data.High, 64)
@@ -58,17 +72,21 @@ func (ac *chartConsumer) FetchData(ticker string) ([]models.DailyData, error) {
 	return timeSeries, nil
 }
 
-func (ac *chartConsumer) doRequest(ticker string) (*models.StockData, error) {
+func (ac *chartConsumer) doRequest(ctx context.Context, ticker string) (*models.StockData, error) {
+
+	query := url.Values{}
+	query.Set("function", "TIME_SERIES_DAILY")
+	query.Set("symbol", ticker)
+	query.Set("apikey", ac.apiKey)
 
-	url := fmt.Sprintf("https://www.alphavantage.co/query?function=TIME_SERIES_DAILY&symbol=%s&apikey=%s", ticker, ac.apiKey)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ac.apiURL+"?"+query.Encode(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	resp, err := ac.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error executing request: %w", err)
+		return nil, fmt.Errorf("%w: error executing request: %v", ErrUpstreamUnavailable, err)
 	}
 
 	defer resp.Body.Close()
@@ -79,7 +97,7 @@ func (ac *chartConsumer) doRequest(ticker string) (*models.StockData, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api returned status code: %d and body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%w: api returned status code: %d and body: %s", ErrUpstreamUnavailable, resp.StatusCode, string(body))
 	}
 
 	var stockData models.StockData
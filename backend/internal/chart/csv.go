@@ -0,0 +1,139 @@
+package chart
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+var (
+	csvColumns = []string{"date", "open", "high", "low", "close", "volume"}
+
+	ErrInvalidTicker = errors.New("invalid ticker")
+	ErrMissingColumn = errors.New("missing csv column")
+)
+
+// csvProvider reads the bars of every ticker from <dir>/<TICKER>.csv with a
+// header row holding the date, open, high, low, close and volume columns.
+type csvProvider struct {
+	dir string
+}
+
+func NewCSVProvider(dir string) *csvProvider {
+	return &csvProvider{
+		dir: dir,
+	}
+}
+
+func (cp *csvProvider) Name() string {
+	return CSVProvider
+}
+
+func (cp *csvProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if ticker == "" || strings.ContainsAny(ticker, `/\.`) {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidTicker, ticker)
+	}
+
+	file, err := os.Open(filepath.Join(cp.dir, ticker+".csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: no csv file for %s", ErrNoData, ticker)
+		}
+		return nil, fmt.Errorf("error opening csv file: %w", err)
+	}
+
+	defer file.Close()
+
+	timeSeries, err := ReadCSV(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(timeSeries) == 0 {
+		return nil, fmt.Errorf("%w: the csv file of %s has no rows", ErrNoData, ticker)
+	}
+
+	return timeSeries, nil
+}
+
+// ReadCSV reads the bars of a csv with a date, open, high, low, close and volume
+// header, sorted from oldest to newest.
+func ReadCSV(r io.Reader) ([]models.DailyData, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range csvColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, column)
+		}
+	}
+
+	var timeSeries []models.DailyData
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv record: %w", err)
+		}
+
+		dailyData, err := parseRecord(record, index)
+		if err != nil {
+			return nil, err
+		}
+
+		timeSeries = append(timeSeries, dailyData)
+	}
+
+	sort.Slice(timeSeries, func(i, j int) bool {
+		return timeSeries[i].Date < timeSeries[j].Date
+	})
+
+	return timeSeries, nil
+}
+
+func parseRecord(record []string, index map[string]int) (models.DailyData, error) {
+	prices := make(map[string]float64, 4)
+	for _, column := range []string{"open", "high", "low", "close"} {
+		value, err := strconv.ParseFloat(record[index[column]], 64)
+		if err != nil {
+			return models.DailyData{}, fmt.Errorf("error parsing %s '%s': %w", column, record[index[column]], err)
+		}
+		prices[column] = value
+	}
+
+	volume, err := strconv.ParseInt(record[index["volume"]], 10, 64)
+	if err != nil {
+		return models.DailyData{}, fmt.Errorf("error parsing volume '%s': %w", record[index["volume"]], err)
+	}
+
+	return models.DailyData{
+		Open:   prices["open"],
+		High:   prices["high"],
+		Low:    prices["low"],
+		Close:  prices["close"],
+		Volume: volume,
+		Date:   record[index["date"]],
+	}, nil
+}
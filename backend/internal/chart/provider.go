@@ -0,0 +1,128 @@
+package chart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	AlphaVantageProvider = "alphavantage"
+	CSVProvider          = "csv"
+	StoreProvider        = "store"
+)
+
+var (
+	ErrNoData = errors.New("no price data")
+	// ErrUpstreamUnavailable is returned when a remote provider can't be reached
+	// or answers with an error status.
+	ErrUpstreamUnavailable = errors.New("price provider unavailable")
+	ErrUnknownProvider     = errors.New("unknown price provider")
+	ErrNoProviders         = errors.New("no price providers configured")
+	ErrMissingAPIKey       = errors.New("api key cannot be empty")
+	ErrMissingCSVDir       = errors.New("csv directory cannot be empty")
+	ErrMissingBarsTable    = errors.New("bars table cannot be empty")
+)
+
+// PriceProvider returns the daily bars of a ticker sorted from oldest to newest.
+type PriceProvider interface {
+	Name() string
+	FetchData(ctx context.Context, ticker string) ([]models.DailyData, error)
+}
+
+type fallbackProvider struct {
+	providers []PriceProvider
+}
+
+// NewPriceProvider builds the providers listed in cfg.PriceProviders and tries
+// them in that order until one of them returns data. The store provider reads
+// the bars from store, and when it is listed the bars returned by the other
+// providers are written to store as they are fetched.
+func NewPriceProvider(cfg *config.Config, store BarStore) (PriceProvider, error) {
+	if len(cfg.PriceProviders) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	recording := slices.Contains(cfg.PriceProviders, StoreProvider)
+
+	var providers []PriceProvider
+	for _, name := range cfg.PriceProviders {
+		provider, err := newProvider(name, cfg, store)
+		if err != nil {
+			return nil, err
+		}
+		if recording && name != StoreProvider {
+			provider = NewRecordingProvider(provider, store, cfg.BarsTable)
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	return NewFallbackProvider(providers...), nil
+}
+
+func newProvider(name string, cfg *config.Config, store BarStore) (PriceProvider, error) {
+	switch name {
+	case AlphaVantageProvider:
+		if cfg.APIKEY == "" {
+			return nil, ErrMissingAPIKey
+		}
+		return NewChartConsumer(cfg), nil
+
+	case CSVProvider:
+		if cfg.PriceCSVDir == "" {
+			return nil, ErrMissingCSVDir
+		}
+		return NewCSVProvider(cfg.PriceCSVDir), nil
+
+	case StoreProvider:
+		if cfg.BarsTable == "" {
+			return nil, ErrMissingBarsTable
+		}
+		return NewStoreProvider(store, cfg.BarsTable), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+}
+
+func NewFallbackProvider(providers ...PriceProvider) *fallbackProvider {
+	return &fallbackProvider{
+		providers: providers,
+	}
+}
+
+func (fp *fallbackProvider) Name() string {
+	return "fallback"
+}
+
+func (fp *fallbackProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	var errs []error
+	for _, provider := range fp.providers {
+		data, err := provider.FetchData(ctx, ticker)
+		if err == nil && len(data) > 0 {
+			return data, nil
+		}
+
+		if err == nil {
+			err = fmt.Errorf("%w for %s", ErrNoData, ticker)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		slog.WarnContext(ctx, "price provider failed", "provider", provider.Name(), "ticker", ticker, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	return nil, fmt.Errorf("all price providers failed: %w", errors.Join(errs...))
+}
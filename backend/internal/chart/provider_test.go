@@ -0,0 +1,227 @@
+package chart
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type stubProvider struct {
+	name string
+	data []models.DailyData
+	err  error
+}
+
+func (sp *stubProvider) Name() string {
+	return sp.name
+}
+
+func (sp *stubProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	return sp.data, sp.err
+}
+
+func TestAlphaVantageFetchData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("symbol") != "AAPL" {
+			t.Errorf("Expected symbol AAPL, got %s", r.URL.Query().Get("symbol"))
+		}
+		if r.URL.Query().Get("apikey") != "test-key" {
+			t.Errorf("Expected apikey test-key, got %s", r.URL.Query().Get("apikey"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Time Series (Daily)": {
+			"2024-01-09": {"1. open": "101", "2. high": "103", "3. low": "100", "4. close": "102", "5. volume": "2000"},
+			"2024-01-08": {"1. open": "99", "2. high": "101", "3. low": "98", "4. close": "100", "5. volume": "1000"}
+		}}`))
+	}))
+	defer server.Close()
+
+	consumer := NewChartConsumer(&config.Config{APIKEY: "test-key", AlphaVantageURL: server.URL})
+
+	data, err := consumer.FetchData(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("FetchData returned unexpected error: %v", err)
+	}
+
+	if len(data) != 2 || data[0].Date != "2024-01-08" || data[1].Close != 102 {
+		t.Errorf("Unexpected time series: %+v", data)
+	}
+}
+
+func TestAlphaVantageNoData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Note": "API call frequency exceeded"}`))
+	}))
+	defer server.Close()
+
+	consumer := NewChartConsumer(&config.Config{APIKEY: "test-key", AlphaVantageURL: server.URL})
+
+	_, err := consumer.FetchData(context.Background(), "AAPL")
+	if !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData, got %v", err)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := `Date,Open,High,Low,Close,Volume
+2024-01-09,101,103,100,102,2000
+2024-01-08,99,101,98,100,1000
+`
+	data, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV returned unexpected error: %v", err)
+	}
+
+	if len(data) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(data))
+	}
+
+	if data[0].Date != "2024-01-08" || data[0].Close != 100 || data[1].Volume != 2000 {
+		t.Errorf("Unexpected time series: %+v", data)
+	}
+
+	_, err = ReadCSV(strings.NewReader("date,open,close\n2024-01-08,1,2\n"))
+	if !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Expected ErrMissingColumn, got %v", err)
+	}
+}
+
+func TestCSVProvider(t *testing.T) {
+	dir := t.TempDir()
+	content := "date,open,high,low,close,volume\n2024-01-08,99,101,98,100,1000\n"
+	if err := os.WriteFile(filepath.Join(dir, "AAPL.csv"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	provider := NewCSVProvider(dir)
+
+	data, err := provider.FetchData(context.Background(), "aapl")
+	if err != nil {
+		t.Fatalf("FetchData returned unexpected error: %v", err)
+	}
+	if len(data) != 1 {
+		t.Errorf("Expected 1 row, got %d", len(data))
+	}
+
+	if _, err := provider.FetchData(context.Background(), "MSFT"); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData for a missing file, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "TSLA.csv"), []byte("date,open,high,low,close,volume\n"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if _, err := provider.FetchData(context.Background(), "TSLA"); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData for a file with only a header, got %v", err)
+	}
+
+	if _, err := provider.FetchData(context.Background(), "../AAPL"); !errors.Is(err, ErrInvalidTicker) {
+		t.Errorf("Expected ErrInvalidTicker, got %v", err)
+	}
+}
+
+func TestFallbackProvider(t *testing.T) {
+	want := []models.DailyData{{Date: "2024-01-08", Close: 100}}
+
+	provider := NewFallbackProvider(
+		&stubProvider{name: "failing", err: errors.New("boom")},
+		&stubProvider{name: "empty"},
+		&stubProvider{name: "working", data: want},
+	)
+
+	data, err := provider.FetchData(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("FetchData returned unexpected error: %v", err)
+	}
+	if len(data) != 1 || data[0].Close != 100 {
+		t.Errorf("Expected data from the working provider, got %+v", data)
+	}
+
+	provider = NewFallbackProvider(&stubProvider{name: "empty"})
+	if _, err := provider.FetchData(context.Background(), "AAPL"); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData when every provider fails, got %v", err)
+	}
+}
+
+func TestNewPriceProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr error
+	}{
+		{"Alpha Vantage", &config.Config{PriceProviders: []string{"alphavantage"}, APIKEY: "key"}, nil},
+		{"Missing API key", &config.Config{PriceProviders: []string{"alphavantage"}}, ErrMissingAPIKey},
+		{"Missing CSV dir", &config.Config{PriceProviders: []string{"csv"}}, ErrMissingCSVDir},
+		{"Unknown provider", &config.Config{PriceProviders: []string{"bloomberg"}}, ErrUnknownProvider},
+		{"No providers", &config.Config{}, ErrNoProviders},
+		{"Fallback chain", &config.Config{PriceProviders: []string{"store", "csv"}, BarsTable: "daily_bars", PriceCSVDir: "/tmp"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPriceProvider(tt.cfg, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewPriceProvider() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeBarStore struct {
+	bars []*models.DailyBar
+	err  error
+}
+
+func (fs *fakeBarStore) GetTickerDailyBars(ctx context.Context, tableName, ticker string, from, to time.Time) ([]*models.DailyBar, error) {
+	var bars []*models.DailyBar
+	for _, bar := range fs.bars {
+		if bar.Ticker == ticker {
+			bars = append(bars, bar)
+		}
+	}
+	return bars, nil
+}
+
+func (fs *fakeBarStore) UpsertDailyBars(ctx context.Context, bars []*models.DailyBar, tableName string) error {
+	if fs.err != nil {
+		return fs.err
+	}
+	fs.bars = append(fs.bars, bars...)
+	return nil
+}
+
+func TestRecordingProvider(t *testing.T) {
+	store := &fakeBarStore{}
+	upstream := &stubProvider{name: "upstream", data: []models.DailyData{{Date: "2024-01-08", Close: 100}}}
+	provider := NewFallbackProvider(NewRecordingProvider(upstream, store, "daily_bars"), NewStoreProvider(store, "daily_bars"))
+
+	if _, err := provider.FetchData(context.Background(), "aapl"); err != nil {
+		t.Fatalf("FetchData returned unexpected error: %v", err)
+	}
+	if len(store.bars) != 1 || store.bars[0].Ticker != "AAPL" {
+		t.Fatalf("Expected the fetched bars to be stored, got %+v", store.bars)
+	}
+
+	// the stored bars are served once the upstream provider is down
+	upstream.data, upstream.err = nil, ErrUpstreamUnavailable
+	data, err := provider.FetchData(context.Background(), "AAPL")
+	if err != nil || len(data) != 1 || data[0].Close != 100 {
+		t.Errorf("Expected the stored bars, got %+v, %v", data, err)
+	}
+
+	// a failed write doesn't fail the fetch
+	upstream.data, upstream.err = []models.DailyData{{Date: "2024-01-09", Close: 101}}, nil
+	store.err = errors.New("boom")
+	if _, err := provider.FetchData(context.Background(), "AAPL"); err != nil {
+		t.Errorf("Expected the bars to be served when they can't be stored, got %v", err)
+	}
+}
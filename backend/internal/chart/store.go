@@ -0,0 +1,95 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	storeLookback = 180 * 24 * time.Hour
+)
+
+// BarStore reads and writes the daily bars persisted in the database.
+type BarStore interface {
+	GetTickerDailyBars(ctx context.Context, tableName, ticker string, from, to time.Time) ([]*models.DailyBar, error)
+	UpsertDailyBars(ctx context.Context, bars []*models.DailyBar, tableName string) error
+}
+
+// storeProvider reads the daily bars already persisted in the database.
+type storeProvider struct {
+	store     BarStore
+	tableName string
+}
+
+func NewStoreProvider(store BarStore, tableName string) *storeProvider {
+	return &storeProvider{
+		store:     store,
+		tableName: tableName,
+	}
+}
+
+func (sp *storeProvider) Name() string {
+	return StoreProvider
+}
+
+func (sp *storeProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	to := time.Now()
+
+	bars, err := sp.store.GetTickerDailyBars(ctx, sp.tableName, ticker, to.Add(-storeLookback), to)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stored bars: %w", err)
+	}
+
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("%w: no stored bars for %s", ErrNoData, ticker)
+	}
+
+	timeSeries := make([]models.DailyData, 0, len(bars))
+	for _, bar := range bars {
+		timeSeries = append(timeSeries, bar.DailyData)
+	}
+
+	return timeSeries, nil
+}
+
+// recordingProvider writes the bars returned by its provider into the store,
+// so that the store provider still serves them when the provider is down.
+type recordingProvider struct {
+	PriceProvider
+	store     BarStore
+	tableName string
+}
+
+func NewRecordingProvider(provider PriceProvider, store BarStore, tableName string) *recordingProvider {
+	return &recordingProvider{
+		PriceProvider: provider,
+		store:         store,
+		tableName:     tableName,
+	}
+}
+
+func (rp *recordingProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	data, err := rp.PriceProvider.FetchData(ctx, ticker)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	bars := make([]*models.DailyBar, 0, len(data))
+	for _, dailyData := range data {
+		bars = append(bars, &models.DailyBar{Ticker: ticker, DailyData: dailyData})
+	}
+
+	// the bars are served even when they can't be stored
+	if err := rp.store.UpsertDailyBars(ctx, bars, rp.tableName); err != nil {
+		slog.WarnContext(ctx, "failed to store fetched bars", "provider", rp.Name(), "ticker", ticker, "error", err)
+	}
+
+	return data, nil
+}
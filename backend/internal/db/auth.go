@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	apiKeysTable  = "api_keys"
+	apiUsageTable = "api_usage"
+
+	apiKeyColumns = "id, name, scopes, rate_per_minute, burst, daily_quota, created_at, revoked_at"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// CreateAuthTables creates the api keys and usage tables when missing.
+func (repo *CockRoachRepository) CreateAuthTables(ctx context.Context) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createKeysQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id STRING PRIMARY KEY,
+		name STRING NOT NULL,
+		key_hash STRING NOT NULL UNIQUE,
+		scopes STRING[] NOT NULL,
+		rate_per_minute INT NOT NULL DEFAULT 0,
+		burst INT NOT NULL DEFAULT 0,
+		daily_quota INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		revoked_at TIMESTAMPTZ
+		)`, pq.QuoteIdentifier(apiKeysTable))
+
+		if _, err := tx.ExecContext(ctx, createKeysQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", apiKeysTable, err)
+		}
+
+		createUsageQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		client_id STRING PRIMARY KEY,
+		tokens FLOAT8 NOT NULL,
+		refilled_at TIMESTAMPTZ NOT NULL,
+		quota_day DATE NOT NULL,
+		quota_used INT NOT NULL
+		)`, pq.QuoteIdentifier(apiUsageTable))
+
+		if _, err := tx.ExecContext(ctx, createUsageQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", apiUsageTable, err)
+		}
+
+		return nil
+	})
+}
+
+func (repo *CockRoachRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	err := repo.CreateAuthTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, name, key_hash, scopes, rate_per_minute, burst, daily_quota)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`, pq.QuoteIdentifier(apiKeysTable))
+
+	err = repo.db.QueryRowContext(ctx, query,
+		key.ID, key.Name, key.Hash, pq.Array(key.Scopes),
+		key.Limits.RatePerMinute, key.Limits.Burst, key.Limits.DailyQuota,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating api key %s: %w", key.ID, err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash returns the unrevoked key with the given hash.
+func (repo *CockRoachRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE key_hash = $1 AND revoked_at IS NULL`,
+		apiKeyColumns, pq.QuoteIdentifier(apiKeysTable))
+
+	rows, err := repo.db.QueryContext(ctx, query, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+
+	defer rows.Close()
+
+	keys, err := scanAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return keys[0], nil
+}
+
+func (repo *CockRoachRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	err := repo.CreateAuthTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY created_at`, apiKeyColumns, pq.QuoteIdentifier(apiKeysTable))
+
+	rows, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanAPIKeys(rows)
+}
+
+func (repo *CockRoachRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	query := fmt.Sprintf(`UPDATE %s SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`,
+		pq.QuoteIdentifier(apiKeysTable))
+
+	result, err := repo.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error revoking api key %s: %w", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error revoking api key %s: %w", id, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+
+	return nil
+}
+
+// UpdateUsage locks the usage row of the client and stores the changes fn
+// makes to it. fn may run more than once when the transaction is retried.
+func (repo *CockRoachRepository) UpdateUsage(ctx context.Context, clientID string, fn func(*models.Usage)) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		selectQuery := fmt.Sprintf(`SELECT tokens, refilled_at, quota_day, quota_used FROM %s
+		WHERE client_id = $1 FOR UPDATE`, pq.QuoteIdentifier(apiUsageTable))
+
+		usage := &models.Usage{ClientID: clientID}
+		var quotaDay time.Time
+
+		err := tx.QueryRowContext(ctx, selectQuery, clientID).Scan(&usage.Tokens, &usage.RefilledAt, &quotaDay, &usage.QuotaUsed)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("error reading usage of %s: %w", clientID, err)
+		default:
+			usage.QuotaDay = quotaDay.Format(dateLayout)
+		}
+
+		fn(usage)
+
+		upsertQuery := fmt.Sprintf(`UPSERT INTO %s (client_id, tokens, refilled_at, quota_day, quota_used)
+		VALUES ($1, $2, $3, $4, $5)`, pq.QuoteIdentifier(apiUsageTable))
+
+		_, err = tx.ExecContext(ctx, upsertQuery, clientID, usage.Tokens, usage.RefilledAt, usage.QuotaDay, usage.QuotaUsed)
+		if err != nil {
+			return fmt.Errorf("error storing usage of %s: %w", clientID, err)
+		}
+
+		return nil
+	})
+}
+
+func scanAPIKeys(rows *sql.Rows) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		var revokedAt sql.NullTime
+		if err := rows.Scan(
+			&key.ID,
+			&key.Name,
+			pq.Array(&key.Scopes),
+			&key.Limits.RatePerMinute,
+			&key.Limits.Burst,
+			&key.Limits.DailyQuota,
+			&key.CreatedAt,
+			&revokedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning api keys: %w", err)
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
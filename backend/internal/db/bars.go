@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// barsBatchSize is how many bars every UPSERT statement writes.
+	barsBatchSize = 500
+)
+
+func (repo *CockRoachRepository) GetDailyBars(ctx context.Context, tableName string, from, to time.Time) ([]*models.DailyBar, error) {
+	query := fmt.Sprintf(`SELECT ticker, date, open, high, low, close, volume FROM %s
+		WHERE date >= $1 AND date <= $2
+		ORDER BY ticker, date`, pq.QuoteIdentifier(tableName))
+
+	rows, err := repo.db.QueryContext(ctx, query, from.Format(dateLayout), to.Format(dateLayout))
+
+	var pqErr *pq.Error
+	switch {
+	// no bars have been loaded yet
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to query daily bars: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanBars(rows)
+}
+
+func (repo *CockRoachRepository) GetTickerDailyBars(ctx context.Context, tableName, ticker string, from, to time.Time) ([]*models.DailyBar, error) {
+	query := fmt.Sprintf(`SELECT ticker, date, open, high, low, close, volume FROM %s
+		WHERE ticker = $1 AND date >= $2 AND date <= $3
+		ORDER BY date`, pq.QuoteIdentifier(tableName))
+
+	rows, err := repo.db.QueryContext(ctx, query, ticker, from.Format(dateLayout), to.Format(dateLayout))
+
+	var pqErr *pq.Error
+	switch {
+	// no bars have been loaded yet
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to query daily bars for %s: %w", ticker, err)
+	}
+
+	defer rows.Close()
+
+	return scanBars(rows)
+}
+
+// UpsertDailyBars writes the bars into the table created by CreateBarsTable,
+// replacing the stored bar of the same ticker and date.
+func (repo *CockRoachRepository) UpsertDailyBars(ctx context.Context, bars []*models.DailyBar, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		for batch := range slices.Chunk(bars, barsBatchSize) {
+			values := make([]string, 0, len(batch))
+			args := make([]any, 0, len(batch)*7)
+			for _, bar := range batch {
+				n := len(args)
+				values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+				args = append(args, bar.Ticker, bar.Date, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume)
+			}
+
+			upsertQuery := fmt.Sprintf(`UPSERT INTO %s (ticker, date, open, high, low, close, volume) VALUES %s`,
+				pq.QuoteIdentifier(tableName), strings.Join(values, ", "))
+
+			if _, err := tx.ExecContext(ctx, upsertQuery, args...); err != nil {
+				return fmt.Errorf("error upserting daily bars: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "upserted daily bars", "table", tableName, "rows", len(bars))
+
+		return nil
+	})
+}
+
+// CreateBarsTable creates the daily bars table when missing. It is run by the
+// bars command, before that the table reads as empty.
+func (repo *CockRoachRepository) CreateBarsTable(ctx context.Context, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		ticker VARCHAR(10) NOT NULL,
+		date DATE NOT NULL,
+		open DECIMAL(12, 4) NOT NULL,
+		high DECIMAL(12, 4) NOT NULL,
+		low DECIMAL(12, 4) NOT NULL,
+		close DECIMAL(12, 4) NOT NULL,
+		volume INT8 NOT NULL,
+		PRIMARY KEY (ticker, date)
+		)`, pq.QuoteIdentifier(tableName))
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", tableName, err)
+		}
+
+		return nil
+	})
+}
+
+func scanBars(rows *sql.Rows) ([]*models.DailyBar, error) {
+	var bars []*models.DailyBar
+	for rows.Next() {
+		var bar models.DailyBar
+		var date time.Time
+		if err := rows.Scan(
+			&bar.Ticker,
+			&date,
+			&bar.Open,
+			&bar.High,
+			&bar.Low,
+			&bar.Close,
+			&bar.Volume,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning daily bars: %w", err)
+		}
+		bar.Date = date.Format(dateLayout)
+		bars = append(bars, &bar)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily bars: %w", err)
+	}
+
+	return bars, nil
+}
@@ -1,21 +1,31 @@
 package db
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"context"
 
 	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/money"
 	"github.com/CorreaJose13/StockAPI/models"
 	"github.com/cockroachdb/cockroach-go/v2/crdb"
 	"github.com/lib/pq"
 )
 
 var (
+	stockColumnNames = strings.Split(strings.ReplaceAll(stockColumns, " ", ""), ",")
+
 	validFields = map[string]bool{
 		"ticker":      true,
 		"target_from": true,
@@ -26,6 +36,8 @@ var (
 		"rating_from": true,
 		"rating_to":   true,
 		"time":        true,
+		"source":      true,
+		"currency":    true,
 	}
 
 	validOrders = map[string]bool{
@@ -33,12 +45,19 @@ var (
 		"DESC": true,
 	}
 
-	ErrInvalidField = errors.New("invalid field")
-	ErrInvalidOrder = errors.New("invalid order")
+	ErrInvalidField    = errors.New("invalid field")
+	ErrInvalidOrder    = errors.New("invalid order")
+	ErrDeleteThreshold = errors.New("delete threshold exceeded")
 )
 
 type CockRoachRepository struct {
 	db *sql.DB
+	// replica serves the stock reads of the API, it's db unless a read URL is
+	// configured.
+	replica       *sql.DB
+	followerReads bool
+	// migrated holds the stocks tables already migrated by migrateTables.
+	migrated sync.Map
 }
 
 type queryBuilder struct {
@@ -52,27 +71,76 @@ const (
 	defaultField = "time"
 	defaultOrder = "DESC"
 	maxLimit     = 100
+
+	historySuffix  = "_history"
+	versionsSuffix = "_versions"
+	stagingSuffix  = "_staging_"
+
+	stockColumns = "ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, source, currency"
+
+	// stocksBatchSize is how many stocks every INSERT statement writes.
+	stocksBatchSize = 500
+
+	// sameStock matches the rows t and s of the same ticker and source, the
+	// ratings of every source are kept side by side.
+	sameStock = "(s.ticker = t.ticker AND s.source = t.source)"
+
+	// stockChanged matches rows t, staged or versioned, that differ from the
+	// stored row s.
+	stockChanged = `(s.target_from != t.target_from OR
+       			s.target_to != t.target_to OR
+       			s.time != t.time OR
+       			s.company != t.company OR
+       			s.action != t.action OR
+       			s.brokerage != t.brokerage OR
+       			s.rating_from != t.rating_from OR
+       			s.rating_to != t.rating_to OR
+       			s.currency != t.currency
+				)`
+
+	// stockOutdated matches stored rows s that the staging row t changes or
+	// brings back after a soft delete.
+	stockOutdated = "(" + stockChanged + " OR s.deleted_at IS NOT NULL)"
+
+	// notRejected matches the stored rows s of the stocks missing from the
+	// rejected ones, passed as the arrays $1 of tickers and $2 of sources.
+	notRejected = "(s.ticker, s.source) NOT IN (SELECT * FROM unnest($1::STRING[], $2::STRING[]))"
 )
 
-func ConnectCockRoachDB(cfg *config.Config) (*CockRoachRepository, error) {
-	db, err := sql.Open("postgres", cfg.DBURL)
+// ConnectCockRoachDB opens the pools of the primary and of the read URL, if
+// any, and fails unless both answer a ping within the configured attempts.
+func ConnectCockRoachDB(ctx context.Context, cfg *config.Config) (*CockRoachRepository, error) {
+	db, err := openPool(ctx, cfg, cfg.DBURL, "cockroachdb")
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect database: %w", err)
+		return nil, err
+	}
+
+	repo := &CockRoachRepository{
+		db:            db,
+		replica:       db,
+		followerReads: cfg.DBFollowerReads,
+	}
+
+	if cfg.DBReadURL != "" {
+		repo.replica, err = openPool(ctx, cfg, cfg.DBReadURL, "cockroachdb-replica")
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
 
-	return &CockRoachRepository{db}, nil
+	return repo, nil
 }
 
-func (repo *CockRoachRepository) GetStocksFiltered(ctx context.Context, field, order, search, tableName string, page, limit int) ([]*models.FormattedStock, error) {
+func (repo *CockRoachRepository) GetStocksFiltered(ctx context.Context, tableName string, filter models.StockFilter) ([]*models.FormattedStock, error) {
+	reader, followerRead := repo.reader(ctx, filter.AsOf)
 
-	result, err := filterQueryParams(field, order, search, tableName, page, limit)
+	result, err := filterQueryParams(tableName, filter, followerRead)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := repo.db.QueryContext(ctx, result.query, result.params...)
+	rows, err := reader.QueryContext(ctx, result.query, result.params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query filtered stocks: %w", err)
 	}
@@ -87,9 +155,13 @@ func (repo *CockRoachRepository) GetStocksFiltered(ctx context.Context, field, o
 	return stocks, nil
 }
 
-func (repo *CockRoachRepository) GetStocks(ctx context.Context, tableName string) ([]*models.FormattedStock, error) {
-	query := fmt.Sprintf(`SELECT * FROM %s`, pq.QuoteIdentifier(tableName))
-	rows, err := repo.db.QueryContext(ctx, query)
+// GetStocks returns the live stocks, or the ones that were live at asOf when
+// it isn't zero.
+func (repo *CockRoachRepository) GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error) {
+	reader, followerRead := repo.reader(ctx, asOf)
+
+	query, params := stocksQuery(tableName, "", false, asOf, followerRead)
+	rows, err := reader.QueryContext(ctx, query, params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query stocks: %w", err)
 	}
@@ -104,6 +176,33 @@ func (repo *CockRoachRepository) GetStocks(ctx context.Context, tableName string
 	return stocks, nil
 }
 
+// GetStocksHistory returns the rating changes since the given time, along with
+// when they were recorded. When asOf isn't zero only the changes already
+// recorded at that instant are returned.
+func (repo *CockRoachRepository) GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error) {
+	query := fmt.Sprintf(`SELECT %s, recorded_at FROM %s WHERE time >= $1`, stockColumns, pq.QuoteIdentifier(HistoryTableName(tableName)))
+	params := []any{since}
+	if !asOf.IsZero() {
+		query += " AND time <= $2 AND recorded_at <= $2"
+		params = append(params, asOf)
+	}
+	query += " ORDER BY time DESC"
+
+	rows, err := repo.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks history: %w", err)
+	}
+
+	defer rows.Close()
+
+	stocks, err := scanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
 func (repo *CockRoachRepository) GetTableLength(ctx context.Context, tableName string) (int, error) {
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", pq.QuoteIdentifier(tableName))
@@ -115,9 +214,29 @@ func (repo *CockRoachRepository) GetTableLength(ctx context.Context, tableName s
 	return count, nil
 }
 
+func (repo *CockRoachRepository) CountStocks(ctx context.Context, tableName string, includeDeleted bool, asOf time.Time) (int, error) {
+	reader, followerRead := repo.reader(ctx, asOf)
+
+	var count int
+	query, params := stocksQuery(tableName, "", includeDeleted, asOf, false)
+
+	// the clause only applies to the outer statement
+	query = fmt.Sprintf("SELECT COUNT(*) FROM (%s)", query)
+	if followerRead {
+		query += " " + followerReadClause
+	}
+
+	err := reader.QueryRowContext(ctx, query, params...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count stocks in %s: %w", tableName, err)
+	}
+
+	return count, nil
+}
+
 func (repo *CockRoachRepository) BulkInsertStocks(ctx context.Context, stocks []*models.FormattedStock, tableName string) error {
 
-	err := repo.createTable(ctx, tableName)
+	err := repo.setupTables(ctx, tableName)
 	if err != nil {
 		return err
 	}
@@ -127,127 +246,451 @@ func (repo *CockRoachRepository) BulkInsertStocks(ctx context.Context, stocks []
 		return err
 	}
 
-	return nil
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		err := insertHistory(ctx, tx, tableName, tableName)
+		if err != nil {
+			return err
+		}
+
+		err = recordVersions(ctx, tx, tableName)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordSyncRun(ctx, tx, tableName, &models.SyncPlan{Inserts: stocks}, nil)
+		return err
+	})
 }
 
-func (repo *CockRoachRepository) BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable, tempTable string) error {
-	err := repo.createTable(ctx, tempTable)
+// BulkUpdateStocks writes the stocks into a staging table unique to this run
+// and reconciles the original table with it in a single transaction.
+func (repo *CockRoachRepository) BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable string, opts models.SyncOptions) (plan *models.SyncPlan, err error) {
+	tempTable, err := repo.PrepareStagingTable(ctx, originalTable)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer func() error {
-		err = repo.dropTable(ctx, tempTable)
-		if err != nil {
-			return err
+	defer func() {
+		// the staging table is dropped even when the run was cancelled
+		if dropErr := repo.dropTable(context.WithoutCancel(ctx), tempTable); dropErr != nil {
+			err = errors.Join(err, dropErr)
 		}
-
-		return nil
 	}()
 
 	err = repo.bulkInsertToTable(ctx, tempTable, stocks)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	count, err := repo.compareTables(ctx, originalTable, tempTable)
+	return repo.ReconcileStocks(ctx, originalTable, tempTable, opts)
+}
+
+// PrepareStagingTable creates an empty staging table, named uniquely so that
+// concurrent runs don't collide, and returns its name. Batches of stocks can be
+// written to it before ReconcileStocks applies them to the original table.
+func (repo *CockRoachRepository) PrepareStagingTable(ctx context.Context, originalTable string) (string, error) {
+	tempTable, err := stagingTableName(originalTable)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	err = repo.createTable(ctx, tempTable)
+	if err != nil {
+		return "", err
+	}
+
+	return tempTable, nil
+}
+
+func (repo *CockRoachRepository) InsertStocksBatch(ctx context.Context, stocks []*models.FormattedStock, tableName string) error {
+	return repo.bulkInsertToTable(ctx, tableName, stocks)
+}
+
+// ReconcileStocks applies the content of the staging table to the original
+// table: new tickers are merged, changed ones updated and missing ones soft
+// deleted unless listed in opts.Rejected, while rows deleted longer than the
+// retention ago are purged. Every change is applied in one transaction, so a
+// failure leaves the original table untouched, and running it again with the
+// same staging table is a no-op. On a dry run the plan is computed without
+// applying it.
+//
+// The resulting content of the original table is recorded as a new version of
+// every changed stock, see GetStocksFiltered to read it at a past instant.
+//
+// A plan deleting more rows than allowed by opts.MaxDeletePercent is refused
+// with ErrDeleteThreshold unless forced, the plan is still returned so it can
+// be reviewed.
+func (repo *CockRoachRepository) ReconcileStocks(ctx context.Context, originalTable, tempTable string, opts models.SyncOptions) (*models.SyncPlan, error) {
+	var plan *models.SyncPlan
+
+	purgeBefore := time.Time{}
+	if opts.Retention > 0 {
+		purgeBefore = time.Now().Add(-opts.Retention)
 	}
 
-	if count > 0 {
-		err = repo.mergeTables(ctx, originalTable, tempTable)
+	if opts.DryRun {
+		err := repo.execReadOnly(ctx, func(tx *sql.Tx) error {
+			var err error
+			plan, err = planReconcile(ctx, tx, originalTable, tempTable, purgeBefore, opts.Rejected)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		plan.DryRun = true
+		slog.InfoContext(ctx, "dry run planned", "table", originalTable, "inserts", len(plan.Inserts),
+			"updates", len(plan.Updates), "deletes", len(plan.Deletes), "purges", len(plan.Purges))
+
+		return plan, checkDeleteThreshold(plan, opts)
+	}
+
+	err := repo.setupTables(ctx, originalTable)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		plan, err = planReconcile(ctx, tx, originalTable, tempTable, purgeBefore, opts.Rejected)
 		if err != nil {
 			return err
 		}
 
-		err = repo.updateTable(ctx, originalTable, tempTable)
+		err = checkDeleteThreshold(plan, opts)
 		if err != nil {
 			return err
 		}
 
+		err = insertHistory(ctx, tx, originalTable, tempTable)
+		if err != nil {
+			return err
+		}
+
+		if len(plan.Inserts) > 0 {
+			err = mergeTables(ctx, tx, originalTable, tempTable)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plan.Updates) > 0 {
+			err = updateTable(ctx, tx, originalTable, tempTable)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plan.Deletes) > 0 {
+			err = softDeleteObsoleteRows(ctx, tx, originalTable, tempTable, opts.Rejected)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plan.Purges) > 0 {
+			err = purgeDeletedRows(ctx, tx, originalTable, purgeBefore)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = recordVersions(ctx, tx, originalTable)
+		if err != nil {
+			return err
+		}
+
+		plan.RunID, err = recordSyncRun(ctx, tx, originalTable, plan, opts.Quality)
+		return err
+	})
+	if errors.Is(err, ErrDeleteThreshold) {
+		return plan, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (repo *CockRoachRepository) DropTable(ctx context.Context, tableName string) error {
+	return repo.dropTable(ctx, tableName)
+}
+
+// HistoryTableName returns the append-only table that keeps every rating
+// change ever seen for the given stocks table.
+func HistoryTableName(tableName string) string {
+	return tableName + historySuffix
+}
+
+// VersionsTableName returns the table that keeps every version of the stocks
+// table rows together with the period in which they were served.
+func VersionsTableName(tableName string) string {
+	return tableName + versionsSuffix
+}
+
+func stagingTableName(originalTable string) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("error generating staging table name: %w", err)
 	}
 
-	deleteCount, err := repo.compareTables(ctx, tempTable, originalTable)
+	return fmt.Sprintf("%s%s%s", originalTable, stagingSuffix, hex.EncodeToString(suffix)), nil
+}
+
+// qualifiedColumns prefixes every stock column with the given table alias.
+func qualifiedColumns(alias string) string {
+	columns := strings.Split(stockColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// checkDeleteThreshold refuses plans deleting more than the allowed share of
+// the live rows of the original table.
+func checkDeleteThreshold(plan *models.SyncPlan, opts models.SyncOptions) error {
+	if opts.Force || opts.MaxDeletePercent <= 0 || plan.LiveRows == 0 {
+		return nil
+	}
+
+	percent := float64(len(plan.Deletes)) * 100 / float64(plan.LiveRows)
+	if percent > opts.MaxDeletePercent {
+		return fmt.Errorf("%w: %d of %d stocks (%.1f%%) would be deleted, the limit is %.1f%%",
+			ErrDeleteThreshold, len(plan.Deletes), plan.LiveRows, percent, opts.MaxDeletePercent)
+	}
+
+	return nil
+}
+
+func planReconcile(ctx context.Context, tx *sql.Tx, originalTable, tempTable string, purgeBefore time.Time,
+	rejected []models.StockKey) (*models.SyncPlan, error) {
+	original, temp := pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable)
+
+	inserts, err := queryStocks(ctx, tx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s t
+		LEFT JOIN %s s ON %s
+		WHERE s.ticker IS NULL`, qualifiedColumns("t"), temp, original, sameStock))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error planning inserts into %s: %w", originalTable, err)
 	}
 
-	if deleteCount > 0 {
-		err = repo.deleteObsoleteRows(ctx, originalTable, tempTable)
+	updates, err := queryStocks(ctx, tx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s t
+		JOIN %s s ON %s
+		WHERE %s`, qualifiedColumns("t"), temp, original, sameStock, stockOutdated))
+	if err != nil {
+		return nil, fmt.Errorf("error planning updates in %s: %w", originalTable, err)
+	}
+
+	deletes, err := queryStocks(ctx, tx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s s
+		LEFT JOIN %s t ON %s
+		WHERE t.ticker IS NULL AND s.deleted_at IS NULL AND %s`, qualifiedColumns("s"), original, temp, sameStock, notRejected),
+		rejectedArrays(rejected)...)
+	if err != nil {
+		return nil, fmt.Errorf("error planning deletes from %s: %w", originalTable, err)
+	}
+
+	var purges []*models.FormattedStock
+	if !purgeBefore.IsZero() {
+		purges, err = queryStocks(ctx, tx, fmt.Sprintf(`
+		SELECT %s, deleted_at
+		FROM %s
+		WHERE deleted_at < $1`, stockColumns, original), purgeBefore)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error planning purges from %s: %w", originalTable, err)
 		}
 	}
 
+	var liveRows int
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE deleted_at IS NULL`, original)).Scan(&liveRows)
+	if err != nil {
+		return nil, fmt.Errorf("error counting stocks in %s: %w", originalTable, err)
+	}
+
+	return &models.SyncPlan{
+		Inserts:  inserts,
+		Updates:  updates,
+		Deletes:  deletes,
+		Purges:   purges,
+		LiveRows: liveRows,
+	}, nil
+}
+
+func queryStocks(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]*models.FormattedStock, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanRows(rows)
+}
+
+func insertHistory(ctx context.Context, tx *sql.Tx, originalTable, sourceTable string) error {
+	historyTable := HistoryTableName(originalTable)
+
+	historyQuery := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT %s
+		FROM %s
+		ON CONFLICT DO NOTHING`, pq.QuoteIdentifier(historyTable), stockColumns, stockColumns, pq.QuoteIdentifier(sourceTable))
+
+	result, err := tx.ExecContext(ctx, historyQuery)
+	if err != nil {
+		return fmt.Errorf("error appending history to table %s: %w", historyTable, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", historyTable, err)
+	}
+
+	slog.InfoContext(ctx, "appended rating changes", "table", historyTable, "rows", rowsAffected)
+
 	return nil
 }
 
-func (repo *CockRoachRepository) compareTables(ctx context.Context, originalTable, tempTable string) (int, error) {
-	var count int
-	query := fmt.Sprintf(`SELECT COUNT(*) FROM (SELECT * FROM %s EXCEPT SELECT * FROM %s)`, pq.QuoteIdentifier(tempTable), pq.QuoteIdentifier(originalTable))
-	err := repo.db.QueryRowContext(ctx, query).Scan(&count)
+// recordVersions closes the open versions of stocks that changed or were
+// deleted and opens a version for every live stock without one. All versions
+// written in a transaction share its timestamp.
+func recordVersions(ctx context.Context, tx *sql.Tx, originalTable string) error {
+	versionsTable := pq.QuoteIdentifier(VersionsTableName(originalTable))
+	original := pq.QuoteIdentifier(originalTable)
+
+	closeQuery := fmt.Sprintf(`
+		UPDATE %s t
+		SET valid_to = now()
+		WHERE t.valid_to IS NULL AND NOT EXISTS (
+			SELECT 1
+			FROM %s s
+			WHERE %s AND s.deleted_at IS NULL AND NOT %s
+			)`, versionsTable, original, sameStock, stockChanged)
+
+	closed, err := tx.ExecContext(ctx, closeQuery)
 	if err != nil {
-		return 0, fmt.Errorf("failed to compare tables %s and %s: %w", originalTable, tempTable, err)
+		return fmt.Errorf("error closing versions in table %s: %w", versionsTable, err)
 	}
-	return count, nil
+
+	openQuery := fmt.Sprintf(`
+		INSERT INTO %s (%s, valid_from)
+		SELECT %s, now()
+		FROM %s s
+		WHERE s.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1
+			FROM %s t
+			WHERE %s AND t.valid_to IS NULL
+			)`, versionsTable, stockColumns, qualifiedColumns("s"), original, versionsTable, sameStock)
+
+	opened, err := tx.ExecContext(ctx, openQuery)
+	if err != nil {
+		return fmt.Errorf("error opening versions in table %s: %w", versionsTable, err)
+	}
+
+	closedCount, err := closed.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", versionsTable, err)
+	}
+
+	openedCount, err := opened.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", versionsTable, err)
+	}
+
+	slog.InfoContext(ctx, "updated versions", "table", VersionsTableName(originalTable), "closed", closedCount, "opened", openedCount)
+
+	return nil
 }
 
-func (repo *CockRoachRepository) deleteObsoleteRows(ctx context.Context, originalTable, tempTable string) error {
-	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
-		mergeQuery := fmt.Sprintf(`
-		DELETE FROM %s 
-		WHERE ticker IN (
-    		SELECT o.ticker 
-    		FROM %s o 
-    		LEFT JOIN %s t ON o.ticker = t.ticker 
-    		WHERE t.ticker IS NULL
-			)`, pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable))
-
-		result, err := tx.ExecContext(ctx, mergeQuery)
-		if err != nil {
-			return fmt.Errorf("error deleting rows in table %s: %w", originalTable, err)
-		}
+// softDeleteObsoleteRows marks the stocks missing from the staging table as
+// deleted, they are kept until purged by the retention policy. The rejected
+// stocks are still in the feed and kept as stored.
+func softDeleteObsoleteRows(ctx context.Context, tx *sql.Tx, originalTable, tempTable string, rejected []models.StockKey) error {
+	deleteQuery := fmt.Sprintf(`
+		UPDATE %s 
+		SET deleted_at = now()
+		WHERE deleted_at IS NULL AND (ticker, source) IN (
+    		SELECT s.ticker, s.source
+    		FROM %s s
+    		LEFT JOIN %s t ON %s
+    		WHERE t.ticker IS NULL AND %s
+			)`, pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable), sameStock,
+		notRejected)
+
+	result, err := tx.ExecContext(ctx, deleteQuery, rejectedArrays(rejected)...)
+	if err != nil {
+		return fmt.Errorf("error deleting rows in table %s: %w", originalTable, err)
+	}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
-		}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
+	}
 
-		log.Printf("Deleted %d obsolete stocks into %s table", rowsAffected, originalTable)
+	slog.InfoContext(ctx, "soft deleted obsolete stocks", "table", originalTable, "rows", rowsAffected)
 
-		return nil
-	})
+	return nil
 }
 
-func (repo *CockRoachRepository) mergeTables(ctx context.Context, originalTable, tempTable string) error {
-	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
-		mergeQuery := fmt.Sprintf(`
-        INSERT INTO %s (ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time)
-        SELECT t.ticker, t.target_from, t.target_to, t.company, t.action, t.brokerage, t.rating_from, t.rating_to, t.time
+// rejectedArrays returns the arguments of notRejected.
+func rejectedArrays(rejected []models.StockKey) []any {
+	tickers := make([]string, len(rejected))
+	sources := make([]string, len(rejected))
+	for i, key := range rejected {
+		tickers[i], sources[i] = key.Ticker, key.Source
+	}
+	return []any{pq.Array(tickers), pq.Array(sources)}
+}
+
+func purgeDeletedRows(ctx context.Context, tx *sql.Tx, originalTable string, purgeBefore time.Time) error {
+	purgeQuery := fmt.Sprintf(`DELETE FROM %s WHERE deleted_at < $1`, pq.QuoteIdentifier(originalTable))
+
+	result, err := tx.ExecContext(ctx, purgeQuery, purgeBefore)
+	if err != nil {
+		return fmt.Errorf("error purging rows in table %s: %w", originalTable, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
+	}
+
+	slog.InfoContext(ctx, "purged deleted stocks", "table", originalTable, "rows", rowsAffected, "deleted_before", purgeBefore)
+
+	return nil
+}
+
+func mergeTables(ctx context.Context, tx *sql.Tx, originalTable, tempTable string) error {
+	mergeQuery := fmt.Sprintf(`
+        INSERT INTO %s (%s)
+        SELECT %s
         FROM %s t
-        LEFT JOIN stocks s ON t.ticker = s.ticker
-        WHERE s.ticker IS NULL`, pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable))
+        LEFT JOIN %s s ON %s
+        WHERE s.ticker IS NULL`, pq.QuoteIdentifier(originalTable), stockColumns, qualifiedColumns("t"),
+		pq.QuoteIdentifier(tempTable), pq.QuoteIdentifier(originalTable), sameStock)
 
-		result, err := tx.ExecContext(ctx, mergeQuery)
-		if err != nil {
-			return fmt.Errorf("error merging in table %s: %w", originalTable, err)
-		}
+	result, err := tx.ExecContext(ctx, mergeQuery)
+	if err != nil {
+		return fmt.Errorf("error merging in table %s: %w", originalTable, err)
+	}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
-		}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
+	}
 
-		log.Printf("Merged %d new stocks into %s table", rowsAffected, originalTable)
+	slog.InfoContext(ctx, "merged new stocks", "table", originalTable, "rows", rowsAffected)
 
-		return nil
-	})
+	return nil
 }
 
-func (repo *CockRoachRepository) updateTable(ctx context.Context, originalTable, tempTable string) error {
-	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
-		updateQuery := fmt.Sprintf(`
+func updateTable(ctx context.Context, tx *sql.Tx, originalTable, tempTable string) error {
+	updateQuery := fmt.Sprintf(`
 		UPDATE %s s
 		SET
     		target_from = t.target_from,
@@ -257,89 +700,408 @@ func (repo *CockRoachRepository) updateTable(ctx context.Context, originalTable,
     		action = t.action,
     		brokerage = t.brokerage,
     		rating_from = t.rating_from,
-    		rating_to = t.rating_to
+    		rating_to = t.rating_to,
+    		currency = t.currency,
+    		deleted_at = NULL
 		FROM %s t
-		WHERE s.ticker = t.ticker
-  			AND (s.target_from != t.target_from OR
-       			s.target_to != t.target_to OR
-       			s.time != t.time OR
-       			s.company != t.company OR
-       			s.action != t.action OR
-       			s.brokerage != t.brokerage OR
-       			s.rating_from != t.rating_from OR
-       			s.rating_to != t.rating_to
-				);
-    	`, pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable))
+		WHERE %s
+  			AND %s;
+    	`, pq.QuoteIdentifier(originalTable), pq.QuoteIdentifier(tempTable), sameStock, stockOutdated)
 
-		result, err := tx.ExecContext(ctx, updateQuery)
-		if err != nil {
-			return fmt.Errorf("error updating table %s: %w", originalTable, err)
-		}
+	result, err := tx.ExecContext(ctx, updateQuery)
+	if err != nil {
+		return fmt.Errorf("error updating table %s: %w", originalTable, err)
+	}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
-		}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected in %s: %w", originalTable, err)
+	}
 
-		log.Printf("Updated %d stocks in %s table", rowsAffected, originalTable)
+	slog.InfoContext(ctx, "updated stocks", "table", originalTable, "rows", rowsAffected)
 
-		return nil
-	})
+	return nil
 }
 
+// bulkInsertToTable writes the newest rating of every ticker and source of the
+// stocks, a feed listing a ticker once per brokerage, keeping the stored one
+// when it isn't older so the batches of a run can repeat a stock.
 func (repo *CockRoachRepository) bulkInsertToTable(ctx context.Context, tableName string, stocks []*models.FormattedStock) error {
+	stocks = newestStocks(stocks)
+
+	updates := make([]string, 0, len(stockColumnNames))
+	for _, column := range stockColumnNames {
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+
 	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
-		stmt, err := tx.Prepare(pq.CopyIn(tableName, "ticker", "target_from", "target_to",
-			"company", "action", "brokerage", "rating_from", "rating_to", "time"))
-		if err != nil {
-			return fmt.Errorf("error preparing bulk insert statement: %w", err)
-		}
+		for batch := range slices.Chunk(stocks, stocksBatchSize) {
+			values := make([]string, 0, len(batch))
+			args := make([]any, 0, len(batch)*len(stockColumnNames))
+			for _, stock := range batch {
+				placeholders := make([]string, len(stockColumnNames))
+				for i := range placeholders {
+					placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+				}
+				values = append(values, "("+strings.Join(placeholders, ", ")+")")
+				args = append(args, stock.Ticker, stock.TargetFrom, stock.TargetTo, stock.Company, stock.Action,
+					stock.Brokerage, stock.RatingFrom, stock.RatingTo, stock.Time, stock.Source, stock.Currency)
+			}
 
-		defer stmt.Close()
+			insertQuery := fmt.Sprintf(`
+				INSERT INTO %s AS s (%s) VALUES %s
+				ON CONFLICT (ticker, source) DO UPDATE SET %s
+				WHERE excluded.time > s.time`, pq.QuoteIdentifier(tableName), stockColumns,
+				strings.Join(values, ", "), strings.Join(updates, ", "))
 
-		for _, stock := range stocks {
-			_, err = stmt.ExecContext(ctx, stock.Ticker, stock.TargetFrom, stock.TargetTo, stock.Company,
-				stock.Action, stock.Brokerage, stock.RatingFrom, stock.RatingTo, stock.Time)
-			if err != nil {
-				return fmt.Errorf("error adding item %s to bulk insert: %w", stock.Ticker, err)
+			if _, err := tx.ExecContext(ctx, insertQuery, args...); err != nil {
+				return fmt.Errorf("error inserting stocks into %s: %w", tableName, err)
 			}
 		}
 
-		_, err = stmt.ExecContext(ctx)
-		if err != nil {
-			return fmt.Errorf("error finalizing bulk insert to %s: %w", tableName, err)
+		slog.InfoContext(ctx, "inserted stocks", "table", tableName, "rows", len(stocks))
+
+		return nil
+	})
+}
+
+// newestStocks keeps the newest rating of every ticker and source, the first
+// one on a tie, since a single statement can't write a row twice.
+func newestStocks(stocks []*models.FormattedStock) []*models.FormattedStock {
+	positions := make(map[models.StockKey]int, len(stocks))
+	newest := make([]*models.FormattedStock, 0, len(stocks))
+
+	for _, stock := range stocks {
+		key := models.StockKey{Ticker: stock.Ticker, Source: stock.Source}
+
+		pos, seen := positions[key]
+		if !seen {
+			positions[key] = len(newest)
+			newest = append(newest, stock)
+			continue
+		}
+
+		if stock.Time.After(newest[pos].Time) {
+			newest[pos] = stock
+		}
+	}
+
+	return newest
+}
+
+// createTable creates a stocks table, or a staging table, with the current
+// schema when missing. The tables of earlier releases are migrated by
+// migrateTables.
+func (repo *CockRoachRepository) createTable(ctx context.Context, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		ticker VARCHAR(10) NOT NULL,
+		target_from DECIMAL(14, 4) NOT NULL,
+		target_to DECIMAL(14, 4) NOT NULL,
+		company VARCHAR(100) NOT NULL,
+		action VARCHAR(50) NOT NULL,
+		brokerage VARCHAR(100) NOT NULL,
+		rating_from VARCHAR(50) NOT NULL,
+		rating_to VARCHAR(50) NOT NULL,
+		time TIMESTAMP WITH TIME ZONE NOT NULL,
+		source VARCHAR(50) NOT NULL DEFAULT '%s',
+		currency VARCHAR(3) NOT NULL DEFAULT '%s',
+		deleted_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY (ticker, source)
+		)`, pq.QuoteIdentifier(tableName), models.DefaultSource, money.DefaultCurrency)
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", tableName, err)
 		}
 
-		log.Printf("Inserted %d stocks into %s", len(stocks), tableName)
+		slog.InfoContext(ctx, "created table", "table", tableName)
 
 		return nil
 	})
 }
 
-func (repo *CockRoachRepository) createTable(ctx context.Context, tableName string) error {
+func (repo *CockRoachRepository) createHistoryTable(ctx context.Context, tableName string) error {
 	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
 		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
-		ticker VARCHAR(10) PRIMARY KEY NOT NULL,
-		target_from DECIMAL(10, 2) NOT NULL,
-		target_to DECIMAL(10, 2) NOT NULL,
+		ticker VARCHAR(10) NOT NULL,
+		target_from DECIMAL(14, 4) NOT NULL,
+		target_to DECIMAL(14, 4) NOT NULL,
 		company VARCHAR(100) NOT NULL,
 		action VARCHAR(50) NOT NULL,
 		brokerage VARCHAR(100) NOT NULL,
 		rating_from VARCHAR(50) NOT NULL,
 		rating_to VARCHAR(50) NOT NULL,
-		time TIMESTAMP WITH TIME ZONE NOT NULL
-		)`, pq.QuoteIdentifier(tableName))
+		time TIMESTAMP WITH TIME ZONE NOT NULL,
+		source VARCHAR(50) NOT NULL DEFAULT '%s',
+		currency VARCHAR(3) NOT NULL DEFAULT '%s',
+		recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		PRIMARY KEY (ticker, source, brokerage, time),
+		INDEX (time)
+		)`, pq.QuoteIdentifier(tableName), models.DefaultSource, money.DefaultCurrency)
 
 		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
 			return fmt.Errorf("error creating table %s: %w", tableName, err)
 		}
 
-		log.Printf("Table %s created successfully", tableName)
+		return nil
+	})
+}
+
+func (repo *CockRoachRepository) createVersionsTable(ctx context.Context, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		ticker VARCHAR(10) NOT NULL,
+		target_from DECIMAL(14, 4) NOT NULL,
+		target_to DECIMAL(14, 4) NOT NULL,
+		company VARCHAR(100) NOT NULL,
+		action VARCHAR(50) NOT NULL,
+		brokerage VARCHAR(100) NOT NULL,
+		rating_from VARCHAR(50) NOT NULL,
+		rating_to VARCHAR(50) NOT NULL,
+		time TIMESTAMP WITH TIME ZONE NOT NULL,
+		source VARCHAR(50) NOT NULL DEFAULT '%s',
+		currency VARCHAR(3) NOT NULL DEFAULT '%s',
+		valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+		valid_to TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY (ticker, source, valid_from),
+		INDEX (valid_from, valid_to)
+		)`, pq.QuoteIdentifier(tableName), models.DefaultSource, money.DefaultCurrency)
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", tableName, err)
+		}
 
 		return nil
 	})
 }
 
+// setupTables creates the stocks table along with its history, versions and
+// sync runs tables and its search indexes, migrating the ones of earlier
+// releases.
+func (repo *CockRoachRepository) setupTables(ctx context.Context, tableName string) error {
+	err := repo.createTable(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	err = repo.createHistoryTable(ctx, HistoryTableName(tableName))
+	if err != nil {
+		return err
+	}
+
+	err = repo.createVersionsTable(ctx, VersionsTableName(tableName))
+	if err != nil {
+		return err
+	}
+
+	err = repo.createSyncRunsTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = repo.migrateTables(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	return repo.createSearchIndexes(ctx, tableName)
+}
+
+// migrateTables brings the stocks table and its history and versions tables
+// created by earlier releases to the current schema. It runs once per stocks
+// table for the life of the repository, the staging tables are created with
+// the current schema and never migrated.
+func (repo *CockRoachRepository) migrateTables(ctx context.Context, tableName string) error {
+	if _, ok := repo.migrated.Load(tableName); ok {
+		return nil
+	}
+
+	type addColumn func(ctx context.Context, tx *sql.Tx, tableName string) error
+
+	migrations := []struct {
+		table      string
+		primaryKey string
+		columns    []addColumn
+	}{
+		{tableName, "ticker, source", []addColumn{addSourceColumn, addCurrencyColumn, addDeletedAtColumn}},
+		{HistoryTableName(tableName), "ticker, source, brokerage, time", []addColumn{addSourceColumn, addCurrencyColumn, addRecordedAtColumn}},
+		{VersionsTableName(tableName), "ticker, source, valid_from", []addColumn{addSourceColumn, addCurrencyColumn}},
+	}
+
+	for _, migration := range migrations {
+		err := repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+			for _, add := range migration.columns {
+				if err := add(ctx, tx, migration.table); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := repo.addSourceToPrimaryKey(ctx, migration.table, migration.primaryKey); err != nil {
+			return err
+		}
+
+		if err := repo.widenTargetColumns(ctx, migration.table); err != nil {
+			return err
+		}
+	}
+
+	repo.migrated.Store(tableName, true)
+
+	return nil
+}
+
+// addSourceToPrimaryKey migrates tables keyed before the ratings of several
+// sources were kept side by side to the given primary key columns. It runs in
+// its own transaction, after the source column is added.
+func (repo *CockRoachRepository) addSourceToPrimaryKey(ctx context.Context, tableName, columns string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		var constraint string
+		var keyed bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT c.constraint_name, bool_or(k.column_name = 'source')
+			FROM information_schema.table_constraints c
+			JOIN information_schema.key_column_usage k
+				ON k.table_schema = c.table_schema AND k.table_name = c.table_name AND k.constraint_name = c.constraint_name
+			WHERE c.table_schema = current_schema() AND c.table_name = $1 AND c.constraint_type = 'PRIMARY KEY'
+			GROUP BY c.constraint_name`, tableName).Scan(&constraint, &keyed)
+		if err != nil {
+			return fmt.Errorf("error reading primary key of table %s: %w", tableName, err)
+		}
+
+		if keyed {
+			return nil
+		}
+
+		// dropping and adding the constraint in one statement doesn't leave a
+		// unique index on the old key, as ALTER PRIMARY KEY does
+		alterQuery := fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s, ADD CONSTRAINT %s PRIMARY KEY (%s)`,
+			pq.QuoteIdentifier(tableName), pq.QuoteIdentifier(constraint), pq.QuoteIdentifier(constraint), columns)
+
+		if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+			return fmt.Errorf("error adding source to primary key of table %s: %w", tableName, err)
+		}
+
+		slog.InfoContext(ctx, "added source to primary key", "table", tableName)
+
+		return nil
+	})
+}
+
+// widenTargetColumns migrates the targets of tables created with two decimal
+// places, whose stored targets would otherwise never equal the staged ones
+// and be updated on every sync. The type change rewrites the columns, so it
+// runs outside a transaction.
+func (repo *CockRoachRepository) widenTargetColumns(ctx context.Context, tableName string) error {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name IN ('target_from', 'target_to')
+			AND (numeric_precision != 14 OR numeric_scale != 4)`, tableName)
+	if err != nil {
+		return fmt.Errorf("error reading target columns of table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return fmt.Errorf("error scanning target columns of table %s: %w", tableName, err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating target columns of table %s: %w", tableName, err)
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+
+	// the setting is per session, so the statements share a connection which
+	// is reset before it goes back to the pool, or discarded when it can't be
+	conn, err := repo.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error widening target columns of table %s: %w", tableName, err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `RESET enable_experimental_alter_column_type_general`); err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}()
+
+	if _, err := conn.ExecContext(ctx, `SET enable_experimental_alter_column_type_general = true`); err != nil {
+		return fmt.Errorf("error widening target columns of table %s: %w", tableName, err)
+	}
+
+	for _, column := range columns {
+		alterQuery := fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s TYPE DECIMAL(14, 4)`,
+			pq.QuoteIdentifier(tableName), pq.QuoteIdentifier(column))
+
+		if _, err := conn.ExecContext(ctx, alterQuery); err != nil {
+			return fmt.Errorf("error widening column %s of table %s: %w", column, tableName, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "widened target columns", "table", tableName, "columns", columns)
+
+	return nil
+}
+
+// addSourceColumn migrates tables created before ratings carried their source.
+func addSourceColumn(ctx context.Context, tx *sql.Tx, tableName string) error {
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT '%s'`,
+		pq.QuoteIdentifier(tableName), models.DefaultSource)
+
+	if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("error adding source column to table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// addCurrencyColumn migrates tables created before the targets carried their
+// currency, their targets were all read as dollars.
+func addCurrencyColumn(ctx context.Context, tx *sql.Tx, tableName string) error {
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT '%s'`,
+		pq.QuoteIdentifier(tableName), money.DefaultCurrency)
+
+	if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("error adding currency column to table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// addRecordedAtColumn migrates history tables created before point-in-time
+// reads, their rows get the migration time.
+func addRecordedAtColumn(ctx context.Context, tx *sql.Tx, tableName string) error {
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()`,
+		pq.QuoteIdentifier(tableName))
+
+	if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("error adding recorded_at column to table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// addDeletedAtColumn migrates tables created before obsolete stocks were soft deleted.
+func addDeletedAtColumn(ctx context.Context, tx *sql.Tx, tableName string) error {
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`, pq.QuoteIdentifier(tableName))
+
+	if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("error adding deleted_at column to table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
 func (repo *CockRoachRepository) dropTable(ctx context.Context, tableName string) error {
 	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
 		dropTableQuery := fmt.Sprintf("DROP TABLE IF EXISTS %s", pq.QuoteIdentifier(tableName))
@@ -348,26 +1110,25 @@ func (repo *CockRoachRepository) dropTable(ctx context.Context, tableName string
 			return fmt.Errorf("error droping table %s: %w", tableName, err)
 		}
 
-		log.Printf("Table %s dropped successfully", tableName)
+		slog.InfoContext(ctx, "dropped table", "table", tableName)
 
 		return nil
 	})
 }
 
-func filterQueryParams(field, order, search, tableName string, page, limit int) (queryBuilder, error) {
-	page, limit = normalizePaginationParams(page, limit)
+func filterQueryParams(tableName string, filter models.StockFilter, followerRead bool) (queryBuilder, error) {
+	page, limit := normalizePaginationParams(filter.Page, filter.Limit)
 	offset := (page - 1) * limit
 
-	query, err := generatePaginationQuery(field, order, search, tableName)
+	orderStm, err := buildOrderStatement(filter.Field, filter.Order)
 	if err != nil {
 		return queryBuilder{}, err
 	}
 
-	params := make([]any, 0)
+	query, params := stocksQuery(tableName, filter.Search, filter.IncludeDeleted, filter.AsOf, followerRead)
 
-	searchParams := getSearchParams(search)
-	params = append(params, searchParams...)
 	params = append(params, limit, offset)
+	query += fmt.Sprintf(" %s LIMIT $%d OFFSET $%d", orderStm, len(params)-1, len(params))
 
 	return queryBuilder{
 		query:  query,
@@ -375,32 +1136,39 @@ func filterQueryParams(field, order, search, tableName string, page, limit int)
 	}, nil
 }
 
-func generatePaginationQuery(field, order, search, tableName string) (string, error) {
-
-	baseQuery := fmt.Sprintf(`SELECT * FROM %s`, pq.QuoteIdentifier(tableName))
+// stocksQuery selects the stocks matching the search. When asOf isn't zero the
+// versions served at that instant are read instead of the live rows, deleted
+// stocks weren't served so includeDeleted doesn't apply. A follower read may
+// be served by the closest replica a few seconds behind.
+func stocksQuery(tableName, search string, includeDeleted bool, asOf time.Time, followerRead bool) (string, []any) {
+	params := getSearchParams(search)
 
-	orderStm, err := buildOrderStatement(field, order)
-	if err != nil {
-		return "", err
+	var conditions []string
+	if searchStm := buildSearchStatement(search); searchStm != "" {
+		conditions = append(conditions, searchStm)
 	}
 
-	searchStm := buildSearchStatement(search)
-
-	query := baseQuery
-	if searchStm != "" {
-		query += " " + searchStm
+	var query string
+	if asOf.IsZero() {
+		query = fmt.Sprintf(`SELECT %s, deleted_at FROM %s`, stockColumns, pq.QuoteIdentifier(tableName))
+		if !includeDeleted {
+			conditions = append(conditions, "deleted_at IS NULL")
+		}
+	} else {
+		params = append(params, asOf)
+		query = fmt.Sprintf(`SELECT %s FROM %s`, stockColumns, pq.QuoteIdentifier(VersionsTableName(tableName)))
+		conditions = append(conditions, fmt.Sprintf("valid_from <= $%d AND (valid_to IS NULL OR valid_to > $%d)", len(params), len(params)))
 	}
 
-	query += " " + orderStm
+	if followerRead {
+		query += " " + followerReadClause
+	}
 
-	var pagination string
-	if searchStm != "" {
-		pagination = " LIMIT $2 OFFSET $3"
-	} else {
-		pagination = " LIMIT $1 OFFSET $2"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	return query + pagination, nil
+	return query, params
 }
 
 // validate max pages
@@ -420,6 +1188,16 @@ func normalizePaginationParams(page, limit int) (int, int) {
 	return page, limit
 }
 
+// SortableFields returns the fields stocks can be ordered by.
+func SortableFields() []string {
+	fields := make([]string, 0, len(validFields))
+	for field := range validFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 func isValidField(field string) bool {
 	return validFields[strings.ToLower(field)]
 }
@@ -433,7 +1211,7 @@ func buildSearchStatement(search string) string {
 	if search == "" {
 		return ""
 	}
-	return "WHERE ticker ILIKE $1 OR company ILIKE $1 OR brokerage ILIKE $1"
+	return "(ticker ILIKE $1 OR company ILIKE $1 OR brokerage ILIKE $1)"
 }
 
 func getSearchParams(search string) []any {
@@ -467,11 +1245,17 @@ func buildOrderStatement(field, order string) (string, error) {
 	return fmt.Sprintf("ORDER BY %s %s", field, order), nil
 }
 
+// scanRows scans the stock columns, followed by deleted_at when selected.
 func scanRows(rows *sql.Rows) ([]*models.FormattedStock, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+
 	var stocks []*models.FormattedStock
 	for rows.Next() {
 		var stock models.FormattedStock
-		if err := rows.Scan(
+		dest := []any{
 			&stock.Ticker,
 			&stock.TargetFrom,
 			&stock.TargetTo,
@@ -481,11 +1265,28 @@ func scanRows(rows *sql.Rows) ([]*models.FormattedStock, error) {
 			&stock.RatingFrom,
 			&stock.RatingTo,
 			&stock.Time,
-		); err != nil {
+			&stock.Source,
+			&stock.Currency,
+		}
+		for _, column := range columns[len(dest):] {
+			switch column {
+			case "deleted_at":
+				dest = append(dest, &stock.DeletedAt)
+			case "recorded_at":
+				dest = append(dest, &stock.RecordedAt)
+			}
+		}
+
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("error scanning rows: %w", err)
 		}
 		stocks = append(stocks, &stock)
 	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
 	return stocks, nil
 }
 
@@ -493,6 +1294,21 @@ func (repo *CockRoachRepository) execInTransaction(ctx context.Context, fn func(
 	return crdb.ExecuteTx(ctx, repo.db, nil, fn)
 }
 
+// execReadOnly runs fn against a consistent snapshot and never commits.
+func (repo *CockRoachRepository) execReadOnly(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := repo.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error starting read-only transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	return fn(tx)
+}
+
 func (repo *CockRoachRepository) Close() error {
+	if repo.replica != repo.db {
+		repo.replica.Close()
+	}
 	return repo.db.Close()
 }
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func TestStagingTableName(t *testing.T) {
+	first, err := stagingTableName("stocks")
+	if err != nil {
+		t.Fatalf("stagingTableName returned unexpected error: %v", err)
+	}
+
+	second, err := stagingTableName("stocks")
+	if err != nil {
+		t.Fatalf("stagingTableName returned unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(first, "stocks"+stagingSuffix) {
+		t.Errorf("Expected staging table to be prefixed by the original table, got %s", first)
+	}
+
+	if first == second {
+		t.Errorf("Expected unique staging tables per run, got %s twice", first)
+	}
+}
+
+func TestQualifiedColumns(t *testing.T) {
+	columns := strings.Split(qualifiedColumns("t"), ", ")
+
+	if len(columns) != len(strings.Split(stockColumns, ", ")) {
+		t.Fatalf("Expected one qualified column per stock column, got %v", columns)
+	}
+
+	for _, column := range columns {
+		if !strings.HasPrefix(column, "t.") {
+			t.Errorf("Expected column %s to be qualified by the alias", column)
+		}
+	}
+}
+
+func TestNewestStocks(t *testing.T) {
+	now := time.Now()
+	stocks := []*models.FormattedStock{
+		{Ticker: "AAPL", Source: "api", Brokerage: "Barclays", Time: now.Add(-time.Hour)},
+		{Ticker: "AAPL", Source: "api", Brokerage: "Citigroup", Time: now},
+		{Ticker: "AAPL", Source: "file", Brokerage: "Barclays", Time: now.Add(-time.Hour)},
+		{Ticker: "MSFT", Source: "api", Brokerage: "Barclays", Time: now},
+		{Ticker: "MSFT", Source: "api", Brokerage: "Citigroup", Time: now},
+	}
+
+	got := newestStocks(stocks)
+
+	want := []*models.FormattedStock{stocks[1], stocks[2], stocks[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newestStocks kept %v, want the newest or first rating of every ticker and source", got)
+	}
+}
+
+func TestCheckDeleteThreshold(t *testing.T) {
+	deletes := func(n int) []*models.FormattedStock {
+		return make([]*models.FormattedStock, n)
+	}
+
+	tests := []struct {
+		name    string
+		plan    *models.SyncPlan
+		opts    models.SyncOptions
+		wantErr error
+	}{
+		{"Below the limit", &models.SyncPlan{Deletes: deletes(2), LiveRows: 100}, models.SyncOptions{MaxDeletePercent: 20}, nil},
+		{"Exactly the limit", &models.SyncPlan{Deletes: deletes(20), LiveRows: 100}, models.SyncOptions{MaxDeletePercent: 20}, nil},
+		{"Above the limit", &models.SyncPlan{Deletes: deletes(21), LiveRows: 100}, models.SyncOptions{MaxDeletePercent: 20}, ErrDeleteThreshold},
+		{"Forced", &models.SyncPlan{Deletes: deletes(90), LiveRows: 100}, models.SyncOptions{MaxDeletePercent: 20, Force: true}, nil},
+		{"Check disabled", &models.SyncPlan{Deletes: deletes(90), LiveRows: 100}, models.SyncOptions{}, nil},
+		{"Empty table", &models.SyncPlan{}, models.SyncOptions{MaxDeletePercent: 20}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDeleteThreshold(tt.plan, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkDeleteThreshold() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterQueryParams(t *testing.T) {
+	asOf := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		filter       models.StockFilter
+		followerRead bool
+		wantQuery    string
+		wantParams   []any
+	}{
+		{
+			"Live stocks",
+			models.StockFilter{},
+			false,
+			"SELECT " + stockColumns + ", deleted_at FROM \"stocks\" WHERE deleted_at IS NULL ORDER BY time DESC LIMIT $1 OFFSET $2",
+			[]any{10, 0},
+		},
+		{
+			"Live stocks matching search",
+			models.StockFilter{Search: "apple", Page: 2},
+			false,
+			"SELECT " + stockColumns + ", deleted_at FROM \"stocks\" WHERE (ticker ILIKE $1 OR company ILIKE $1 OR brokerage ILIKE $1) AND deleted_at IS NULL ORDER BY time DESC LIMIT $2 OFFSET $3",
+			[]any{"%apple%", 10, 10},
+		},
+		{
+			"Deleted stocks included",
+			models.StockFilter{IncludeDeleted: true, Field: "ticker", Order: "asc"},
+			false,
+			"SELECT " + stockColumns + ", deleted_at FROM \"stocks\" ORDER BY ticker ASC LIMIT $1 OFFSET $2",
+			[]any{10, 0},
+		},
+		{
+			"Stocks as of a past instant",
+			models.StockFilter{Search: "apple", AsOf: asOf},
+			false,
+			"SELECT " + stockColumns + " FROM \"stocks_versions\" WHERE (ticker ILIKE $1 OR company ILIKE $1 OR brokerage ILIKE $1) AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2) ORDER BY time DESC LIMIT $3 OFFSET $4",
+			[]any{"%apple%", asOf, 10, 0},
+		},
+		{
+			"Follower read of the live stocks",
+			models.StockFilter{Search: "apple"},
+			true,
+			"SELECT " + stockColumns + ", deleted_at FROM \"stocks\" AS OF SYSTEM TIME follower_read_timestamp() WHERE (ticker ILIKE $1 OR company ILIKE $1 OR brokerage ILIKE $1) AND deleted_at IS NULL ORDER BY time DESC LIMIT $2 OFFSET $3",
+			[]any{"%apple%", 10, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filterQueryParams("stocks", tt.filter, tt.followerRead)
+			if err != nil {
+				t.Fatalf("filterQueryParams returned unexpected error: %v", err)
+			}
+
+			if result.query != tt.wantQuery {
+				t.Errorf("Expected query %q, got %q", tt.wantQuery, result.query)
+			}
+
+			if !reflect.DeepEqual(result.params, tt.wantParams) {
+				t.Errorf("Expected params %v, got %v", tt.wantParams, result.params)
+			}
+		})
+	}
+}
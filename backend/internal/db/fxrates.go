@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	fxRatesTable = "fx_rates"
+)
+
+// GetFXRates returns the latest rate of every currency pair on or before asOf,
+// shared by every stocks table.
+func (repo *CockRoachRepository) GetFXRates(ctx context.Context, asOf time.Time) ([]*models.FXRate, error) {
+	query := fmt.Sprintf(`SELECT DISTINCT ON (base, quote) base, quote, rate::STRING, as_of FROM %s
+		WHERE as_of <= $1
+		ORDER BY base, quote, as_of DESC`, pq.QuoteIdentifier(fxRatesTable))
+
+	rows, err := repo.db.QueryContext(ctx, query, asOf.Format(dateLayout))
+
+	var pqErr *pq.Error
+	switch {
+	// no rates have been loaded yet
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to query fx rates: %w", err)
+	}
+
+	defer rows.Close()
+
+	var rates []*models.FXRate
+	for rows.Next() {
+		var rate models.FXRate
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.AsOf); err != nil {
+			return nil, fmt.Errorf("failed to scan fx rate: %w", err)
+		}
+		rates = append(rates, &rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fx rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// UpsertFXRates stores the rates, replacing the ones of the same pair and day.
+func (repo *CockRoachRepository) UpsertFXRates(ctx context.Context, rates []*models.FXRate) error {
+	if err := repo.createFXRatesTable(ctx); err != nil {
+		return err
+	}
+
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		upsertQuery := fmt.Sprintf(`UPSERT INTO %s (base, quote, rate, as_of) VALUES ($1, $2, $3, $4)`,
+			pq.QuoteIdentifier(fxRatesTable))
+
+		stmt, err := tx.PrepareContext(ctx, upsertQuery)
+		if err != nil {
+			return fmt.Errorf("error preparing fx rates upsert statement: %w", err)
+		}
+
+		defer stmt.Close()
+
+		for _, rate := range rates {
+			_, err = stmt.ExecContext(ctx, rate.Base, rate.Quote, rate.Rate, rate.AsOf.Format(dateLayout))
+			if err != nil {
+				return fmt.Errorf("error upserting fx rate %s/%s %s: %w", rate.Base, rate.Quote,
+					rate.AsOf.Format(dateLayout), err)
+			}
+		}
+
+		slog.InfoContext(ctx, "upserted fx rates", "rows", len(rates))
+
+		return nil
+	})
+}
+
+func (repo *CockRoachRepository) createFXRatesTable(ctx context.Context) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		base VARCHAR(3) NOT NULL,
+		quote VARCHAR(3) NOT NULL,
+		rate DECIMAL(24, 10) NOT NULL CHECK (rate > 0),
+		as_of DATE NOT NULL,
+		PRIMARY KEY (base, quote, as_of)
+		)`, pq.QuoteIdentifier(fxRatesTable))
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", fxRatesTable, err)
+		}
+
+		return nil
+	})
+}
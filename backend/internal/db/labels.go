@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	unmappedLabelsTable = "unmapped_labels"
+)
+
+// SaveUnmappedLabels adds the labels counted by a sync to the ones kept for
+// review, shared by every stocks table, and sets when each was first and last
+// seen.
+func (repo *CockRoachRepository) SaveUnmappedLabels(ctx context.Context, labels []*models.UnmappedLabel) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	if err := repo.createUnmappedLabelsTable(ctx); err != nil {
+		return err
+	}
+
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		upsertQuery := fmt.Sprintf(`INSERT INTO %[1]s AS l (kind, source, label, count) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kind, source, label) DO UPDATE SET count = l.count + excluded.count, last_seen = now()
+		RETURNING first_seen, last_seen`,
+			pq.QuoteIdentifier(unmappedLabelsTable))
+
+		for _, label := range labels {
+			err := tx.QueryRowContext(ctx, upsertQuery, label.Kind, label.Source, label.Label, label.Count).
+				Scan(&label.FirstSeen, &label.LastSeen)
+			if err != nil {
+				return fmt.Errorf("error saving unmapped %s %q: %w", label.Kind, label.Label, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo *CockRoachRepository) createUnmappedLabelsTable(ctx context.Context) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		kind STRING NOT NULL,
+		source STRING NOT NULL,
+		label STRING NOT NULL,
+		count INT NOT NULL,
+		first_seen TIMESTAMPTZ NOT NULL DEFAULT now(),
+		last_seen TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (kind, source, label)
+		)`, pq.QuoteIdentifier(unmappedLabelsTable))
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", unmappedLabelsTable, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/lib/pq"
+)
+
+const (
+	followerReadClause = "AS OF SYSTEM TIME follower_read_timestamp()"
+
+	// followerReadLag is more than follower_read_timestamp() trails the
+	// present, as_of reads more recent than that could miss a version.
+	followerReadLag = time.Minute
+)
+
+var (
+	ErrUnreachable = errors.New("database unreachable")
+)
+
+type primaryKey struct{}
+
+// ReadPrimary makes the reads of the context go to the primary without
+// follower reads, for the ones that must see the writes just made.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsPrimary reports whether the reads of the context go to the primary.
+func ReadsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// MayLag reports whether the reads that don't go to the primary may still
+// miss the writes made at t.
+func MayLag(t time.Time) bool {
+	return time.Since(t) <= followerReadLag
+}
+
+// reader returns the pool of the stock reads of the context and whether they
+// may be follower reads.
+func (repo *CockRoachRepository) reader(ctx context.Context, asOf time.Time) (*sql.DB, bool) {
+	if ReadsPrimary(ctx) {
+		return repo.db, false
+	}
+
+	followerRead := repo.followerReads && (asOf.IsZero() || time.Since(asOf) > followerReadLag)
+
+	return repo.replica, followerRead
+}
+
+func openPool(ctx context.Context, cfg *config.Config, dsn, system string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	db := sql.OpenDB(telemetry.Connector(connector, system))
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err := ping(ctx, db, system, cfg.DBConnectAttempts, cfg.DBConnectBackoff); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// ping retries with an exponential backoff, since the cluster may still be
+// starting or scaling from zero.
+func ping(ctx context.Context, db *sql.DB, system string, attempts int, backoff time.Duration) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil || attempt >= attempts {
+			break
+		}
+
+		slog.WarnContext(ctx, "database unreachable, retrying", "system", system, "attempt", attempt,
+			"backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrUnreachable, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrUnreachable, system, err)
+	}
+
+	return nil
+}
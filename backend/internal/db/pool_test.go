@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	repo := &CockRoachRepository{db: primary, replica: replica, followerReads: true}
+
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		asOf             time.Time
+		wantPool         *sql.DB
+		wantFollowerRead bool
+	}{
+		{"Latest stocks", context.Background(), time.Time{}, replica, true},
+		{"Past instant", context.Background(), time.Now().Add(-time.Hour), replica, true},
+		{"Instant within the follower lag", context.Background(), time.Now(), replica, false},
+		{"Primary required", ReadPrimary(context.Background()), time.Time{}, primary, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, followerRead := repo.reader(tt.ctx, tt.asOf)
+			if pool != tt.wantPool || followerRead != tt.wantFollowerRead {
+				t.Errorf("reader = %p, %v, want %p, %v", pool, followerRead, tt.wantPool, tt.wantFollowerRead)
+			}
+		})
+	}
+}
+
+// flakyConnector fails the first connections.
+type flakyConnector struct {
+	failures int
+	attempts int
+}
+
+func (c *flakyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		attempts     int
+		wantAttempts int
+		wantErr      error
+	}{
+		{"Reachable", 0, 3, 1, nil},
+		{"Reachable after retries", 2, 3, 3, nil},
+		{"Unreachable", 5, 3, 3, ErrUnreachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &flakyConnector{failures: tt.failures}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+
+			err := ping(context.Background(), db, "test", tt.attempts, time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ping error = %v, want %v", err, tt.wantErr)
+			}
+
+			if connector.attempts != tt.wantAttempts {
+				t.Errorf("connected %d times, want %d", connector.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+// searchColumns are indexed with trigrams, which serve both the similarity
+// operator and the ILIKE searches of the stocks.
+var searchColumns = []string{"ticker", "company", "brokerage"}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchStocks returns the tickers, companies and brokerages of the live
+// stocks containing the query or similar to it, the prefix matches first.
+// The candidates aren't scored, the search package ranks them.
+func (repo *CockRoachRepository) SearchStocks(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error) {
+	reader, followerRead := repo.reader(ctx, time.Time{})
+
+	statement, params := searchQuery(tableName, query, limit, followerRead)
+
+	rows, err := reader.QueryContext(ctx, statement, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stocks in %s: %w", tableName, err)
+	}
+
+	defer rows.Close()
+
+	var suggestions []models.SearchSuggestion
+	for rows.Next() {
+		var suggestion models.SearchSuggestion
+		var prefix bool
+		var similarity float64
+		if err := rows.Scan(&suggestion.Field, &suggestion.Value, &suggestion.Ticker, &prefix, &similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// searchQuery matches every search column with ILIKE and the % similarity
+// operator of pg_trgm, the brokerages being listed once.
+func searchQuery(tableName, query string, limit int, followerRead bool) (string, []any) {
+	escaped := likeEscaper.Replace(query)
+	params := []any{query, escaped + "%", "%" + escaped + "%", limit}
+
+	selects := make([]string, 0, len(searchColumns))
+	for _, column := range searchColumns {
+		ticker := "ticker"
+		if column == "brokerage" {
+			ticker = "''"
+		}
+
+		selects = append(selects, fmt.Sprintf(
+			`SELECT '%[1]s' AS field, %[1]s AS value, %[2]s AS ticker, %[1]s ILIKE $2 AS prefix, similarity(%[1]s, $1) AS sim
+			FROM %[3]s WHERE deleted_at IS NULL AND (%[1]s ILIKE $3 OR %[1]s %% $1)`,
+			column, ticker, pq.QuoteIdentifier(tableName)))
+	}
+
+	statement := fmt.Sprintf("SELECT DISTINCT field, value, ticker, prefix, sim FROM (%s) AS candidates",
+		strings.Join(selects, " UNION ALL "))
+
+	// the clause only applies to the outer statement
+	if followerRead {
+		statement += " " + followerReadClause
+	}
+
+	statement += " ORDER BY prefix DESC, sim DESC, value LIMIT $4"
+
+	return statement, params
+}
+
+// createSearchIndexes adds the trigram indexes of the search columns to a
+// served table, the staging tables don't need them.
+func (repo *CockRoachRepository) createSearchIndexes(ctx context.Context, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		for _, column := range searchColumns {
+			query := fmt.Sprintf(`CREATE INVERTED INDEX IF NOT EXISTS %s ON %s (%s gin_trgm_ops)`,
+				pq.QuoteIdentifier(tableName+"_"+column+"_trgm_idx"), pq.QuoteIdentifier(tableName), column)
+
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("error creating %s search index on %s: %w", column, tableName, err)
+			}
+		}
+
+		slog.DebugContext(ctx, "created search indexes", "table", tableName)
+
+		return nil
+	})
+}
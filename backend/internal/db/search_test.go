@@ -0,0 +1,45 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		followerRead bool
+		wantParams   []any
+		wantClause   bool
+	}{
+		{"Plain query", "apple", false, []any{"apple", "apple%", "%apple%", 30}, false},
+		{"LIKE wildcards escaped", `50%_off\`, false, []any{`50%_off\`, `50\%\_off\\%`, `%50\%\_off\\%`, 30}, false},
+		{"Follower read", "apple", true, []any{"apple", "apple%", "%apple%", 30}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, params := searchQuery("stocks", tt.query, 30, tt.followerRead)
+
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("Expected params %v, got %v", tt.wantParams, params)
+			}
+
+			for _, column := range searchColumns {
+				if !strings.Contains(query, column+` ILIKE $3 OR `+column+` % $1`) {
+					t.Errorf("Expected %s to be matched, got %q", column, query)
+				}
+			}
+
+			if got := strings.Contains(query, ") AS candidates "+followerReadClause+" ORDER BY"); got != tt.wantClause {
+				t.Errorf("Expected follower read %v on the outer statement, got %q", tt.wantClause, query)
+			}
+
+			if !strings.HasSuffix(query, "ORDER BY prefix DESC, sim DESC, value LIMIT $4") {
+				t.Errorf("Expected the prefix matches first, got %q", query)
+			}
+		})
+	}
+}
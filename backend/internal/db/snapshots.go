@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	snapshotsSuffix = "_snapshots"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// SnapshotsTableName returns the table that keeps the responses precomputed
+// from every sync run of the given stocks table.
+func SnapshotsTableName(tableName string) string {
+	return tableName + snapshotsSuffix
+}
+
+// SaveSnapshots stores the snapshots, replacing the ones of the same run and
+// kind.
+func (repo *CockRoachRepository) SaveSnapshots(ctx context.Context, tableName string, snapshots []*models.Snapshot) error {
+	snapshotsTable := SnapshotsTableName(tableName)
+
+	err := repo.createSnapshotsTable(ctx, snapshotsTable)
+	if err != nil {
+		return err
+	}
+
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		upsertQuery := fmt.Sprintf(`UPSERT INTO %s (run_id, kind, payload) VALUES ($1, $2, $3)`,
+			pq.QuoteIdentifier(snapshotsTable))
+
+		for _, snapshot := range snapshots {
+			_, err := tx.ExecContext(ctx, upsertQuery, snapshot.RunID, snapshot.Kind, []byte(snapshot.Payload))
+			if err != nil {
+				return fmt.Errorf("error saving %s snapshot of run %s: %w", snapshot.Kind, snapshot.RunID, err)
+			}
+		}
+
+		slog.InfoContext(ctx, "saved snapshots", "table", snapshotsTable, "snapshots", len(snapshots))
+
+		return nil
+	})
+}
+
+// GetSnapshot returns the snapshot of the given kind taken by the latest run
+// that changed the stocks up to asOf, or up to now for the zero value. A run
+// without snapshots isn't skipped since older ones would be stale.
+func (repo *CockRoachRepository) GetSnapshot(ctx context.Context, tableName, kind string, asOf time.Time) (*models.Snapshot, error) {
+	qb := &queryBuilder{
+		query: fmt.Sprintf(`SELECT r.id, r.finished_at, s.payload FROM %s r
+		LEFT JOIN %s s ON s.run_id = r.id AND s.kind = $2
+		WHERE r.table_name = $1 AND r.inserts + r.updates + r.deletes + r.purges > 0`,
+			pq.QuoteIdentifier(syncRunsTable), pq.QuoteIdentifier(SnapshotsTableName(tableName))),
+		params: []any{tableName, kind},
+	}
+
+	if !asOf.IsZero() {
+		qb.params = append(qb.params, asOf)
+		qb.query += fmt.Sprintf(" AND r.finished_at <= $%d", len(qb.params))
+	}
+
+	qb.query += " ORDER BY r.finished_at DESC LIMIT 1"
+
+	snapshot := &models.Snapshot{Kind: kind}
+	var payload []byte
+
+	err := repo.db.QueryRowContext(ctx, qb.query, qb.params...).Scan(&snapshot.RunID, &snapshot.TakenAt, &payload)
+
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrSnapshotNotFound
+	// no sync or no snapshot has been recorded yet
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		return nil, ErrSnapshotNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to query %s snapshot: %w", kind, err)
+	case payload == nil:
+		return nil, fmt.Errorf("%w: run %s has no %s snapshot", ErrSnapshotNotFound, snapshot.RunID, kind)
+	}
+
+	snapshot.Payload = payload
+
+	return snapshot, nil
+}
+
+func (repo *CockRoachRepository) createSnapshotsTable(ctx context.Context, tableName string) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		run_id STRING NOT NULL,
+		kind STRING NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (run_id, kind)
+		)`, pq.QuoteIdentifier(tableName))
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", tableName, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/lib/pq"
+)
+
+const (
+	syncRunsTable = "sync_runs"
+
+	// undefinedTable is the SQLSTATE of a missing relation.
+	undefinedTable = "42P01"
+)
+
+var (
+	ErrSyncRunNotFound = errors.New("sync run not found")
+)
+
+// LatestSyncRun returns the latest run that changed the given table.
+func (repo *CockRoachRepository) LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error) {
+	query := fmt.Sprintf(`SELECT id, table_name, finished_at, inserts, updates, deletes, purges FROM %s
+		WHERE table_name = $1 AND inserts + updates + deletes + purges > 0
+		ORDER BY finished_at DESC
+		LIMIT 1`, pq.QuoteIdentifier(syncRunsTable))
+
+	var run models.SyncRun
+	err := repo.db.QueryRowContext(ctx, query, tableName).Scan(
+		&run.ID,
+		&run.Table,
+		&run.FinishedAt,
+		&run.Inserts,
+		&run.Updates,
+		&run.Deletes,
+		&run.Purges,
+	)
+
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrSyncRunNotFound
+	// no sync has run yet
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		return nil, ErrSyncRunNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to query latest sync run of %s: %w", tableName, err)
+	}
+
+	return &run, nil
+}
+
+func (repo *CockRoachRepository) createSyncRunsTable(ctx context.Context) error {
+	return repo.execInTransaction(ctx, func(tx *sql.Tx) error {
+		createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id STRING PRIMARY KEY,
+		table_name STRING NOT NULL,
+		finished_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		inserts INT NOT NULL,
+		updates INT NOT NULL,
+		deletes INT NOT NULL,
+		purges INT NOT NULL,
+		quality JSONB,
+		INDEX (table_name, finished_at DESC)
+		)`, pq.QuoteIdentifier(syncRunsTable))
+
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return fmt.Errorf("error creating table %s: %w", syncRunsTable, err)
+		}
+
+		// the runs recorded before the quality reports have none
+		alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS quality JSONB`, pq.QuoteIdentifier(syncRunsTable))
+		if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
+			return fmt.Errorf("error adding quality column to table %s: %w", syncRunsTable, err)
+		}
+
+		return nil
+	})
+}
+
+// recordSyncRun stores the run and its quality report, if any, in the
+// transaction applying it, so its finished_at matches the valid_from of the
+// versions it opened.
+func recordSyncRun(ctx context.Context, tx *sql.Tx, tableName string, plan *models.SyncPlan, report *models.QualityReport) (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating sync run id: %w", err)
+	}
+
+	runID := hex.EncodeToString(id)
+
+	var quality any
+	if report != nil {
+		encoded, err := json.Marshal(report)
+		if err != nil {
+			return "", fmt.Errorf("error encoding quality report of %s: %w", tableName, err)
+		}
+		quality = string(encoded)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, table_name, inserts, updates, deletes, purges, quality)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, pq.QuoteIdentifier(syncRunsTable))
+
+	_, err := tx.ExecContext(ctx, query, runID, tableName,
+		len(plan.Inserts), len(plan.Updates), len(plan.Deletes), len(plan.Purges), quality)
+	if err != nil {
+		return "", fmt.Errorf("error recording sync run of %s: %w", tableName, err)
+	}
+
+	return runID, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/middleware"
+	"github.com/CorreaJose13/StockAPI/internal/handlers"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var (
+	document   = spec.NewDocument()
+	cfg        *config.Config
+	corsPolicy middleware.CORSPolicy
+	initErr    error
+)
+
+func init() {
+	ctx := context.Background()
+
+	cfg, initErr = config.Load(ctx)
+	if initErr != nil {
+		return
+	}
+
+	initErr = telemetry.Setup(ctx, "openapi", cfg)
+	if initErr != nil {
+		return
+	}
+
+	corsPolicy, initErr = middleware.NewCORSPolicy(cfg)
+}
+
+func main() {
+	handler := middleware.Traced(spec.OpenAPI, middleware.CORS(corsPolicy, spec.OpenAPI.Validated(handlers.OpenAPI(document))))
+	if initErr != nil {
+		handler = handlers.Failed(spec.OpenAPI, cfg, initErr)
+	}
+
+	lambda.StartWithOptions(handler, lambda.WithEnableSIGTERM(telemetry.DeletePushed))
+}
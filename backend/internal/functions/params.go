@@ -0,0 +1,37 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidType      = errors.New("invalid type")
+	ErrInvalidParameter = errors.New("invalid parameter")
+	ErrInvalidAsOf      = errors.New("invalid as_of")
+)
+
+const asOfDateLayout = "2006-01-02"
+
+// ParseAsOf parses the as_of query parameter, either an RFC 3339 timestamp or
+// a date read as midnight UTC. An empty value returns the zero time, which
+// reads the latest data.
+func ParseAsOf(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return asOf, nil
+	}
+
+	asOf, err := time.Parse(asOfDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: must be an RFC 3339 timestamp or a YYYY-MM-DD date", ErrInvalidAsOf)
+	}
+
+	return asOf, nil
+}
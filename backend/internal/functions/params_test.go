@@ -0,0 +1,34 @@
+package functions
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr error
+	}{
+		{"Empty", "", time.Time{}, nil},
+		{"Timestamp", "2024-03-05T14:30:00Z", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), nil},
+		{"Date", "2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), nil},
+		{"Invalid", "last tuesday", time.Time{}, ErrInvalidAsOf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAsOf(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseAsOf() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseAsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,68 +2,110 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/CorreaJose13/StockAPI/config"
-	"github.com/CorreaJose13/StockAPI/internal/api"
-	"github.com/CorreaJose13/StockAPI/internal/db"
-	"github.com/CorreaJose13/StockAPI/internal/functions"
-	"github.com/CorreaJose13/StockAPI/internal/repository"
-	"github.com/CorreaJose13/StockAPI/models"
-	"github.com/CorreaJose13/StockAPI/utils"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/ingest"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
 var (
-	repo    *db.CockRoachRepository
-	cfg     *config.Config
+	a       *app.App
 	initErr error
 )
 
 func init() {
-	repo, cfg, initErr = functions.FullSetup()
-}
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		initErr = err
+		return
+	}
 
-func handler(ctx context.Context) {
+	initErr = telemetry.Setup(ctx, "schedule", cfg)
 	if initErr != nil {
-		log.Fatalf("failed to initialize: %v", initErr)
+		return
 	}
 
-	stocks := fetchStocks(cfg)
+	a, initErr = app.New(ctx, cfg)
+}
+
+// Event is the payload of the scheduler, naming the dataset to sync. The
+// default dataset is synced when it's empty.
+type Event struct {
+	Dataset string `json:"dataset"`
+}
 
-	formattedStocks := formatStocks(stocks)
+// handler fails the invocation on error, the runs are logged under the ID of
+// the invocation.
+func handler(ctx context.Context, event Event) (err error) {
+	ctx = telemetry.WithRequestID(ctx, telemetry.InvocationRequestID(ctx))
+	ctx, span := telemetry.Start(ctx, "sync stocks")
+	defer telemetry.Push(ctx)
+	defer telemetry.Flush(ctx)
+	defer func() { telemetry.End(span, err) }()
 
-	if err := repository.BulkUpdateStocks(context.Background(), formattedStocks, "stocks", "temp"); err != nil {
-		log.Fatalf("failed to insert stocks: %v", err)
+	if initErr != nil {
+		return fmt.Errorf("failed to initialize: %w", initErr)
 	}
-}
 
-func main() {
-	lambda.Start(handler)
-}
+	dataset, err := a.Config.Dataset(event.Dataset)
+	if err != nil {
+		return err
+	}
 
-func fetchStocks(cfg *config.Config) []models.Stock {
-	consumer := api.NewAPIConsumer(cfg)
+	source, err := a.Sources(dataset)
+	if err != nil {
+		return fmt.Errorf("failed to initialize ratings source: %w", err)
+	}
 
-	log.Println("fetching stocks from API...")
-	stocks, err := consumer.FetchStocks()
+	logger := a.Logger.With("dataset", dataset.Name)
+	logger.InfoContext(ctx, "syncing stocks", "source", source.Name())
+
+	opts := ingest.NewOptions(a.Config)
+	opts.Brokerages = a.Brokerages
+	opts.Taxonomy = a.Taxonomy
+	opts.Quality = a.Quality
+
+	result, err := ingest.Sync(ctx, a.Repo, source, dataset.Table, opts)
 	if err != nil {
-		log.Fatalf("failed to fetch stocks: %v", err)
+		return fmt.Errorf("failed to sync stocks: %w", err)
 	}
 
-	log.Printf("successfully fetched %d stocks", len(stocks))
+	if len(result.UnmappedBrokerages) > 0 {
+		logger.WarnContext(ctx, "brokerages missing from the registry", "count", len(result.UnmappedBrokerages),
+			"names", result.UnmappedBrokerages.Names())
+	}
+	if len(result.UnmappedLabels) > 0 {
+		logger.WarnContext(ctx, "labels missing from the taxonomy", "count", len(result.UnmappedLabels),
+			"labels", result.UnmappedLabels.Names())
+	}
 
-	return stocks
-}
+	if report := result.Quality.Summary(); report.Rejected+report.Warned+report.Flagged > 0 {
+		logger.WarnContext(ctx, "data quality issues", "checked", report.Checked, "rejected", report.Rejected,
+			"warned", report.Warned, "flagged", report.Flagged, "rules", result.Quality.Issues())
+	}
+
+	plan := result.Plan
+	if plan.DryRun {
+		logger.InfoContext(ctx, "dry run completed", "inserts", len(plan.Inserts), "updates", len(plan.Updates),
+			"deletes", len(plan.Deletes), "purges", len(plan.Purges))
+		return nil
+	}
+
+	logger.InfoContext(ctx, "synced stocks", "run_id", plan.RunID, "written", result.Written,
+		"inserts", len(plan.Inserts), "updates", len(plan.Updates), "deletes", len(plan.Deletes), "purges", len(plan.Purges))
 
-func formatStocks(stocks []models.Stock) []*models.FormattedStock {
-	var formattedStocks []*models.FormattedStock
-	for _, stock := range stocks {
-		formattedStock, err := utils.Formatter(&stock)
-		if err != nil {
-			log.Fatalf("failed to format stock: %v", err)
-		}
-		formattedStocks = append(formattedStocks, formattedStock)
+	// the reads compute the analysis themselves until the snapshots exist
+	if err := a.Snapshots.Refresh(ctx, dataset.Table); err != nil {
+		logger.ErrorContext(ctx, "failed to refresh analysis snapshots", "error", err)
 	}
-	return formattedStocks
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
 }
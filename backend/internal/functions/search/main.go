@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/CorreaJose13/StockAPI/internal/handlers"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	lambda.StartWithOptions(handlers.Lambda("search", spec.Search), lambda.WithEnableSIGTERM(telemetry.DeletePushed))
+}
@@ -0,0 +1,261 @@
+// Package handlers holds the API handlers shared by the Lambdas and the
+// standalone server, which wrap them with the middlewares of internal/api.
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/middleware"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/chart"
+	"github.com/CorreaJose13/StockAPI/internal/functions"
+	"github.com/CorreaJose13/StockAPI/internal/search"
+	"github.com/CorreaJose13/StockAPI/internal/snapshot"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/CorreaJose13/StockAPI/utils"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	maxChartResults = 10
+)
+
+func Stocks(a *app.App) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		page, err := strconv.Atoi(req.QueryStringParameters["page"])
+		if err != nil {
+			return response.ErrorContext(ctx, spec.InvalidParameter("page", "page must be a number"))
+		}
+		limit, err := strconv.Atoi(req.QueryStringParameters["limit"])
+		if err != nil {
+			return response.ErrorContext(ctx, spec.InvalidParameter("limit", "limit must be a number"))
+		}
+
+		field := req.QueryStringParameters["field"]
+		order := req.QueryStringParameters["order"]
+		search := req.QueryStringParameters["search"]
+
+		includeDeleted := false
+		if value := req.QueryStringParameters["include_deleted"]; value != "" {
+			includeDeleted, err = strconv.ParseBool(value)
+			if err != nil {
+				return response.ErrorContext(ctx, spec.InvalidParameter("include_deleted", "include_deleted must be a boolean"))
+			}
+		}
+
+		asOf, err := functions.ParseAsOf(req.QueryStringParameters["as_of"])
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		if includeDeleted && !asOf.IsZero() {
+			return response.ErrorContext(ctx, fmt.Errorf("%w: include_deleted cannot be combined with as_of", functions.ErrInvalidAsOf))
+		}
+
+		filter := models.StockFilter{
+			Field:          field,
+			Order:          order,
+			Search:         search,
+			IncludeDeleted: includeDeleted,
+			AsOf:           asOf,
+			Page:           page,
+			Limit:          limit,
+		}
+
+		dataset, err := datasetOf(ctx, a)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		stocks, err := a.Repo.GetStocksFiltered(ctx, dataset.Table, filter)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		stocksLength, err := a.Repo.CountStocks(ctx, dataset.Table, includeDeleted, asOf)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		responseBody := models.StocksResponse{
+			Stocks: stocks,
+			Length: stocksLength,
+		}
+
+		return response.Success(responseBody)
+	}
+}
+
+func Analyze(a *app.App) spec.Handler {
+	return serveSnapshot(a, snapshot.KindAnalysis)
+}
+
+func Metrics(a *app.App) spec.Handler {
+	return serveSnapshot(a, snapshot.KindSummary)
+}
+
+func serveSnapshot(a *app.App, kind string) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		asOf, err := functions.ParseAsOf(req.QueryStringParameters["as_of"])
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		dataset, err := datasetOf(ctx, a)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		result, err := a.Snapshots.Get(ctx, dataset.Table, kind, asOf)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		return response.Success(result)
+	}
+}
+
+// Chart serves the latest maxChartResults daily prices of a ticker, up to the
+// newest one, from the price providers of the app, which are shared by every
+// dataset.
+func Chart(a *app.App) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ticker := strings.TrimSpace(req.QueryStringParameters["ticker"])
+		if ticker == "" {
+			return response.ErrorContext(ctx, fmt.Errorf("%w: ticker query parameter is required", utils.ErrEmptyTickerString))
+		}
+
+		// the prices are shared by every dataset, a dataset would be ignored
+		if _, ok := req.QueryStringParameters["dataset"]; ok {
+			return response.ErrorContext(ctx, spec.InvalidParameter("dataset", "dataset is not supported, the prices are shared by every dataset"))
+		}
+
+		provider, err := a.Prices()
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		stockData, err := provider.FetchData(ctx, ticker)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		if len(stockData) == 0 {
+			return response.ErrorContext(ctx, fmt.Errorf("%w for %s", chart.ErrNoData, ticker))
+		}
+
+		// the bars are sorted from oldest to newest
+		chartResponse := models.ChartResponse{
+			TimeSeries: stockData[max(len(stockData)-maxChartResults, 0):],
+		}
+
+		return response.Success(chartResponse)
+	}
+}
+
+// Search serves the typeahead suggestions of the filter box from the search
+// backend of the app.
+func Search(a *app.App) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		query := strings.TrimSpace(req.QueryStringParameters["q"])
+		if query == "" {
+			return response.ErrorContext(ctx, spec.InvalidParameter("q", "q is required"))
+		}
+
+		limit := search.DefaultLimit
+		if value := req.QueryStringParameters["limit"]; value != "" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil {
+				return response.ErrorContext(ctx, spec.InvalidParameter("limit", "limit must be a number"))
+			}
+		}
+
+		dataset, err := datasetOf(ctx, a)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		backend, err := a.Search()
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		suggestions, err := backend.Search(ctx, dataset.Table, query, limit)
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		// the typeahead expects a list even without suggestions
+		if suggestions == nil {
+			suggestions = []models.SearchSuggestion{}
+		}
+
+		return response.Success(models.SearchResponse{
+			Query:       query,
+			Suggestions: suggestions,
+		})
+	}
+}
+
+// Token issues a short-lived bearer token to the browser making the request,
+// whose limits are reported like the ones of the other endpoints.
+func Token(a *app.App) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if a.Auth == nil {
+			return response.ErrorContext(ctx, auth.ErrNotConfigured)
+		}
+
+		token, decision, err := a.Auth.IssueToken(ctx, req.RequestContext.Identity.SourceIP)
+		if err != nil {
+			resp, respErr := response.ErrorContext(ctx, err)
+			return response.WithHeaders(resp, decision.Headers()), respErr
+		}
+
+		resp, err := response.Success(models.TokenResponse{
+			AccessToken: token.Value,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(time.Until(token.ExpiresAt).Seconds()),
+			Scope:       strings.Join(token.Scopes, " "),
+		})
+
+		// the token must not be reused by a shared cache
+		return response.WithHeaders(resp, map[string]string{"Cache-Control": "no-store"}), err
+	}
+}
+
+// OpenAPI serves the document, built once since the endpoints never change at
+// runtime.
+func OpenAPI(document *spec.Document) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return response.Success(document)
+	}
+}
+
+// datasetOf returns the dataset resolved by middleware.Datasets, or the
+// default one for the handlers called without it.
+func datasetOf(ctx context.Context, a *app.App) (config.Dataset, error) {
+	if dataset, ok := middleware.DatasetFrom(ctx); ok {
+		return dataset, nil
+	}
+	return a.Config.Dataset("")
+}
+
+// Guard replaces the handler with one returning err when the setup failed, so
+// the callers get a typed error instead of a crash.
+func Guard(err error, handler spec.Handler) spec.Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/middleware"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/repository"
+	"github.com/CorreaJose13/StockAPI/internal/search"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// fakeRepo serves the stocks from memory, the methods the handlers don't call
+// panic through the nil embedded interface.
+type fakeRepo struct {
+	repository.StockRepository
+	stocks []*models.FormattedStock
+	// firstVersion is when the stocks were first recorded, GetStocks serves
+	// none before it like the versions table.
+	firstVersion time.Time
+	// table is the last one read by GetStocksFiltered.
+	table string
+}
+
+func (r *fakeRepo) GetStocksFiltered(ctx context.Context, tableName string, filter models.StockFilter) ([]*models.FormattedStock, error) {
+	r.table = tableName
+	return r.stocks, nil
+}
+
+func (r *fakeRepo) CountStocks(ctx context.Context, tableName string, includeDeleted bool, asOf time.Time) (int, error) {
+	return len(r.stocks), nil
+}
+
+func (r *fakeRepo) GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error) {
+	if !asOf.IsZero() && asOf.Before(r.firstVersion) {
+		return nil, nil
+	}
+	return r.stocks, nil
+}
+
+func (r *fakeRepo) GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error) {
+	return nil, db.ErrSyncRunNotFound
+}
+
+func (r *fakeRepo) GetSnapshot(ctx context.Context, tableName, kind string, asOf time.Time) (*models.Snapshot, error) {
+	return nil, db.ErrSnapshotNotFound
+}
+
+func (r *fakeRepo) GetFXRates(ctx context.Context, asOf time.Time) ([]*models.FXRate, error) {
+	return nil, nil
+}
+
+type fakeProvider struct {
+	data []models.DailyData
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) FetchData(ctx context.Context, ticker string) ([]models.DailyData, error) {
+	return p.data, nil
+}
+
+func newTestApp(t *testing.T, tickers ...string) *app.App {
+	t.Helper()
+
+	repo := &fakeRepo{firstVersion: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	for _, ticker := range tickers {
+		repo.stocks = append(repo.stocks, &models.FormattedStock{Ticker: ticker, Company: ticker, Action: "upgraded by", RatingTo: "buy"})
+	}
+
+	provider := &fakeProvider{data: []models.DailyData{{Date: "2025-01-02"}, {Date: "2025-01-03"}}}
+
+	cfg := &config.Config{SearchBackend: search.MemoryBackend}
+
+	a, err := app.New(context.Background(), cfg, app.WithRepository(repo), app.WithPrices(provider))
+	if err != nil {
+		t.Fatalf("app.New returned unexpected error: %v", err)
+	}
+	return a
+}
+
+func TestHandlersIsolatedApps(t *testing.T) {
+	tests := []struct {
+		name    string
+		tickers []string
+	}{
+		{"First tenant", []string{"AAPL", "MSFT"}},
+		{"Second tenant", []string{"TSLA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newTestApp(t, tt.tickers...)
+
+			resp, err := Stocks(a)(context.Background(), events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"page": "1", "limit": "10"},
+			})
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Fatalf("Stocks returned %d, %v: %s", resp.StatusCode, err, resp.Body)
+			}
+
+			var body models.StocksResponse
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if body.Length != len(tt.tickers) || len(body.Stocks) != len(tt.tickers) {
+				t.Fatalf("got %d stocks, want %d", body.Length, len(tt.tickers))
+			}
+			for i, stock := range body.Stocks {
+				if stock.Ticker != tt.tickers[i] {
+					t.Errorf("stock %d = %s, want %s", i, stock.Ticker, tt.tickers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	a := newTestApp(t, "AAPL")
+	empty := newTestApp(t)
+
+	tests := []struct {
+		name       string
+		handler    spec.Handler
+		query      map[string]string
+		wantStatus int
+	}{
+		{"Analysis computed without a snapshot", Analyze(a), nil, http.StatusOK},
+		{"Summary computed without a snapshot", Metrics(a), nil, http.StatusOK},
+		{"Chart from the app providers", Chart(a), map[string]string{"ticker": "AAPL"}, http.StatusOK},
+		{"Chart without ticker", Chart(a), nil, http.StatusBadRequest},
+		{"Chart of a dataset", Chart(a), map[string]string{"ticker": "AAPL", "dataset": "us_equities"}, http.StatusBadRequest},
+		{"Invalid as_of", Analyze(a), map[string]string{"as_of": "yesterday"}, http.StatusBadRequest},
+		{"Analysis before any data", Analyze(a), map[string]string{"as_of": "2024-06-01T00:00:00Z"}, http.StatusOK},
+		{"Summary before any data", Metrics(a), map[string]string{"as_of": "2024-06-01T00:00:00Z"}, http.StatusOK},
+		{"Analysis of an empty dataset", Analyze(empty), nil, http.StatusOK},
+		{"Summary of an empty dataset", Metrics(empty), nil, http.StatusOK},
+		{"Search from the in-process index", Search(a), map[string]string{"q": "aap"}, http.StatusOK},
+		{"Search without query", Search(a), nil, http.StatusBadRequest},
+		{"Token without authentication", Token(a), nil, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.handler(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: tt.query})
+			if err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+		})
+	}
+}
+
+func TestDatasets(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := &config.Config{
+		Datasets: map[string]config.Dataset{
+			"us_equities": {Name: "us_equities", Table: "stocks"},
+			"sandbox":     {Name: "sandbox", Table: "stocks_sandbox"},
+		},
+		DefaultDataset: "us_equities",
+	}
+
+	a, err := app.New(context.Background(), cfg, app.WithRepository(repo))
+	if err != nil {
+		t.Fatalf("app.New returned unexpected error: %v", err)
+	}
+
+	handler := middleware.Datasets(cfg, Stocks(a))
+
+	tests := []struct {
+		name       string
+		dataset    string
+		wantStatus int
+		wantTable  string
+	}{
+		{"Default dataset", "", http.StatusOK, "stocks"},
+		{"Named dataset", "sandbox", http.StatusOK, "stocks_sandbox"},
+		{"Unknown dataset", "backfill_2024", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.table = ""
+
+			resp, err := handler(context.Background(), events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"page": "1", "limit": "10", "dataset": tt.dataset},
+			})
+			if err != nil {
+				t.Fatalf("handler returned unexpected error: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus || repo.table != tt.wantTable {
+				t.Errorf("got %d from %q, want %d from %q: %s", resp.StatusCode, repo.table, tt.wantStatus, tt.wantTable, resp.Body)
+			}
+		})
+	}
+}
+
+func TestChart(t *testing.T) {
+	var bars []models.DailyData
+	for day := 1; day <= 12; day++ {
+		bars = append(bars, models.DailyData{Date: fmt.Sprintf("2025-01-%02d", day)})
+	}
+
+	tests := []struct {
+		name       string
+		data       []models.DailyData
+		wantStatus int
+		wantDates  []string
+	}{
+		{"Latest bars up to the newest", bars, http.StatusOK, []string{"2025-01-03", "2025-01-12"}},
+		{"Fewer bars than the limit", bars[:2], http.StatusOK, []string{"2025-01-01", "2025-01-02"}},
+		{"No bars", nil, http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := app.New(context.Background(), &config.Config{}, app.WithRepository(&fakeRepo{}),
+				app.WithPrices(&fakeProvider{data: tt.data}))
+			if err != nil {
+				t.Fatalf("app.New returned unexpected error: %v", err)
+			}
+
+			resp, err := Chart(a)(context.Background(), events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"ticker": "AAPL"},
+			})
+			if err != nil || resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Chart returned %d, %v, want %d: %s", resp.StatusCode, err, tt.wantStatus, resp.Body)
+			}
+			if tt.wantDates == nil {
+				return
+			}
+
+			var body models.ChartResponse
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			series := body.TimeSeries
+			if len(series) == 0 || series[0].Date != tt.wantDates[0] || series[len(series)-1].Date != tt.wantDates[1] {
+				t.Errorf("got bars %v, want %s to %s", series, tt.wantDates[0], tt.wantDates[1])
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	a := newTestApp(t, "AAPL", "MSFT")
+
+	resp, err := Search(a)(context.Background(), events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"q": "msf", "limit": "5"},
+	})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Search returned %d, %v: %s", resp.StatusCode, err, resp.Body)
+	}
+
+	var body models.SearchResponse
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if body.Query != "msf" || len(body.Suggestions) == 0 || body.Suggestions[0].Ticker != "MSFT" {
+		t.Errorf("Search = %+v, want MSFT first", body)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{"Config failed to load", nil},
+		{"Invalid CORS policy", &config.Config{CORSAllowedOrigins: []string{"*"}, CORSAllowCredentials: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Failed(spec.Stocks, tt.cfg, errors.New("boom"))
+
+			resp, _ := handler(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Headers:    map[string]string{"Origin": "https://app.example.com"},
+			})
+
+			if resp.StatusCode != http.StatusInternalServerError {
+				t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+			}
+			if resp.Headers["Access-Control-Allow-Origin"] != "*" {
+				t.Errorf("Expected the CORS headers of the defaults, got %v", resp.Headers)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api/middleware"
+	"github.com/CorreaJose13/StockAPI/internal/app"
+	"github.com/CorreaJose13/StockAPI/internal/server"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+)
+
+var (
+	ErrUnknownEndpoint = errors.New("unknown endpoint")
+)
+
+// New builds the handler of the endpoint from the app and wraps it with its
+// middlewares, the same way for the Lambdas, the server and the tests.
+func New(a *app.App, endpoint *spec.Endpoint) (spec.Handler, error) {
+	corsPolicy, err := middleware.NewCORSPolicy(a.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch endpoint {
+	case spec.OpenAPI:
+		return traced(endpoint, corsPolicy, endpoint.Validated(OpenAPI(spec.NewDocument()))), nil
+	case spec.Token:
+		return traced(endpoint, corsPolicy, endpoint.Validated(Token(a))), nil
+	}
+
+	dataset, err := a.Config.Dataset("")
+	if err != nil {
+		return nil, err
+	}
+
+	cachePolicy := middleware.NewCachePolicy(a.Repo, dataset.Table, a.Config)
+
+	var handler spec.Handler
+	switch endpoint {
+	case spec.Stocks:
+		handler = middleware.Cached(cachePolicy, Stocks(a))
+	case spec.Analyze:
+		handler = middleware.Cached(cachePolicy, Analyze(a))
+	case spec.Metrics:
+		handler = middleware.Cached(cachePolicy, Metrics(a))
+	case spec.Chart:
+		handler = Chart(a)
+	case spec.Search:
+		handler = middleware.Cached(cachePolicy, Search(a))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEndpoint, endpoint.Path)
+	}
+
+	validated := endpoint.Validated(middleware.Datasets(a.Config, handler))
+
+	return traced(endpoint, corsPolicy, middleware.Authenticated(a.Auth, endpoint, validated)), nil
+}
+
+// Routes builds the handlers of every endpoint for the standalone server.
+func Routes(a *app.App) ([]server.Route, error) {
+	var routes []server.Route
+	for _, endpoint := range spec.Endpoints {
+		handler, err := New(a, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		routes = append(routes, server.Route{Endpoint: endpoint, Handler: handler})
+	}
+
+	return routes, nil
+}
+
+// Lambda builds the app and the handler of the endpoint on cold start, when
+// that fails every invocation returns the error instead of crashing.
+func Lambda(service string, endpoint *spec.Endpoint) spec.Handler {
+	ctx := context.Background()
+
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		return Failed(endpoint, nil, err)
+	}
+
+	err = telemetry.Setup(ctx, service, cfg)
+	if err != nil {
+		return Failed(endpoint, cfg, err)
+	}
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		return Failed(endpoint, cfg, err)
+	}
+
+	handler, err := New(a, endpoint)
+	if err != nil {
+		return Failed(endpoint, cfg, err)
+	}
+
+	return handler
+}
+
+// Failed returns err on every request with the CORS headers of cfg, or of the
+// defaults when cfg is nil or its policy is invalid, so the browsers can read
+// the error.
+func Failed(endpoint *spec.Endpoint, cfg *config.Config, err error) spec.Handler {
+	if cfg == nil {
+		cfg = config.Defaults()
+	}
+
+	corsPolicy, policyErr := middleware.NewCORSPolicy(cfg)
+	if policyErr != nil {
+		corsPolicy, _ = middleware.NewCORSPolicy(config.Defaults())
+	}
+
+	return traced(endpoint, corsPolicy, Guard(err, nil))
+}
+
+func traced(endpoint *spec.Endpoint, corsPolicy middleware.CORSPolicy, handler spec.Handler) spec.Handler {
+	return middleware.Traced(endpoint, middleware.CORS(corsPolicy, handler))
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/CorreaJose13/StockAPI/internal/quality"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// Checker runs the data-quality checks of the formatted stocks of a run,
+// logging the issues worth a warning and remembering the stocks rejected. It
+// is safe for concurrent use.
+type Checker struct {
+	checker *quality.Checker
+
+	mu       sync.Mutex
+	report   *quality.Report
+	rejected map[models.StockKey]bool
+}
+
+// NewChecker checks the stocks with the rules, and their targets against the
+// history for outliers.
+func NewChecker(rules quality.Rules, history []*models.FormattedStock) (*Checker, error) {
+	checker, err := quality.NewChecker(rules, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checker{
+		checker:  checker,
+		report:   quality.NewReport(),
+		rejected: map[models.StockKey]bool{},
+	}, nil
+}
+
+// Check adds the issues of the stock to the report and reports whether the
+// stock passed.
+func (c *Checker) Check(ctx context.Context, stock *models.FormattedStock) bool {
+	issues := c.checker.Check(stock)
+
+	for _, issue := range issues {
+		if issue.Severity == quality.SeverityWarn {
+			slog.WarnContext(ctx, "data quality issue", "rule", issue.Rule, "ticker", stock.Ticker,
+				"brokerage", stock.Brokerage, "issue", issue.Message)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.report.Add(stock, issues)
+	if !quality.Rejected(issues) {
+		return true
+	}
+
+	c.rejected[models.StockKey{Ticker: stock.Ticker, Source: stock.Source}] = true
+	return false
+}
+
+// rejectInvalid remembers a stock the formatter rejected, keyed as the
+// formatter would have, when it has a ticker at all.
+func (c *Checker) rejectInvalid(stock *models.Stock) {
+	ticker := strings.ToUpper(strings.TrimSpace(stock.Ticker))
+	if ticker == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rejected[models.StockKey{Ticker: ticker, Source: strings.ToLower(strings.TrimSpace(stock.Source))}] = true
+}
+
+// Report returns the data-quality report of the checked stocks, it must not
+// be read while stocks are being checked.
+func (c *Checker) Report() *quality.Report {
+	return c.report
+}
+
+// Rejected returns the stocks rejected so far, in order. They never reach the
+// staging table, the reconciliation keeps the stored ones instead of soft
+// deleting them.
+func (c *Checker) Rejected() []models.StockKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.SortedFunc(maps.Keys(c.rejected), func(a, b models.StockKey) int {
+		return cmp.Or(strings.Compare(a.Ticker, b.Ticker), strings.Compare(a.Source, b.Source))
+	})
+}
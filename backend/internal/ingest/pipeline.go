@@ -0,0 +1,358 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"github.com/CorreaJose13/StockAPI/internal/api"
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/quality"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/CorreaJose13/StockAPI/utils"
+)
+
+const (
+	// the stages of the ingest_rows_total metric
+	StageFetched  = "fetched"
+	StageRejected = "rejected"
+	StageWritten  = "written"
+	StageInserted = "inserted"
+	StageUpdated  = "updated"
+	StageDeleted  = "deleted"
+	StagePurged   = "purged"
+
+	defaultFormatWorkers = 4
+	defaultBatchSize     = 500
+	defaultPageBuffer    = 2
+)
+
+var (
+	ErrInvalidStock = errors.New("invalid stock")
+)
+
+type Options struct {
+	// FormatWorkers is the number of goroutines formatting fetched stocks.
+	FormatWorkers int
+	// BatchSize is the number of formatted stocks written per insert.
+	BatchSize int
+	// PageBuffer is how many fetched pages may wait for the formatters before
+	// the fetcher is blocked.
+	PageBuffer int
+	// SkipInvalid drops stocks rejected by the formatter instead of failing
+	// the whole run.
+	SkipInvalid bool
+	// Brokerages names the brokerages of the formatted stocks, the default
+	// registry when nil.
+	Brokerages *brokerage.Registry
+	// Taxonomy maps the ratings and actions of the formatted stocks, the
+	// default one when nil.
+	Taxonomy *taxonomy.Taxonomy
+	// Quality checks the formatted stocks, its severities and thresholds
+	// default to the ones of quality.DefaultRules. The stocks it rejects are
+	// dropped whether SkipInvalid is set or not.
+	Quality quality.Rules
+	// History is the recent rating history the targets are checked against
+	// for outliers, Sync reads it from the history of the original table.
+	History []*models.FormattedStock
+	// Reconcile controls how Sync applies the staged stocks to the original
+	// table.
+	Reconcile models.SyncOptions
+}
+
+// NewOptions reads the pipeline tuning and reconciliation policy from the config.
+func NewOptions(cfg *config.Config) Options {
+	return Options{
+		FormatWorkers: cfg.IngestWorkers,
+		BatchSize:     cfg.IngestBatchSize,
+		PageBuffer:    cfg.IngestPageBuffer,
+		SkipInvalid:   cfg.IngestSkipInvalid,
+		Reconcile: models.SyncOptions{
+			DryRun:           cfg.IngestDryRun,
+			MaxDeletePercent: float64(cfg.SyncMaxDeletePercent),
+			Force:            cfg.SyncForce,
+			Retention:        time.Duration(cfg.SyncRetentionDays) * 24 * time.Hour,
+		},
+	}
+}
+
+type Result struct {
+	Fetched  int
+	Rejected int
+	Written  int
+	// UnmappedBrokerages counts the stocks of the brokerages missing from the
+	// registry by name, stored as the feed spells them.
+	UnmappedBrokerages brokerage.Unmapped
+	// UnmappedLabels counts the ratings and actions missing from the
+	// taxonomy, Sync stores them for review.
+	UnmappedLabels taxonomy.Unmapped
+	// Quality reports the data-quality issues of the formatted stocks, Sync
+	// stores it with the run.
+	Quality *quality.Report
+	// RejectedStocks lists the stocks rejected by the formatter or the
+	// data-quality rules, which Sync keeps from being soft deleted.
+	RejectedStocks []models.StockKey
+	// Plan is the reconciliation applied by Sync, or planned on a dry run.
+	Plan *models.SyncPlan
+}
+
+// BatchWriter persists one batch of formatted stocks.
+type BatchWriter interface {
+	WriteBatch(ctx context.Context, stocks []*models.FormattedStock) error
+}
+
+type tableWriter struct {
+	store     Store
+	tableName string
+}
+
+func NewTableWriter(store Store, tableName string) *tableWriter {
+	return &tableWriter{
+		store:     store,
+		tableName: tableName,
+	}
+}
+
+func (tw *tableWriter) WriteBatch(ctx context.Context, stocks []*models.FormattedStock) error {
+	return tw.store.InsertStocksBatch(ctx, stocks, tw.tableName)
+}
+
+type pipeline struct {
+	opts      Options
+	formatter utils.StockFormatter
+	checker   *Checker
+	source    string
+	cancel    context.CancelFunc
+
+	mu     sync.Mutex
+	err    error
+	result Result
+}
+
+// Run streams the source through the formatting workers into the writer. The
+// stages are connected by bounded channels, so a slow writer holds back the
+// formatters and these the fetcher. The first error cancels every stage.
+func Run(ctx context.Context, source api.RatingsSource, writer BatchWriter, opts Options) (*Result, error) {
+	opts = withDefaults(opts)
+
+	checker, err := NewChecker(opts.Quality, opts.History)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &pipeline{
+		opts:      opts,
+		formatter: utils.StockFormatter{Brokerages: opts.Brokerages, Taxonomy: opts.Taxonomy},
+		checker:   checker,
+		source:    source.Name(),
+		cancel:    cancel,
+		result: Result{
+			UnmappedBrokerages: brokerage.Unmapped{},
+			UnmappedLabels:     taxonomy.Unmapped{},
+			Quality:            checker.Report(),
+		},
+	}
+
+	pages := make(chan []models.Stock, opts.PageBuffer)
+	formatted := make(chan *models.FormattedStock, opts.BatchSize)
+
+	var fetchWG, formatWG, writeWG sync.WaitGroup
+
+	fetchWG.Add(1)
+	go func() {
+		defer fetchWG.Done()
+		defer close(pages)
+
+		start := time.Now()
+		err := fetch(ctx, source, pages)
+		telemetry.ObserveFetch(p.source, err, time.Since(start))
+		p.fail(err)
+	}()
+
+	for range opts.FormatWorkers {
+		formatWG.Add(1)
+		go func() {
+			defer formatWG.Done()
+			p.fail(p.format(ctx, pages, formatted))
+		}()
+	}
+
+	writeWG.Add(1)
+	go func() {
+		defer writeWG.Done()
+		p.fail(p.write(ctx, writer, formatted))
+	}()
+
+	fetchWG.Wait()
+	formatWG.Wait()
+	close(formatted)
+	writeWG.Wait()
+
+	p.result.RejectedStocks = checker.Rejected()
+
+	if p.err != nil {
+		return &p.result, p.err
+	}
+
+	// the parent context may have been cancelled without any stage noticing
+	if err := ctx.Err(); err != nil {
+		return &p.result, err
+	}
+
+	slog.InfoContext(ctx, "ingested stocks", "source", source.Name(),
+		"fetched", p.result.Fetched, "written", p.result.Written, "rejected", p.result.Rejected,
+		"quality_issues", p.result.Quality.Issues())
+
+	return &p.result, nil
+}
+
+func withDefaults(opts Options) Options {
+	if opts.FormatWorkers <= 0 {
+		opts.FormatWorkers = defaultFormatWorkers
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.PageBuffer <= 0 {
+		opts.PageBuffer = defaultPageBuffer
+	}
+	if opts.Brokerages == nil {
+		opts.Brokerages = brokerage.Default()
+	}
+	if opts.Taxonomy == nil {
+		opts.Taxonomy = taxonomy.Default()
+	}
+	return opts
+}
+
+func fetch(ctx context.Context, source api.RatingsSource, pages chan<- []models.Stock) error {
+	if paged, ok := source.(api.PagedSource); ok {
+		return paged.StreamPages(ctx, pages)
+	}
+
+	stocks, err := source.FetchStocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case pages <- stocks:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pipeline) format(ctx context.Context, pages <-chan []models.Stock, formatted chan<- *models.FormattedStock) error {
+	for page := range pages {
+		p.count(func(r *Result) { r.Fetched += len(page) })
+		telemetry.CountPage(p.source)
+		telemetry.CountRows(StageFetched, len(page))
+
+		for i := range page {
+			formattedStock, err := p.formatter.Format(&page[i])
+			if err != nil {
+				if !p.opts.SkipInvalid {
+					return fmt.Errorf("%w: %v", ErrInvalidStock, err)
+				}
+
+				slog.WarnContext(ctx, "skipping invalid stock", "error", err)
+				p.checker.rejectInvalid(&page[i])
+				p.count(func(r *Result) { r.Rejected++ })
+				telemetry.CountRows(StageRejected, 1)
+				continue
+			}
+
+			if !p.checker.Check(ctx, formattedStock) {
+				p.count(func(r *Result) { r.Rejected++ })
+				telemetry.CountRows(StageRejected, 1)
+				continue
+			}
+
+			p.count(func(r *Result) {
+				r.UnmappedBrokerages.Add(p.opts.Brokerages, formattedStock.Brokerage)
+				r.UnmappedLabels.Add(p.opts.Taxonomy, formattedStock)
+			})
+
+			select {
+			case formatted <- formattedStock:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (p *pipeline) write(ctx context.Context, writer BatchWriter, formatted <-chan *models.FormattedStock) error {
+	batch := make([]*models.FormattedStock, 0, p.opts.BatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := writer.WriteBatch(ctx, batch); err != nil {
+			return fmt.Errorf("error writing batch: %w", err)
+		}
+
+		p.count(func(r *Result) { r.Written += len(batch) })
+		telemetry.CountRows(StageWritten, len(batch))
+		batch = make([]*models.FormattedStock, 0, p.opts.BatchSize)
+
+		return nil
+	}
+
+	for stock := range formatted {
+		if ctx.Err() != nil {
+			continue
+		}
+
+		batch = append(batch, stock)
+		if len(batch) < p.opts.BatchSize {
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// fail records the first error and cancels the remaining stages. Cancellation
+// errors caused by that first error are ignored.
+func (p *pipeline) fail(err error) {
+	if err == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+func (p *pipeline) count(update func(*Result)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	update(&p.result)
+}
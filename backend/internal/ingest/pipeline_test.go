@@ -0,0 +1,342 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/quality"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type pagedSource struct {
+	pages [][]models.Stock
+	err   error
+}
+
+func (ps *pagedSource) Name() string {
+	return "paged"
+}
+
+func (ps *pagedSource) FetchStocks(ctx context.Context) ([]models.Stock, error) {
+	var stocks []models.Stock
+	for _, page := range ps.pages {
+		stocks = append(stocks, page...)
+	}
+	return stocks, ps.err
+}
+
+func (ps *pagedSource) StreamPages(ctx context.Context, pages chan<- []models.Stock) error {
+	for _, page := range ps.pages {
+		select {
+		case pages <- page:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ps.err
+}
+
+// plainSource shadows StreamPages so the pipeline falls back to FetchStocks.
+type plainSource struct {
+	pagedSource
+}
+
+func (ps *plainSource) StreamPages() {}
+
+type recordingWriter struct {
+	mu      sync.Mutex
+	batches [][]*models.FormattedStock
+	err     error
+}
+
+func (rw *recordingWriter) WriteBatch(ctx context.Context, stocks []*models.FormattedStock) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.err != nil {
+		return rw.err
+	}
+
+	rw.batches = append(rw.batches, stocks)
+	return nil
+}
+
+func (rw *recordingWriter) total() int {
+	total := 0
+	for _, batch := range rw.batches {
+		total += len(batch)
+	}
+	return total
+}
+
+func validStock(ticker string) models.Stock {
+	return models.Stock{
+		Ticker:     ticker,
+		TargetFrom: "$100",
+		TargetTo:   "$120",
+		Company:    ticker + " Inc.",
+		Action:     "upgraded by",
+		Brokerage:  "Barclays",
+		RatingFrom: "hold",
+		RatingTo:   "buy",
+		Time:       "2024-01-08T00:00:00Z",
+	}
+}
+
+func makePages(pageCount, pageSize int) [][]models.Stock {
+	pages := make([][]models.Stock, 0, pageCount)
+	for p := range pageCount {
+		page := make([]models.Stock, 0, pageSize)
+		for i := range pageSize {
+			page = append(page, validStock(fmt.Sprintf("T%d_%d", p, i)))
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func TestRun(t *testing.T) {
+	source := &pagedSource{pages: makePages(5, 7)}
+	writer := &recordingWriter{}
+
+	result, err := Run(context.Background(), source, writer, Options{FormatWorkers: 3, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	if result.Fetched != 35 || result.Written != 35 || result.Rejected != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if writer.total() != 35 {
+		t.Errorf("Expected 35 written stocks, got %d", writer.total())
+	}
+
+	for i, batch := range writer.batches {
+		if len(batch) > 10 {
+			t.Errorf("Batch %d has %d stocks, want at most 10", i, len(batch))
+		}
+	}
+}
+
+func TestRunNonPagedSource(t *testing.T) {
+	source := &plainSource{pagedSource{pages: makePages(2, 3)}}
+	writer := &recordingWriter{}
+
+	result, err := Run(context.Background(), source, writer, Options{})
+	if err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	if result.Written != 6 {
+		t.Errorf("Expected 6 written stocks, got %d", result.Written)
+	}
+}
+
+func TestRunUnmappedBrokerages(t *testing.T) {
+	page := []models.Stock{validStock("AAPL"), validStock("MSFT"), validStock("AMZN"), validStock("TSLA")}
+	page[1].Brokerage = "JP Morgan"
+	page[2].Brokerage = "Acme Research"
+	page[3].Brokerage = "Acme Research"
+
+	writer := &recordingWriter{}
+	result, err := Run(context.Background(), &pagedSource{pages: [][]models.Stock{page}}, writer, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if got := writer.batches[0][1].Brokerage; got != "JPMorgan Chase & Co." {
+		t.Errorf("brokerage = %q, want the canonical name", got)
+	}
+
+	if len(result.UnmappedBrokerages) != 1 || result.UnmappedBrokerages["Acme Research"] != 2 {
+		t.Errorf("UnmappedBrokerages = %v, want Acme Research twice", result.UnmappedBrokerages)
+	}
+}
+
+func TestRunUnmappedLabels(t *testing.T) {
+	page := []models.Stock{validStock("AAPL"), validStock("MSFT")}
+	page[0].RatingTo = "Accumulate"
+	page[1].RatingTo = "Conviction List"
+	page[1].Action = "removed from list"
+
+	writer := &recordingWriter{}
+	result, err := Run(context.Background(), &pagedSource{pages: [][]models.Stock{page}}, writer, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if got := writer.batches[0][0].RatingTo; got != "outperform" {
+		t.Errorf("rating = %q, want outperform", got)
+	}
+
+	want := []string{"action:removed from list", "rating:conviction list"}
+	if got := result.UnmappedLabels.Names(); !slices.Equal(got, want) {
+		t.Errorf("UnmappedLabels = %v, want %v", got, want)
+	}
+}
+
+func TestRunQualityChecks(t *testing.T) {
+	page := []models.Stock{validStock("AAPL"), validStock("AAPL"), validStock("MSFT"), validStock("AMZN"), validStock("TSLA")}
+	page[2].TargetTo = "$0.01"
+	page[3].Time = time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	page[4].TargetTo = "$20"
+
+	history := []*models.FormattedStock{}
+	for _, target := range []json.Number{"118", "120", "121", "119", "122"} {
+		history = append(history, &models.FormattedStock{Ticker: "TSLA", TargetTo: target, Currency: "USD"})
+	}
+
+	writer := &recordingWriter{}
+	result, err := Run(context.Background(), &pagedSource{pages: [][]models.Stock{page}}, writer, Options{History: history})
+	if err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	if result.Fetched != 5 || result.Rejected != 3 || result.Written != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	report := result.Quality.Summary()
+	if report.Checked != 5 || report.Rejected != 3 || report.Flagged != 1 {
+		t.Errorf("Unexpected quality report: %+v", report)
+	}
+
+	want := map[string]int{quality.RuleDuplicate: 1, quality.RuleTargetChange: 1, quality.RuleFutureTime: 1, quality.RuleOutlier: 1}
+	if got := result.Quality.Issues(); !maps.Equal(got, want) {
+		t.Errorf("Issues = %v, want %v", got, want)
+	}
+}
+
+func TestRunInvalidStock(t *testing.T) {
+	pages := makePages(1, 3)
+	pages[0][1].Ticker = ""
+
+	t.Run("Fails by default", func(t *testing.T) {
+		_, err := Run(context.Background(), &pagedSource{pages: pages}, &recordingWriter{}, Options{})
+		if !errors.Is(err, ErrInvalidStock) {
+			t.Errorf("Expected ErrInvalidStock, got %v", err)
+		}
+	})
+
+	t.Run("Skipped when enabled", func(t *testing.T) {
+		result, err := Run(context.Background(), &pagedSource{pages: pages}, &recordingWriter{}, Options{SkipInvalid: true})
+		if err != nil {
+			t.Fatalf("Run returned unexpected error: %v", err)
+		}
+
+		if result.Rejected != 1 || result.Written != 2 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestRunWriterError(t *testing.T) {
+	writerErr := errors.New("insert failed")
+	source := &pagedSource{pages: makePages(50, 20)}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run(context.Background(), source, &recordingWriter{err: writerErr}, Options{BatchSize: 5})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, writerErr) {
+			t.Errorf("Expected writer error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after the writer failed")
+	}
+}
+
+func TestRunSourceError(t *testing.T) {
+	sourceErr := errors.New("upstream unavailable")
+	source := &pagedSource{pages: makePages(2, 2), err: sourceErr}
+
+	_, err := Run(context.Background(), source, &recordingWriter{}, Options{})
+	if !errors.Is(err, sourceErr) {
+		t.Errorf("Expected source error, got %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Run(ctx, &pagedSource{pages: makePages(10, 10)}, &recordingWriter{}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// fakeStore reconciles like the database does: the stored stocks missing from
+// the staging table are deleted unless rejected.
+type fakeStore struct {
+	stored  []models.StockKey
+	staged  map[models.StockKey]bool
+	deleted []models.StockKey
+}
+
+func (fs *fakeStore) PrepareStagingTable(ctx context.Context, originalTable string) (string, error) {
+	fs.staged = map[models.StockKey]bool{}
+	return originalTable + "_staging", nil
+}
+
+func (fs *fakeStore) InsertStocksBatch(ctx context.Context, stocks []*models.FormattedStock, tableName string) error {
+	for _, stock := range stocks {
+		fs.staged[models.StockKey{Ticker: stock.Ticker, Source: stock.Source}] = true
+	}
+	return nil
+}
+
+func (fs *fakeStore) GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error) {
+	return nil, nil
+}
+
+func (fs *fakeStore) ReconcileStocks(ctx context.Context, originalTable, tempTable string, opts models.SyncOptions) (*models.SyncPlan, error) {
+	plan := &models.SyncPlan{LiveRows: len(fs.stored)}
+	for _, key := range fs.stored {
+		if !fs.staged[key] && !slices.Contains(opts.Rejected, key) {
+			fs.deleted = append(fs.deleted, key)
+			plan.Deletes = append(plan.Deletes, &models.FormattedStock{Ticker: key.Ticker, Source: key.Source})
+		}
+	}
+	return plan, nil
+}
+
+func (fs *fakeStore) DropTable(ctx context.Context, tableName string) error {
+	return nil
+}
+
+func (fs *fakeStore) SaveUnmappedLabels(ctx context.Context, labels []*models.UnmappedLabel) error {
+	return nil
+}
+
+func TestSyncKeepsRejectedStocks(t *testing.T) {
+	page := []models.Stock{validStock("AAPL"), validStock("MSFT"), validStock("AMZN")}
+	page[1].TargetTo = "$0.01"
+	page[2].Brokerage = ""
+
+	store := &fakeStore{stored: []models.StockKey{{Ticker: "AAPL"}, {Ticker: "MSFT"}, {Ticker: "AMZN"}, {Ticker: "TSLA"}}}
+	result, err := Sync(context.Background(), store, &pagedSource{pages: [][]models.Stock{page}}, "stocks",
+		Options{SkipInvalid: true})
+	if err != nil {
+		t.Fatalf("Sync returned unexpected error: %v", err)
+	}
+
+	if want := []models.StockKey{{Ticker: "AMZN"}, {Ticker: "MSFT"}}; !slices.Equal(result.RejectedStocks, want) {
+		t.Errorf("RejectedStocks = %v, want %v", result.RejectedStocks, want)
+	}
+	if want := []models.StockKey{{Ticker: "TSLA"}}; !slices.Equal(store.deleted, want) {
+		t.Errorf("Expected only TSLA, missing from the feed, to be deleted, got %v", store.deleted)
+	}
+}
@@ -0,0 +1,103 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/api"
+	"github.com/CorreaJose13/StockAPI/internal/quality"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// Store stages the synced stocks and applies them to the original table.
+type Store interface {
+	PrepareStagingTable(ctx context.Context, originalTable string) (string, error)
+	InsertStocksBatch(ctx context.Context, stocks []*models.FormattedStock, tableName string) error
+	GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error)
+	ReconcileStocks(ctx context.Context, originalTable, tempTable string, opts models.SyncOptions) (*models.SyncPlan, error)
+	DropTable(ctx context.Context, tableName string) error
+	SaveUnmappedLabels(ctx context.Context, labels []*models.UnmappedLabel) error
+}
+
+// Sync streams the source into a staging table unique to this run and then
+// reconciles the original table with it in a single transaction, storing the
+// data-quality report with the run. The staging table is dropped once the run
+// finishes.
+func Sync(ctx context.Context, store Store, source api.RatingsSource, originalTable string, opts Options) (result *Result, err error) {
+	tempTable, err := store.PrepareStagingTable(ctx, originalTable)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		// the staging table is dropped even when the run was cancelled
+		if dropErr := store.DropTable(context.WithoutCancel(ctx), tempTable); dropErr != nil {
+			err = errors.Join(err, dropErr)
+		}
+	}()
+
+	lookback := opts.Quality.HistoryLookback
+	if lookback <= 0 {
+		lookback = quality.DefaultRules().HistoryLookback
+	}
+
+	// the outliers are only checked against the history that could be read,
+	// none on the first sync of a table
+	opts.History, err = store.GetStocksHistory(ctx, originalTable, time.Now().Add(-lookback), time.Time{})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read the history checked for outliers", "table", originalTable, "error", err)
+	}
+
+	result, err = Run(ctx, source, NewTableWriter(store, tempTable), opts)
+	if err != nil {
+		return result, fmt.Errorf("error streaming stocks into %s: %w", tempTable, err)
+	}
+
+	opts.Reconcile.Quality = result.Quality.Summary()
+	opts.Reconcile.Rejected = result.RejectedStocks
+
+	result.Plan, err = store.ReconcileStocks(ctx, originalTable, tempTable, opts.Reconcile)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Plan.DryRun {
+		return result, nil
+	}
+
+	CountPlan(result.Plan)
+
+	// the stocks are already stored, losing the labels only delays their review
+	labels := result.UnmappedLabels.Labels()
+	if err := store.SaveUnmappedLabels(ctx, labels); err != nil {
+		slog.WarnContext(ctx, "failed to save unmapped labels", "error", err)
+	} else if firstSeen := firstSeenLabels(labels); len(firstSeen) > 0 {
+		slog.WarnContext(ctx, "labels first seen by this sync", "labels", firstSeen)
+	}
+
+	return result, nil
+}
+
+// firstSeenLabels names the saved labels no earlier sync had stored, the
+// ones whose first and last sightings are the same.
+func firstSeenLabels(labels []*models.UnmappedLabel) []string {
+	var names []string
+	for _, label := range labels {
+		if label.FirstSeen.Equal(label.LastSeen) {
+			names = append(names, label.Kind+":"+label.Source+":"+label.Label)
+		}
+	}
+	return names
+}
+
+// CountPlan adds the rows changed by an applied plan to the ingest metrics.
+func CountPlan(plan *models.SyncPlan) {
+	telemetry.CountRows(StageInserted, len(plan.Inserts))
+	telemetry.CountRows(StageUpdated, len(plan.Updates))
+	telemetry.CountRows(StageDeleted, len(plan.Deletes))
+	telemetry.CountRows(StagePurged, len(plan.Purges))
+}
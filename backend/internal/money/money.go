@@ -0,0 +1,289 @@
+// Package money parses the price targets of the feeds into exact decimal
+// amounts with their currency, and converts them between currencies.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultCurrency is the currency of the targets without a symbol or code.
+const DefaultCurrency = "USD"
+
+// scale is the number of decimal places of a Decimal, enough for the rates of
+// currencies such as JPY or KRW.
+const scale = 8
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrOutOfRange      = errors.New("amount out of range")
+
+	unit = pow10(scale)
+
+	// symbols are matched longest first so C$ isn't read as $.
+	symbols = []struct {
+		symbol   string
+		currency string
+	}{
+		{"US$", "USD"}, {"CA$", "CAD"}, {"AU$", "AUD"}, {"NZ$", "NZD"}, {"HK$", "HKD"}, {"S$", "SGD"},
+		{"C$", "CAD"}, {"A$", "AUD"}, {"R$", "BRL"}, {"MX$", "MXN"},
+		{"$", "USD"}, {"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"₩", "KRW"}, {"₣", "CHF"},
+	}
+
+	// currencies are the ISO 4217 codes accepted, with the minor units some
+	// markets quote in, such as London in pence.
+	currencies = map[string]struct {
+		currency string
+		divisor  int64
+	}{
+		"USD": {"USD", 1}, "EUR": {"EUR", 1}, "GBP": {"GBP", 1}, "CAD": {"CAD", 1}, "AUD": {"AUD", 1},
+		"NZD": {"NZD", 1}, "JPY": {"JPY", 1}, "CHF": {"CHF", 1}, "HKD": {"HKD", 1}, "SGD": {"SGD", 1},
+		"CNY": {"CNY", 1}, "INR": {"INR", 1}, "KRW": {"KRW", 1}, "BRL": {"BRL", 1}, "MXN": {"MXN", 1},
+		"SEK": {"SEK", 1}, "NOK": {"NOK", 1}, "DKK": {"DKK", 1}, "ZAR": {"ZAR", 1}, "ILS": {"ILS", 1},
+		"GBX": {"GBP", 100}, "GBp": {"GBP", 100}, "ZAc": {"ZAR", 100}, "ILA": {"ILS", 100},
+	}
+)
+
+// Decimal is an exact amount with eight decimal places.
+type Decimal int64
+
+// NewDecimal rounds f to the decimal places of a Decimal, it fails when f
+// isn't a number or doesn't fit.
+func NewDecimal(f float64) (Decimal, error) {
+	value := math.Round(f * float64(unit))
+	// float64(math.MaxInt64) rounds up to 2^63, which doesn't fit either
+	if math.IsNaN(value) || value >= math.MaxInt64 || value < math.MinInt64 {
+		return 0, fmt.Errorf("%w: %v", ErrOutOfRange, f)
+	}
+	return Decimal(value), nil
+}
+
+// ParseDecimal reads a plain decimal number such as -1200.5, without
+// thousands separators.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || !digits(whole) || !digits(fraction) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	// rounding half away from zero on the first dropped digit
+	round := false
+	if len(fraction) > scale {
+		round = fraction[scale] >= '5'
+		fraction = fraction[:scale]
+	}
+	fraction += strings.Repeat("0", scale-len(fraction))
+
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || round && value == math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %w: %q", ErrInvalidAmount, ErrOutOfRange, s)
+	}
+
+	if round {
+		value++
+	}
+	if negative {
+		value = -value
+	}
+
+	return Decimal(value), nil
+}
+
+func (d Decimal) Float64() float64 {
+	return float64(d) / float64(unit)
+}
+
+// Mul returns d times m rounded half away from zero, it fails when the
+// product doesn't fit.
+func (d Decimal) Mul(m Decimal) (Decimal, error) {
+	return div(new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(m))), big.NewInt(unit))
+}
+
+// Div returns d divided by m rounded half away from zero, it fails when m is
+// zero or the quotient doesn't fit.
+func (d Decimal) Div(m Decimal) (Decimal, error) {
+	return div(new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(unit)), big.NewInt(int64(m)))
+}
+
+// String formats the decimal with at least two decimal places, 12.5 as
+// "12.50" and 0.00671234 as "0.00671234".
+func (d Decimal) String() string {
+	sign := ""
+	value := int64(d)
+	if value < 0 {
+		sign, value = "-", -value
+	}
+
+	fraction := strings.TrimRight(fmt.Sprintf("%0*d", scale, value%unit), "0")
+	if len(fraction) < 2 {
+		fraction += strings.Repeat("0", 2-len(fraction))
+	}
+
+	return fmt.Sprintf("%s%d.%s", sign, value/unit, fraction)
+}
+
+func div(numerator, denominator *big.Int) (Decimal, error) {
+	if denominator.Sign() == 0 {
+		return 0, fmt.Errorf("%w: division by zero", ErrOutOfRange)
+	}
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	// half away from zero, the remainder has the sign of the numerator
+	if new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2))).Cmp(new(big.Int).Abs(denominator)) >= 0 {
+		if numerator.Sign()*denominator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return 0, ErrOutOfRange
+	}
+
+	return Decimal(quotient.Int64()), nil
+}
+
+// Money is an amount in an ISO 4217 currency.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+// Parse reads the price formats of the feeds: a symbol or a code before or
+// after the number, such as "$1,200.50", "€45", "C$30", "45 EUR" or
+// "GBX 1,200", and decimal commas such as "1.234,56 €". Amounts in minor
+// units, GBX for pence, are converted to their currency. Without symbol or
+// code the currency is empty, for the caller to decide.
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+
+	number, currency, err := splitCurrency(s)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount, err := ParseDecimal(normalizeSeparators(number))
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	if currency == "" {
+		return Money{Amount: amount}, nil
+	}
+
+	code := currencies[currency]
+	if code.divisor != 1 {
+		amount, err = amount.Div(Decimal(code.divisor * unit))
+		if err != nil {
+			return Money{}, fmt.Errorf("%w: %q: %w", ErrInvalidAmount, s, err)
+		}
+	}
+
+	return Money{Amount: amount, Currency: code.currency}, nil
+}
+
+// Valid reports whether code is a currency Parse returns.
+func Valid(code string) bool {
+	c, ok := currencies[code]
+	return ok && c.currency == code
+}
+
+// splitCurrency separates the number from the symbol or code around it.
+func splitCurrency(s string) (string, string, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimSpace(strings.TrimPrefix(s, "-"))
+
+	currency := ""
+	for _, symbol := range symbols {
+		if rest, ok := strings.CutPrefix(s, symbol.symbol); ok {
+			s, currency = rest, symbol.currency
+			break
+		}
+		if rest, ok := strings.CutSuffix(s, symbol.symbol); ok {
+			s, currency = rest, symbol.currency
+			break
+		}
+	}
+
+	if currency == "" {
+		start := strings.IndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == '.' || r == ',' || r == '-' })
+		end := strings.LastIndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == '.' || r == ',' })
+		if start < 0 || end < 0 {
+			return "", "", fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+
+		code := strings.TrimSpace(s[:start] + s[end+1:])
+		s = s[start : end+1]
+
+		if code != "" {
+			// GBp and ZAc are case sensitive, the other codes are not
+			if _, ok := currencies[code]; !ok {
+				code = strings.ToUpper(code)
+			}
+			if _, ok := currencies[code]; !ok {
+				return "", "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+			}
+			currency = code
+		}
+	}
+
+	s = strings.TrimSpace(s)
+	if negative {
+		s = "-" + s
+	}
+
+	return s, currency, nil
+}
+
+// normalizeSeparators drops the thousands separators and writes the decimal
+// separator as a point. With both separators the last one is the decimal
+// one, a lone comma is a thousands separator when followed by three digits.
+func normalizeSeparators(s string) string {
+	lastComma, lastPoint := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+
+	switch {
+	case lastComma >= 0 && lastPoint >= 0 && lastComma > lastPoint:
+		s = strings.ReplaceAll(s, ".", "")
+		return strings.Replace(s, ",", ".", 1)
+	case lastComma >= 0 && lastPoint >= 0:
+		return strings.ReplaceAll(s, ",", "")
+	case lastComma >= 0 && strings.Count(s, ",") == 1 && len(s)-lastComma-1 != 3:
+		return strings.Replace(s, ",", ".", 1)
+	default:
+		return strings.ReplaceAll(s, ",", "")
+	}
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,231 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"Dollars", "$1,200.50", "1200.50 USD", nil},
+		{"Bare number", "45.5", "45.50 ", nil},
+		{"Euro symbol", "€45", "45.00 EUR", nil},
+		{"Symbol after the number", "45 €", "45.00 EUR", nil},
+		{"Canadian dollars", "C$30", "30.00 CAD", nil},
+		{"Hong Kong dollars", "HK$88.2", "88.20 HKD", nil},
+		{"Code before the number", "EUR 45", "45.00 EUR", nil},
+		{"Code after the number", "45.10 chf", "45.10 CHF", nil},
+		{"Pence", "GBX 1,200", "12.00 GBP", nil},
+		{"Pence lowercase p", "1250GBp", "12.50 GBP", nil},
+		{"Decimal comma", "1.234,56 €", "1234.56 EUR", nil},
+		{"Lone decimal comma", "45,5 EUR", "45.50 EUR", nil},
+		{"Negative", "-$3.25", "-3.25 USD", nil},
+		{"Unknown code", "XYZ 30", "", ErrUnknownCurrency},
+		{"Not a number", "$abc", "", ErrInvalidAmount},
+		{"Empty", "", "", ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Parse(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil || got.String() != tt.want {
+				t.Errorf("Parse(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Decimal
+		wantErr bool
+	}{
+		{"12", 12_00000000, false},
+		{"0.5", 50000000, false},
+		{".25", 25000000, false},
+		{"-1.5", -1_50000000, false},
+		{"0.123456785", 12345679, false},
+		{"1,200", 0, true},
+		{"", 0, true},
+		{"1e3", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDecimal(tt.input)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseDecimal(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	price, _ := ParseDecimal("45.10")
+	rate, _ := ParseDecimal("1.0842")
+
+	if got, err := price.Mul(rate); err != nil || got.String() != "48.89742" {
+		t.Errorf("Mul = %s, %v, want 48.89742", got, err)
+	}
+
+	one, _ := ParseDecimal("1")
+	three, _ := ParseDecimal("3")
+	if got, err := one.Div(three); err != nil || got.String() != "0.33333333" {
+		t.Errorf("Div = %s, %v, want 0.33333333", got, err)
+	}
+
+	two, _ := ParseDecimal("2")
+	if got, err := two.Div(three); err != nil || got.String() != "0.66666667" {
+		t.Errorf("Div = %s, %v, want 0.66666667 rounded up", got, err)
+	}
+
+	if got, err := NewDecimal(-0.1); err != nil || got.String() != "-0.10" {
+		t.Errorf("NewDecimal(-0.1) = %s, %v, want -0.10", got, err)
+	}
+
+	billion, _ := ParseDecimal("1000000000")
+	if _, err := billion.Mul(billion); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Mul error = %v, want %v", err, ErrOutOfRange)
+	}
+	if _, err := one.Div(0); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Div by zero error = %v, want %v", err, ErrOutOfRange)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), 1e11, -1e11} {
+		if _, err := NewDecimal(f); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("NewDecimal(%v) error = %v, want %v", f, err, ErrOutOfRange)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rates, err := NewRates([]*models.FXRate{
+		{Base: "EUR", Quote: "USD", Rate: "1.1"},
+		{Base: "USD", Quote: "CAD", Rate: "1.25"},
+		{Base: "GBP", Quote: "USD", Rate: "0"},
+	})
+	if err != nil {
+		t.Fatalf("NewRates returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		from    Money
+		to      string
+		want    string
+		wantErr error
+	}{
+		{"Same currency", Money{Amount: Decimal(10 * unit), Currency: "USD"}, "USD", "10.00 USD", nil},
+		{"Direct", Money{Amount: Decimal(10 * unit), Currency: "EUR"}, "USD", "11.00 USD", nil},
+		{"Inverse", Money{Amount: Decimal(25 * unit), Currency: "CAD"}, "USD", "20.00 USD", nil},
+		{"Cross", Money{Amount: Decimal(10 * unit), Currency: "EUR"}, "CAD", "13.75 CAD", nil},
+		{"Rates that aren't positive are ignored", Money{Amount: Decimal(10 * unit), Currency: "GBP"}, "USD", "", ErrNoRate},
+		{"Unknown currency", Money{Amount: Decimal(10 * unit), Currency: "JPY"}, "USD", "", ErrNoRate},
+		{"Out of range", Money{Amount: Decimal(math.MaxInt64), Currency: "EUR"}, "USD", "", ErrOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Convert(%s, %s) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil || got.String() != tt.want {
+				t.Errorf("Convert(%s, %s) = %s, %v, want %s", tt.from, tt.to, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	rates, err := NewRates([]*models.FXRate{{Base: "EUR", Quote: "USD", Rate: "1.1"}})
+	if err != nil {
+		t.Fatalf("NewRates returned unexpected error: %v", err)
+	}
+
+	stocks := []*models.FormattedStock{
+		{Ticker: "SAP", TargetFrom: "100", TargetTo: "120", Currency: "EUR"},
+		{Ticker: "AAPL", TargetFrom: "150", TargetTo: "180", Currency: "USD"},
+		{Ticker: "7203", TargetFrom: "3000", TargetTo: "3200", Currency: "JPY"},
+	}
+
+	reported, missing, err := rates.Report(stocks, "USD")
+	if err != nil {
+		t.Fatalf("Report returned unexpected error: %v", err)
+	}
+
+	if got := reported[0]; got.TargetFrom != "110.00" || got.TargetTo != "132.00" || got.Currency != "USD" {
+		t.Errorf("Expected SAP targets of 110 and 132 USD, got %v and %v %s", got.TargetFrom, got.TargetTo, got.Currency)
+	}
+	if stocks[0].Currency != "EUR" {
+		t.Errorf("Expected the stocks to be copied, SAP is now in %s", stocks[0].Currency)
+	}
+	if reported[1] != stocks[1] || reported[2] != stocks[2] {
+		t.Errorf("Expected the stocks in dollars or without a rate to be returned as they are")
+	}
+	if !slices.Equal(missing, []string{"JPY"}) {
+		t.Errorf("Expected JPY to be missing, got %v", missing)
+	}
+}
+
+func TestReadRates(t *testing.T) {
+	rates, err := ReadRates(strings.NewReader("base,quote,rate,as_of\neur,USD,1.0842,2024-03-05\nUSD,JPY,149.5,2024-03-05\n"))
+	if err != nil {
+		t.Fatalf("ReadRates returned unexpected error: %v", err)
+	}
+
+	want := []models.FXRate{
+		{Base: "EUR", Quote: "USD", Rate: "1.0842", AsOf: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{Base: "USD", Quote: "JPY", Rate: "149.50", AsOf: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(rates) != len(want) {
+		t.Fatalf("Expected %d rates, got %d", len(want), len(rates))
+	}
+	for i, rate := range rates {
+		if *rate != want[i] {
+			t.Errorf("Expected rate %+v, got %+v", want[i], *rate)
+		}
+	}
+
+	invalid := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"No header", "EUR,USD,1.1,2024-03-05\n", ErrInvalidRate},
+		{"Unknown code", "base,quote,rate,as_of\nXYZ,USD,1.1,2024-03-05\n", ErrUnknownCurrency},
+		{"Negative rate", "base,quote,rate,as_of\nEUR,USD,-1.1,2024-03-05\n", ErrInvalidRate},
+		{"Same currencies", "base,quote,rate,as_of\nEUR,EUR,1,2024-03-05\n", ErrInvalidRate},
+		{"Invalid date", "base,quote,rate,as_of\nEUR,USD,1.1,03/05/2024\n", ErrInvalidRate},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadRates(strings.NewReader(tt.input)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadRates error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
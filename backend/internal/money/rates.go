@@ -0,0 +1,243 @@
+package money
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+var (
+	ErrNoRate      = errors.New("no exchange rate")
+	ErrInvalidRate = errors.New("invalid exchange rate")
+
+	ratesHeader = []string{"base", "quote", "rate", "as_of"}
+)
+
+type pair struct {
+	base  string
+	quote string
+}
+
+// Rates converts amounts with the exchange rates stored for a day, directly,
+// through the inverse rate or across a third currency.
+type Rates struct {
+	rates    map[pair]Decimal
+	inverses map[pair]Decimal
+}
+
+// NewRates indexes the rates and their inverses by currency pair, rates that
+// aren't positive are ignored.
+func NewRates(rates []*models.FXRate) (*Rates, error) {
+	r := &Rates{
+		rates:    make(map[pair]Decimal, len(rates)),
+		inverses: make(map[pair]Decimal, len(rates)),
+	}
+
+	for _, rate := range rates {
+		value, err := ParseDecimal(rate.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("rate %s/%s: %w", rate.Base, rate.Quote, err)
+		}
+		if value <= 0 {
+			continue
+		}
+
+		inverse, err := Decimal(unit).Div(value)
+		if err != nil {
+			return nil, fmt.Errorf("rate %s/%s: %w", rate.Base, rate.Quote, err)
+		}
+
+		r.rates[pair{rate.Base, rate.Quote}] = value
+		r.inverses[pair{rate.Quote, rate.Base}] = inverse
+	}
+
+	return r, nil
+}
+
+// Convert returns the amount in the currency to.
+func (r *Rates) Convert(m Money, to string) (Money, error) {
+	rate, err := r.rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount, err := m.Amount.Mul(rate)
+	if err != nil {
+		return Money{}, fmt.Errorf("%s %s to %s: %w", m.Amount, m.Currency, to, err)
+	}
+
+	return Money{Amount: amount, Currency: to}, nil
+}
+
+func (r *Rates) rate(base, quote string) (Decimal, error) {
+	if base == quote {
+		return Decimal(unit), nil
+	}
+
+	if rate, ok := r.direct(base, quote); ok {
+		return rate, nil
+	}
+
+	// through the first currency quoted against both, in order for the same
+	// rates to always give the same amounts
+	for _, via := range r.currencies() {
+		first, ok := r.direct(base, via)
+		if !ok {
+			continue
+		}
+		second, ok := r.direct(via, quote)
+		if !ok {
+			continue
+		}
+
+		rate, err := first.Mul(second)
+		if err != nil {
+			return 0, fmt.Errorf("rate %s/%s through %s: %w", base, quote, via, err)
+		}
+		return rate, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s to %s", ErrNoRate, base, quote)
+}
+
+func (r *Rates) direct(base, quote string) (Decimal, bool) {
+	if rate, ok := r.rates[pair{base, quote}]; ok {
+		return rate, true
+	}
+	rate, ok := r.inverses[pair{base, quote}]
+	return rate, ok
+}
+
+func (r *Rates) currencies() []string {
+	currencies := map[string]bool{}
+	for p := range r.rates {
+		currencies[p.base] = true
+		currencies[p.quote] = true
+	}
+	return slices.Sorted(maps.Keys(currencies))
+}
+
+// Report returns copies of the stocks with their targets in the currency,
+// the stocks already in it are returned as they are. The stocks without a
+// rate are kept in their own currency and their currencies returned, a
+// target that doesn't fit once converted fails the report.
+func (r *Rates) Report(stocks []*models.FormattedStock, currency string) ([]*models.FormattedStock, []string, error) {
+	reported := make([]*models.FormattedStock, len(stocks))
+	missing := map[string]bool{}
+
+	for i, stock := range stocks {
+		if stock.Currency == "" || stock.Currency == currency {
+			reported[i] = stock
+			continue
+		}
+
+		rate, err := r.rate(stock.Currency, currency)
+		if errors.Is(err, ErrNoRate) {
+			missing[stock.Currency] = true
+			reported[i] = stock
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		converted := *stock
+		for _, target := range []*json.Number{&converted.TargetFrom, &converted.TargetTo} {
+			if *target, err = convertTarget(*target, rate); err != nil {
+				return nil, nil, fmt.Errorf("%s target %s %s to %s: %w", stock.Ticker, *target, stock.Currency, currency, err)
+			}
+		}
+		converted.Currency = currency
+		reported[i] = &converted
+	}
+
+	return reported, slices.Sorted(maps.Keys(missing)), nil
+}
+
+func convertTarget(target json.Number, rate Decimal) (json.Number, error) {
+	amount, err := ParseDecimal(target.String())
+	if err != nil {
+		return target, err
+	}
+
+	amount, err = amount.Mul(rate)
+	if err != nil {
+		return target, err
+	}
+
+	return json.Number(amount.String()), nil
+}
+
+// Targets returns the targets of the stock as floats, for the scores that
+// don't need them exact. A target that isn't a number is read as 0.
+func Targets(stock *models.FormattedStock) (from, to float64) {
+	from, _ = stock.TargetFrom.Float64()
+	to, _ = stock.TargetTo.Float64()
+	return from, to
+}
+
+// ReadRates reads a CSV of rates with a base,quote,rate,as_of header, such as
+// "EUR,USD,1.0842,2024-03-05" for the dollars a euro was worth that day.
+func ReadRates(r io.Reader) ([]*models.FXRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(ratesHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRate, err)
+	}
+	if !slices.Equal(header, ratesHeader) {
+		return nil, fmt.Errorf("%w: header %v must be %v", ErrInvalidRate, header, ratesHeader)
+	}
+
+	var rates []*models.FXRate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRate, err)
+		}
+
+		rate, err := parseRate(record)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+func parseRate(record []string) (*models.FXRate, error) {
+	base, quote := strings.ToUpper(record[0]), strings.ToUpper(record[1])
+	for _, code := range []string{base, quote} {
+		if !Valid(code) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+		}
+	}
+	if base == quote {
+		return nil, fmt.Errorf("%w: %s is quoted against itself", ErrInvalidRate, base)
+	}
+
+	value, err := ParseDecimal(record[2])
+	if err != nil || value <= 0 {
+		return nil, fmt.Errorf("%w: %s/%s %q must be a positive number", ErrInvalidRate, base, quote, record[2])
+	}
+
+	asOf, err := time.Parse(time.DateOnly, record[3])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s/%s date %q: %w", ErrInvalidRate, base, quote, record[3], err)
+	}
+
+	return &models.FXRate{Base: base, Quote: quote, Rate: value.String(), AsOf: asOf}, nil
+}
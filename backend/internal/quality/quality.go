@@ -0,0 +1,289 @@
+// Package quality checks the formatted stocks of a sync against data-quality
+// rules, such as targets far off the history of their ticker, and reports the
+// issues found in every run.
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/money"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+type Severity string
+
+const (
+	// SeverityReject drops the stock from the sync.
+	SeverityReject Severity = "reject"
+	// SeverityWarn keeps the stock and logs the issue.
+	SeverityWarn Severity = "warn"
+	// SeverityFlag keeps the stock and only lists the issue in the report.
+	SeverityFlag Severity = "flag"
+	// SeverityOff disables the rule.
+	SeverityOff Severity = "off"
+)
+
+const (
+	// RuleTargetChange catches targets too far apart to be a revision, such
+	// as a target_to of 0.01 for a $300 target_from.
+	RuleTargetChange = "target_change"
+	// RuleFutureTime catches ratings timestamped in the future.
+	RuleFutureTime = "future_time"
+	// RuleDuplicate catches the rows of a feed repeating the ticker, source,
+	// brokerage and time of an earlier one, the first one is kept.
+	RuleDuplicate = "duplicate"
+	// RuleOutlier catches the targets far off the history of their ticker.
+	RuleOutlier = "outlier"
+
+	// futureTolerance allows for the clock skew of the feeds.
+	futureTolerance = time.Hour
+
+	// minDeviation is the smallest spread of the log targets of a ticker the
+	// outliers are measured against, so a ticker whose target never moved
+	// doesn't flag its first revision.
+	minDeviation = 0.05
+
+	// madScale makes the median absolute deviation comparable to a standard
+	// deviation for the robust z-score.
+	madScale = 0.6745
+)
+
+var (
+	ErrUnknownRule     = errors.New("unknown quality rule")
+	ErrInvalidSeverity = errors.New("invalid quality severity")
+	ErrInvalidRules    = errors.New("invalid quality rules")
+
+	severityOrder = []Severity{SeverityOff, SeverityFlag, SeverityWarn, SeverityReject}
+)
+
+// Rules configures the checks. The zero values of the thresholds fall back to
+// the ones of DefaultRules.
+type Rules struct {
+	// Severities are keyed by rule.
+	Severities map[string]Severity
+	// MaxTargetRatio is the largest ratio between target_from and target_to,
+	// either way.
+	MaxTargetRatio float64
+	// OutlierThreshold is the robust z-score of the log target beyond which a
+	// target is an outlier of its ticker's history.
+	OutlierThreshold float64
+	// MinHistory is how many targets a ticker needs in its history for its
+	// outliers to be checked.
+	MinHistory int
+	// HistoryLookback is how far back the history of the tickers is read.
+	HistoryLookback time.Duration
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		Severities: map[string]Severity{
+			RuleTargetChange: SeverityReject,
+			RuleFutureTime:   SeverityReject,
+			RuleDuplicate:    SeverityReject,
+			RuleOutlier:      SeverityFlag,
+		},
+		MaxTargetRatio:   10,
+		OutlierThreshold: 3.5,
+		MinHistory:       5,
+		HistoryLookback:  180 * 24 * time.Hour,
+	}
+}
+
+// Override returns the rules with the severities of the named rules replaced.
+func (r Rules) Override(severities map[string]string) (Rules, error) {
+	r.Severities = maps.Clone(r.withDefaults().Severities)
+
+	var errs []error
+	for _, rule := range slices.Sorted(maps.Keys(severities)) {
+		if _, ok := DefaultRules().Severities[rule]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownRule, rule))
+			continue
+		}
+
+		severity := Severity(severities[rule])
+		if !slices.Contains(severityOrder, severity) {
+			errs = append(errs, fmt.Errorf("%w: %s %q must be one of %v", ErrInvalidSeverity, rule, severity,
+				severityOrder))
+			continue
+		}
+		r.Severities[rule] = severity
+	}
+
+	return r, errors.Join(errs...)
+}
+
+func (r Rules) withDefaults() Rules {
+	defaults := DefaultRules()
+	if r.Severities == nil {
+		r.Severities = defaults.Severities
+	}
+	if r.MaxTargetRatio == 0 {
+		r.MaxTargetRatio = defaults.MaxTargetRatio
+	}
+	if r.OutlierThreshold == 0 {
+		r.OutlierThreshold = defaults.OutlierThreshold
+	}
+	if r.MinHistory == 0 {
+		r.MinHistory = defaults.MinHistory
+	}
+	if r.HistoryLookback == 0 {
+		r.HistoryLookback = defaults.HistoryLookback
+	}
+	return r
+}
+
+// Issue is a rule broken by a stock.
+type Issue struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// Rejected reports whether any of the issues rejects the stock.
+func Rejected(issues []Issue) bool {
+	return slices.ContainsFunc(issues, func(issue Issue) bool { return issue.Severity == SeverityReject })
+}
+
+type entry struct {
+	ticker    string
+	source    string
+	brokerage string
+	time      time.Time
+}
+
+type spread struct {
+	median    float64
+	deviation float64
+}
+
+// Checker checks the stocks of one run, it is safe for concurrent use.
+type Checker struct {
+	rules Rules
+	now   func() time.Time
+	// spreads of the log targets, keyed by ticker then currency
+	spreads map[string]map[string]spread
+
+	mu   sync.Mutex
+	seen map[entry]bool
+}
+
+// NewChecker checks the stocks with the rules, comparing their targets with
+// the history of their ticker in the same currency.
+func NewChecker(rules Rules, history []*models.FormattedStock) (*Checker, error) {
+	rules = rules.withDefaults()
+	if rules.MaxTargetRatio < 1 {
+		return nil, fmt.Errorf("%w: the max target ratio %v must be at least 1", ErrInvalidRules, rules.MaxTargetRatio)
+	}
+	if rules.OutlierThreshold < 0 {
+		return nil, fmt.Errorf("%w: the outlier threshold %v must not be negative", ErrInvalidRules,
+			rules.OutlierThreshold)
+	}
+
+	return &Checker{
+		rules:   rules,
+		now:     time.Now,
+		spreads: spreads(history, rules.MinHistory),
+		seen:    map[entry]bool{},
+	}, nil
+}
+
+func spreads(history []*models.FormattedStock, minHistory int) map[string]map[string]spread {
+	targets := map[string]map[string][]float64{}
+	for _, stock := range history {
+		_, to := money.Targets(stock)
+		if to <= 0 {
+			continue
+		}
+		if targets[stock.Ticker] == nil {
+			targets[stock.Ticker] = map[string][]float64{}
+		}
+		targets[stock.Ticker][stock.Currency] = append(targets[stock.Ticker][stock.Currency], math.Log(to))
+	}
+
+	spreads := map[string]map[string]spread{}
+	for ticker, currencies := range targets {
+		for currency, values := range currencies {
+			if len(values) < minHistory {
+				continue
+			}
+
+			m := median(values)
+			deviations := make([]float64, len(values))
+			for i, value := range values {
+				deviations[i] = math.Abs(value - m)
+			}
+
+			if spreads[ticker] == nil {
+				spreads[ticker] = map[string]spread{}
+			}
+			spreads[ticker][currency] = spread{median: m, deviation: max(median(deviations), minDeviation)}
+		}
+	}
+
+	return spreads
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Check returns the issues of the stock, including repeating an earlier stock
+// of the run.
+func (c *Checker) Check(stock *models.FormattedStock) []Issue {
+	var issues []Issue
+	add := func(rule, format string, args ...any) {
+		if severity := c.rules.Severities[rule]; severity != SeverityOff && severity != "" {
+			issues = append(issues, Issue{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	from, to := money.Targets(stock)
+	if from > 0 && to > 0 {
+		if ratio := max(to/from, from/to); ratio > c.rules.MaxTargetRatio {
+			add(RuleTargetChange, "target_to %v is %.1fx off target_from %v", stock.TargetTo, ratio, stock.TargetFrom)
+		}
+	}
+
+	if now := c.now(); stock.Time.After(now.Add(futureTolerance)) {
+		add(RuleFutureTime, "time %s is %s in the future", stock.Time.Format(time.RFC3339),
+			stock.Time.Sub(now).Round(time.Minute))
+	}
+
+	if s, ok := c.spreads[stock.Ticker][stock.Currency]; ok && to > 0 {
+		if score := madScale * (math.Log(to) - s.median) / s.deviation; math.Abs(score) > c.rules.OutlierThreshold {
+			add(RuleOutlier, "target_to %v is %.1f deviations off the median target %.2f of the ticker", stock.TargetTo,
+				score, math.Exp(s.median))
+		}
+	}
+
+	// a rejected stock doesn't keep a later one of the same rating out
+	if !Rejected(issues) && c.duplicate(stock) {
+		add(RuleDuplicate, "repeats the ticker, source, brokerage and time of an earlier rating")
+	}
+
+	return issues
+}
+
+func (c *Checker) duplicate(stock *models.FormattedStock) bool {
+	key := entry{ticker: stock.Ticker, source: stock.Source, brokerage: stock.Brokerage, time: stock.Time.UTC()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.seen[key] {
+		return true
+	}
+	c.seen[key] = true
+	return false
+}
@@ -0,0 +1,212 @@
+package quality
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+var now = time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+
+func newTestChecker(t *testing.T, rules Rules, history []*models.FormattedStock) *Checker {
+	t.Helper()
+
+	checker, err := NewChecker(rules, history)
+	if err != nil {
+		t.Fatalf("NewChecker returned unexpected error: %v", err)
+	}
+	checker.now = func() time.Time { return now }
+
+	return checker
+}
+
+func stock(ticker string, from, to float64) *models.FormattedStock {
+	return &models.FormattedStock{
+		Ticker:     ticker,
+		TargetFrom: json.Number(strconv.FormatFloat(from, 'f', -1, 64)),
+		TargetTo:   json.Number(strconv.FormatFloat(to, 'f', -1, 64)),
+		Brokerage:  "Barclays",
+		Currency:   "USD",
+		Time:       now.Add(-time.Hour),
+	}
+}
+
+func rules(issues []Issue) []string {
+	var names []string
+	for _, issue := range issues {
+		names = append(names, issue.Rule)
+	}
+	return names
+}
+
+func TestCheck(t *testing.T) {
+	future := stock("AAPL", 150, 160)
+	future.Time = now.Add(48 * time.Hour)
+
+	skewed := stock("AAPL", 150, 160)
+	skewed.Time = now.Add(30 * time.Minute)
+
+	tests := []struct {
+		name  string
+		stock *models.FormattedStock
+		want  []string
+	}{
+		{"Valid", stock("AAPL", 150, 180), nil},
+		{"Target collapse", stock("AAPL", 300, 0.01), []string{RuleTargetChange}},
+		{"Thousand percent change", stock("AAPL", 10, 110), []string{RuleTargetChange}},
+		{"Initiated without a previous target", stock("AAPL", 0, 180), nil},
+		{"Future time", future, []string{RuleFutureTime}},
+		{"Clock skew", skewed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules(newTestChecker(t, DefaultRules(), nil).Check(tt.stock))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicate(t *testing.T) {
+	checker := newTestChecker(t, DefaultRules(), nil)
+
+	if issues := checker.Check(stock("AAPL", 150, 180)); len(issues) > 0 {
+		t.Fatalf("Expected the first rating to pass, got %v", issues)
+	}
+
+	issues := checker.Check(stock("AAPL", 150, 185))
+	if !slices.Equal(rules(issues), []string{RuleDuplicate}) || !Rejected(issues) {
+		t.Errorf("Expected the repeated rating to be rejected as a duplicate, got %v", issues)
+	}
+
+	other := stock("AAPL", 150, 180)
+	other.Brokerage = "UBS Group"
+	if issues := checker.Check(other); len(issues) > 0 {
+		t.Errorf("Expected the rating of another brokerage to pass, got %v", issues)
+	}
+
+	fromFile := stock("AAPL", 150, 180)
+	fromFile.Source = "file"
+	if issues := checker.Check(fromFile); len(issues) > 0 {
+		t.Errorf("Expected the same rating of another source to pass, got %v", issues)
+	}
+
+	// a rejected rating doesn't keep the next one of the same key out
+	if issues := checker.Check(stock("MSFT", 400, 0.5)); !Rejected(issues) {
+		t.Fatalf("Expected the collapsed target to be rejected, got %v", issues)
+	}
+	if issues := checker.Check(stock("MSFT", 400, 420)); len(issues) > 0 {
+		t.Errorf("Expected the corrected rating to pass, got %v", issues)
+	}
+}
+
+func TestCheckOutlier(t *testing.T) {
+	var history []*models.FormattedStock
+	for _, target := range []float64{290, 295, 300, 305, 310, 300} {
+		history = append(history, stock("AAPL", target, target))
+	}
+	// too few targets to be checked
+	history = append(history, stock("MSFT", 400, 400), stock("MSFT", 410, 410))
+
+	euros := stock("AAPL", 50, 60)
+	euros.Currency = "EUR"
+
+	tests := []struct {
+		name  string
+		stock *models.FormattedStock
+		want  []string
+	}{
+		{"Within the history", stock("AAPL", 300, 330), nil},
+		{"Far off the history", stock("AAPL", 50, 60), []string{RuleOutlier}},
+		{"Another currency isn't compared", euros, nil},
+		{"Short history", stock("MSFT", 50, 60), nil},
+		{"No history", stock("NVDA", 50, 60), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := newTestChecker(t, DefaultRules(), history).Check(tt.stock)
+			if got := rules(issues); !slices.Equal(got, tt.want) {
+				t.Errorf("Check = %v, want %v", got, tt.want)
+			}
+			if Rejected(issues) {
+				t.Errorf("Expected the outliers to be flagged only, got %v", issues)
+			}
+		})
+	}
+}
+
+func TestOverride(t *testing.T) {
+	overridden, err := DefaultRules().Override(map[string]string{RuleOutlier: "reject", RuleDuplicate: "off"})
+	if err != nil {
+		t.Fatalf("Override returned unexpected error: %v", err)
+	}
+
+	if overridden.Severities[RuleOutlier] != SeverityReject || overridden.Severities[RuleDuplicate] != SeverityOff {
+		t.Errorf("Expected the severities to be replaced, got %v", overridden.Severities)
+	}
+	if DefaultRules().Severities[RuleOutlier] != SeverityFlag {
+		t.Errorf("Expected the default rules to be left as they are")
+	}
+
+	checker := newTestChecker(t, overridden, nil)
+	checker.Check(stock("AAPL", 150, 180))
+	if issues := checker.Check(stock("AAPL", 150, 180)); len(issues) > 0 {
+		t.Errorf("Expected the disabled duplicate rule to pass the repeated rating, got %v", issues)
+	}
+
+	_, err = DefaultRules().Override(map[string]string{"stale": "warn", RuleOutlier: "ignore"})
+	if !errors.Is(err, ErrUnknownRule) || !errors.Is(err, ErrInvalidSeverity) {
+		t.Errorf("Expected %v and %v, got %v", ErrUnknownRule, ErrInvalidSeverity, err)
+	}
+}
+
+func TestNewCheckerInvalidRules(t *testing.T) {
+	_, err := NewChecker(Rules{MaxTargetRatio: 0.5}, nil)
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Errorf("Expected %v, got %v", ErrInvalidRules, err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	rules := DefaultRules()
+	rules.Severities[RuleFutureTime] = SeverityWarn
+	checker := newTestChecker(t, rules, nil)
+
+	future := stock("TSLA", 200, 210)
+	future.Time = now.Add(48 * time.Hour)
+
+	report := NewReport()
+	for _, s := range []*models.FormattedStock{
+		stock("MSFT", 400, 0.5),
+		stock("AAPL", 300, 0.01),
+		stock("AAPL", 150, 180),
+		future,
+	} {
+		report.Add(s, checker.Check(s))
+	}
+
+	summary := report.Summary()
+	if summary.Checked != 4 || summary.Rejected != 2 || summary.Warned != 1 || summary.Flagged != 0 {
+		t.Errorf("Unexpected counts: %+v", summary)
+	}
+
+	change := summary.Rules[RuleTargetChange]
+	if change == nil || change.Count != 2 || change.Severity != string(SeverityReject) {
+		t.Fatalf("Expected 2 rejected target changes, got %+v", change)
+	}
+	if len(change.Samples) != 2 || change.Samples[0].Ticker != "AAPL" || change.Samples[1].Ticker != "MSFT" {
+		t.Errorf("Expected the samples in the order of their ticker, got %+v", change.Samples)
+	}
+
+	if issues := report.Issues(); issues[RuleFutureTime] != 1 || issues[RuleTargetChange] != 2 {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+}
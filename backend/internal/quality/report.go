@@ -0,0 +1,86 @@
+package quality
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// maxSamples is how many issues of every rule the report keeps.
+const maxSamples = 20
+
+// Report collects the issues of the stocks of a run. It isn't safe for
+// concurrent use.
+type Report struct {
+	report models.QualityReport
+}
+
+func NewReport() *Report {
+	return &Report{report: models.QualityReport{Rules: map[string]*models.QualityRuleReport{}}}
+}
+
+// Add counts the stock checked and its issues.
+func (r *Report) Add(stock *models.FormattedStock, issues []Issue) {
+	r.report.Checked++
+
+	worst := SeverityOff
+	for _, issue := range issues {
+		if slices.Index(severityOrder, issue.Severity) > slices.Index(severityOrder, worst) {
+			worst = issue.Severity
+		}
+
+		rule, ok := r.report.Rules[issue.Rule]
+		if !ok {
+			rule = &models.QualityRuleReport{Severity: string(issue.Severity)}
+			r.report.Rules[issue.Rule] = rule
+		}
+
+		rule.Count++
+		if len(rule.Samples) < maxSamples {
+			rule.Samples = append(rule.Samples, &models.QualityIssue{
+				Ticker:    stock.Ticker,
+				Brokerage: stock.Brokerage,
+				Time:      stock.Time,
+				Message:   issue.Message,
+			})
+		}
+	}
+
+	switch worst {
+	case SeverityReject:
+		r.report.Rejected++
+	case SeverityWarn:
+		r.report.Warned++
+	case SeverityFlag:
+		r.report.Flagged++
+	}
+}
+
+// Summary returns the report with the samples of every rule in the order of
+// their ticker, since the stocks of a run are checked concurrently.
+func (r *Report) Summary() *models.QualityReport {
+	summary := r.report
+	summary.Rules = make(map[string]*models.QualityRuleReport, len(r.report.Rules))
+
+	for name, rule := range r.report.Rules {
+		sorted := *rule
+		sorted.Samples = slices.SortedFunc(slices.Values(rule.Samples), func(a, b *models.QualityIssue) int {
+			return cmp.Or(strings.Compare(a.Ticker, b.Ticker), strings.Compare(a.Brokerage, b.Brokerage),
+				a.Time.Compare(b.Time))
+		})
+		summary.Rules[name] = &sorted
+	}
+
+	return &summary
+}
+
+// Issues counts the issues by rule, for the logs.
+func (r *Report) Issues() map[string]int {
+	counts := make(map[string]int, len(r.report.Rules))
+	for name, rule := range r.report.Rules {
+		counts[name] = rule.Count
+	}
+	return counts
+}
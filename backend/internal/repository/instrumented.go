@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// instrumentedRepository observes the latency of every method of the wrapped
+// repository in the db_query_duration_seconds metric.
+type instrumentedRepository struct {
+	next StockRepository
+}
+
+func Instrumented(repo StockRepository) StockRepository {
+	if _, ok := repo.(*instrumentedRepository); ok {
+		return repo
+	}
+	return &instrumentedRepository{next: repo}
+}
+
+func (r *instrumentedRepository) BulkInsertStocks(ctx context.Context, stocks []*models.FormattedStock, tableName string) (err error) {
+	defer observe("BulkInsertStocks", time.Now(), &err)
+	return r.next.BulkInsertStocks(ctx, stocks, tableName)
+}
+
+func (r *instrumentedRepository) BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable string, opts models.SyncOptions) (_ *models.SyncPlan, err error) {
+	defer observe("BulkUpdateStocks", time.Now(), &err)
+	return r.next.BulkUpdateStocks(ctx, stocks, originalTable, opts)
+}
+
+func (r *instrumentedRepository) PrepareStagingTable(ctx context.Context, originalTable string) (_ string, err error) {
+	defer observe("PrepareStagingTable", time.Now(), &err)
+	return r.next.PrepareStagingTable(ctx, originalTable)
+}
+
+func (r *instrumentedRepository) InsertStocksBatch(ctx context.Context, stocks []*models.FormattedStock, tableName string) (err error) {
+	defer observe("InsertStocksBatch", time.Now(), &err)
+	return r.next.InsertStocksBatch(ctx, stocks, tableName)
+}
+
+func (r *instrumentedRepository) ReconcileStocks(ctx context.Context, originalTable, tempTable string, opts models.SyncOptions) (_ *models.SyncPlan, err error) {
+	defer observe("ReconcileStocks", time.Now(), &err)
+	return r.next.ReconcileStocks(ctx, originalTable, tempTable, opts)
+}
+
+func (r *instrumentedRepository) DropTable(ctx context.Context, tableName string) (err error) {
+	defer observe("DropTable", time.Now(), &err)
+	return r.next.DropTable(ctx, tableName)
+}
+
+func (r *instrumentedRepository) GetStocks(ctx context.Context, tableName string, asOf time.Time) (_ []*models.FormattedStock, err error) {
+	defer observe("GetStocks", time.Now(), &err)
+	return r.next.GetStocks(ctx, tableName, asOf)
+}
+
+func (r *instrumentedRepository) GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) (_ []*models.FormattedStock, err error) {
+	defer observe("GetStocksHistory", time.Now(), &err)
+	return r.next.GetStocksHistory(ctx, tableName, since, asOf)
+}
+
+func (r *instrumentedRepository) GetTableLength(ctx context.Context, tableName string) (_ int, err error) {
+	defer observe("GetTableLength", time.Now(), &err)
+	return r.next.GetTableLength(ctx, tableName)
+}
+
+func (r *instrumentedRepository) CountStocks(ctx context.Context, tableName string, includeDeleted bool, asOf time.Time) (_ int, err error) {
+	defer observe("CountStocks", time.Now(), &err)
+	return r.next.CountStocks(ctx, tableName, includeDeleted, asOf)
+}
+
+func (r *instrumentedRepository) GetDailyBars(ctx context.Context, tableName string, from, to time.Time) (_ []*models.DailyBar, err error) {
+	defer observe("GetDailyBars", time.Now(), &err)
+	return r.next.GetDailyBars(ctx, tableName, from, to)
+}
+
+func (r *instrumentedRepository) GetTickerDailyBars(ctx context.Context, tableName, ticker string, from, to time.Time) (_ []*models.DailyBar, err error) {
+	defer observe("GetTickerDailyBars", time.Now(), &err)
+	return r.next.GetTickerDailyBars(ctx, tableName, ticker, from, to)
+}
+
+func (r *instrumentedRepository) UpsertDailyBars(ctx context.Context, bars []*models.DailyBar, tableName string) (err error) {
+	defer observe("UpsertDailyBars", time.Now(), &err)
+	return r.next.UpsertDailyBars(ctx, bars, tableName)
+}
+
+func (r *instrumentedRepository) CreateBarsTable(ctx context.Context, tableName string) (err error) {
+	defer observe("CreateBarsTable", time.Now(), &err)
+	return r.next.CreateBarsTable(ctx, tableName)
+}
+
+func (r *instrumentedRepository) GetStocksFiltered(ctx context.Context, tableName string, filter models.StockFilter) (_ []*models.FormattedStock, err error) {
+	defer observe("GetStocksFiltered", time.Now(), &err)
+	return r.next.GetStocksFiltered(ctx, tableName, filter)
+}
+
+func (r *instrumentedRepository) SearchStocks(ctx context.Context, tableName, query string, limit int) (_ []models.SearchSuggestion, err error) {
+	defer observe("SearchStocks", time.Now(), &err)
+	return r.next.SearchStocks(ctx, tableName, query, limit)
+}
+
+func (r *instrumentedRepository) LatestSyncRun(ctx context.Context, tableName string) (_ *models.SyncRun, err error) {
+	defer observe("LatestSyncRun", time.Now(), &err)
+	return r.next.LatestSyncRun(ctx, tableName)
+}
+
+func (r *instrumentedRepository) SaveSnapshots(ctx context.Context, tableName string, snapshots []*models.Snapshot) (err error) {
+	defer observe("SaveSnapshots", time.Now(), &err)
+	return r.next.SaveSnapshots(ctx, tableName, snapshots)
+}
+
+func (r *instrumentedRepository) GetSnapshot(ctx context.Context, tableName, kind string, asOf time.Time) (_ *models.Snapshot, err error) {
+	defer observe("GetSnapshot", time.Now(), &err)
+	return r.next.GetSnapshot(ctx, tableName, kind, asOf)
+}
+
+func (r *instrumentedRepository) SaveUnmappedLabels(ctx context.Context, labels []*models.UnmappedLabel) (err error) {
+	defer observe("SaveUnmappedLabels", time.Now(), &err)
+	return r.next.SaveUnmappedLabels(ctx, labels)
+}
+
+func (r *instrumentedRepository) GetFXRates(ctx context.Context, asOf time.Time) (_ []*models.FXRate, err error) {
+	defer observe("GetFXRates", time.Now(), &err)
+	return r.next.GetFXRates(ctx, asOf)
+}
+
+func (r *instrumentedRepository) UpsertFXRates(ctx context.Context, rates []*models.FXRate) (err error) {
+	defer observe("UpsertFXRates", time.Now(), &err)
+	return r.next.UpsertFXRates(ctx, rates)
+}
+
+func (r *instrumentedRepository) Close() error {
+	return r.next.Close()
+}
+
+func observe(method string, start time.Time, err *error) {
+	telemetry.ObserveQuery(method, *err, time.Since(start))
+}
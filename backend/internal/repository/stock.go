@@ -2,45 +2,33 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
 type StockRepository interface {
 	BulkInsertStocks(ctx context.Context, stocks []*models.FormattedStock, tableName string) error
-	BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable, tempTable string) error
-	GetStocks(ctx context.Context, tableName string) ([]*models.FormattedStock, error)
+	BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable string, opts models.SyncOptions) (*models.SyncPlan, error)
+	PrepareStagingTable(ctx context.Context, originalTable string) (string, error)
+	InsertStocksBatch(ctx context.Context, stocks []*models.FormattedStock, tableName string) error
+	ReconcileStocks(ctx context.Context, originalTable, tempTable string, opts models.SyncOptions) (*models.SyncPlan, error)
+	DropTable(ctx context.Context, tableName string) error
+	GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error)
+	GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error)
 	GetTableLength(ctx context.Context, tableName string) (int, error)
-	GetStocksFiltered(ctx context.Context, field, order, search, tableName string, page, limit int) ([]*models.FormattedStock, error)
+	CountStocks(ctx context.Context, tableName string, includeDeleted bool, asOf time.Time) (int, error)
+	GetDailyBars(ctx context.Context, tableName string, from, to time.Time) ([]*models.DailyBar, error)
+	GetTickerDailyBars(ctx context.Context, tableName, ticker string, from, to time.Time) ([]*models.DailyBar, error)
+	UpsertDailyBars(ctx context.Context, bars []*models.DailyBar, tableName string) error
+	CreateBarsTable(ctx context.Context, tableName string) error
+	GetStocksFiltered(ctx context.Context, tableName string, filter models.StockFilter) ([]*models.FormattedStock, error)
+	SearchStocks(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error)
+	LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error)
+	SaveSnapshots(ctx context.Context, tableName string, snapshots []*models.Snapshot) error
+	GetSnapshot(ctx context.Context, tableName, kind string, asOf time.Time) (*models.Snapshot, error)
+	SaveUnmappedLabels(ctx context.Context, labels []*models.UnmappedLabel) error
+	GetFXRates(ctx context.Context, asOf time.Time) ([]*models.FXRate, error)
+	UpsertFXRates(ctx context.Context, rates []*models.FXRate) error
 	Close() error
 }
-
-var stockRepoImpl StockRepository
-
-func SetStockRepository(repo StockRepository) {
-	stockRepoImpl = repo
-}
-
-func BulkInsertStocks(ctx context.Context, stocks []*models.FormattedStock, tableName string) error {
-	return stockRepoImpl.BulkInsertStocks(ctx, stocks, tableName)
-}
-
-func BulkUpdateStocks(ctx context.Context, stocks []*models.FormattedStock, originalTable, tempTable string) error {
-	return stockRepoImpl.BulkUpdateStocks(ctx, stocks, originalTable, tempTable)
-}
-
-func GetStocks(ctx context.Context, tableName string) ([]*models.FormattedStock, error) {
-	return stockRepoImpl.GetStocks(ctx, tableName)
-}
-
-func GetTableLength(ctx context.Context, tableName string) (int, error) {
-	return stockRepoImpl.GetTableLength(ctx, tableName)
-}
-
-func GetStocksFiltered(ctx context.Context, field, order, search, tableName string, page, limit int) ([]*models.FormattedStock, error) {
-	return stockRepoImpl.GetStocksFiltered(ctx, field, order, search, tableName, page, limit)
-}
-
-func Close() error {
-	return stockRepoImpl.Close()
-}
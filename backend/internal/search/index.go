@@ -0,0 +1,79 @@
+package search
+
+import (
+	"unicode/utf8"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// minTrigramQuery is the length from which a query shares a trigram with the
+// values containing it anywhere.
+const minTrigramQuery = 3
+
+// Index is an inverted index of the trigrams of the tickers, companies and
+// brokerages of a stocks table, for the backends without trigram indexes.
+type Index struct {
+	entries  []models.SearchSuggestion
+	postings map[string][]int
+}
+
+func NewIndex(stocks []*models.FormattedStock) *Index {
+	idx := &Index{postings: map[string][]int{}}
+
+	brokerages := map[string]bool{}
+	for _, stock := range stocks {
+		idx.add(models.SearchSuggestion{Field: FieldTicker, Value: stock.Ticker, Ticker: stock.Ticker})
+		idx.add(models.SearchSuggestion{Field: FieldCompany, Value: stock.Company, Ticker: stock.Ticker})
+
+		// a brokerage rates many stocks but is suggested once
+		if key := normalize(stock.Brokerage); !brokerages[key] {
+			brokerages[key] = true
+			idx.add(models.SearchSuggestion{Field: FieldBrokerage, Value: stock.Brokerage})
+		}
+	}
+
+	return idx
+}
+
+func (idx *Index) add(entry models.SearchSuggestion) {
+	if normalize(entry.Value) == "" {
+		return
+	}
+
+	id := len(idx.entries)
+	idx.entries = append(idx.entries, entry)
+
+	for trigram := range trigrams(entry.Value) {
+		idx.postings[trigram] = append(idx.postings[trigram], id)
+	}
+}
+
+// Search returns the best suggestions for the query among the entries
+// sharing at least a trigram with it, which covers the prefixes and the
+// typos. The shorter queries scan every entry instead.
+func (idx *Index) Search(query string, limit int) []models.SearchSuggestion {
+	// the trigrams of "pl" are padded as the start of a word, they miss the
+	// values containing it within one such as AAPL
+	if utf8.RuneCountInString(normalize(query)) < minTrigramQuery {
+		return rank(idx.entries, query, limit)
+	}
+
+	seen := map[int]bool{}
+
+	var candidates []models.SearchSuggestion
+	for trigram := range trigrams(query) {
+		for _, id := range idx.postings[trigram] {
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, idx.entries[id])
+			}
+		}
+	}
+
+	return rank(candidates, query, limit)
+}
+
+// Len returns the number of indexed values.
+func (idx *Index) Len() int {
+	return len(idx.entries)
+}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+// Tiers of the scores, a better kind of match always ranks first and the
+// similarity only orders the typos.
+const (
+	exactScore      = 1.0
+	prefixScore     = 0.9
+	wordPrefixScore = 0.8
+	substringScore  = 0.6
+	typoScore       = 0.5
+
+	// MinSimilarity is the share of trigrams a value must have in common with
+	// the query to be a typo of it, the default threshold of pg_trgm.
+	MinSimilarity = 0.3
+)
+
+// fieldBonus breaks the ties in favour of the tickers, then the companies.
+var fieldBonus = map[string]float64{
+	FieldTicker:  0.02,
+	FieldCompany: 0.01,
+}
+
+// Score rates how well the value of the field matches the query, from 0 for
+// no match to about 1 for an exact one.
+func Score(field, value, query string) float64 {
+	value, query = normalize(value), normalize(query)
+	if value == "" || query == "" {
+		return 0
+	}
+
+	var score float64
+	switch {
+	case value == query:
+		score = exactScore
+	case strings.HasPrefix(value, query):
+		score = prefixScore
+	case hasWordPrefix(value, query):
+		score = wordPrefixScore
+	case strings.Contains(value, query):
+		score = substringScore
+	default:
+		similarity := Similarity(value, query)
+		if similarity < MinSimilarity {
+			return 0
+		}
+		score = typoScore * similarity
+	}
+
+	return score + fieldBonus[field]
+}
+
+// Similarity is the share of the trigrams of a and b they have in common, the
+// way pg_trgm computes it.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if _, ok := tb[trigram]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// rank scores the candidates against the query and keeps the best ones, the
+// suggestions that don't match at all are dropped.
+func rank(candidates []models.SearchSuggestion, query string, limit int) []models.SearchSuggestion {
+	ranked := make([]models.SearchSuggestion, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate.Score = Score(candidate.Field, candidate.Value, query)
+		if candidate.Score > 0 {
+			ranked = append(ranked, candidate)
+		}
+	}
+
+	slices.SortFunc(ranked, func(a, b models.SearchSuggestion) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(len(a.Value), len(b.Value)),
+			cmp.Compare(a.Value, b.Value),
+			cmp.Compare(a.Ticker, b.Ticker),
+		)
+	})
+
+	return ranked[:min(len(ranked), normalizeLimit(limit))]
+}
+
+// trigrams splits s into words padded with two spaces before and one after,
+// so the first letters of a word make trigrams of their own.
+func trigrams(s string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, word := range words(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+func hasWordPrefix(value, query string) bool {
+	for i := strings.Index(value, query); i >= 0; {
+		if previous, _ := utf8.DecodeLastRuneInString(value[:i]); i == 0 || !isWordRune(previous) {
+			return true
+		}
+
+		next := strings.Index(value[i+1:], query)
+		if next < 0 {
+			return false
+		}
+		i += next + 1
+	}
+	return false
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(normalize(s), func(r rune) bool { return !isWordRune(r) })
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	return min(limit, MaxLimit)
+}
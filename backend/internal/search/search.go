@@ -0,0 +1,158 @@
+// Package search suggests the tickers, companies and brokerages of a dataset
+// matching what is typed in the filter box, tolerating typos and ranking the
+// exact and prefix matches first.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	FieldTicker    = "ticker"
+	FieldCompany   = "company"
+	FieldBrokerage = "brokerage"
+
+	DefaultLimit = 10
+	MaxLimit     = 20
+
+	// DatabaseBackend matches with the trigram indexes of the stocks table,
+	// MemoryBackend with an Index of the stocks built in process.
+	DatabaseBackend = "database"
+	MemoryBackend   = "memory"
+
+	// candidatesPerResult is how many candidates the database returns for
+	// every suggestion, so the ones ranked better here aren't cut.
+	candidatesPerResult = 3
+
+	// staleAfter rebuilds the indexes of the tables without sync runs, whose
+	// changes can't be detected otherwise.
+	staleAfter = time.Minute
+)
+
+var (
+	ErrUnknownBackend = errors.New("unknown search backend")
+)
+
+// Backend returns the suggestions of the query in a stocks table, from the
+// most relevant.
+type Backend interface {
+	Search(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error)
+}
+
+// Store finds the candidates of a query with the indexes of the database.
+type Store interface {
+	SearchStocks(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error)
+}
+
+// StockStore reads the stocks the in-process indexes are built from.
+type StockStore interface {
+	GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error)
+	LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error)
+}
+
+type Repository interface {
+	Store
+	StockStore
+}
+
+// New returns the backend of the given name, the database one when empty.
+func New(name string, repo Repository) (Backend, error) {
+	switch name {
+	case DatabaseBackend, "":
+		return NewDatabase(repo), nil
+	case MemoryBackend:
+		return NewMemory(repo), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+}
+
+type database struct {
+	store Store
+}
+
+func NewDatabase(store Store) Backend {
+	return &database{store: store}
+}
+
+// Search ranks the candidates of the database the same way as the in-process
+// index, so both backends agree on the order.
+func (d *database) Search(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error) {
+	query = normalize(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	candidates, err := d.store.SearchStocks(ctx, tableName, query, normalizeLimit(limit)*candidatesPerResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return rank(candidates, query, limit), nil
+}
+
+// Memory keeps an Index of every table, rebuilt after every sync run.
+type Memory struct {
+	store StockStore
+
+	mu      sync.Mutex
+	indexes map[string]*memoryIndex
+}
+
+type memoryIndex struct {
+	*Index
+	runID   string
+	builtAt time.Time
+}
+
+func NewMemory(store StockStore) *Memory {
+	return &Memory{store: store, indexes: map[string]*memoryIndex{}}
+}
+
+func (m *Memory) Search(ctx context.Context, tableName, query string, limit int) ([]models.SearchSuggestion, error) {
+	if normalize(query) == "" {
+		return nil, nil
+	}
+
+	idx, err := m.index(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.Search(query, limit), nil
+}
+
+func (m *Memory) index(ctx context.Context, tableName string) (*Index, error) {
+	var runID string
+	run, err := m.store.LatestSyncRun(ctx, tableName)
+	switch {
+	case err == nil:
+		runID = run.ID
+	case !errors.Is(err, db.ErrSyncRunNotFound):
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if idx, ok := m.indexes[tableName]; ok && idx.runID == runID && (runID != "" || time.Since(idx.builtAt) < staleAfter) {
+		return idx.Index, nil
+	}
+
+	stocks, err := m.store.GetStocks(ctx, tableName, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &memoryIndex{Index: NewIndex(stocks), runID: runID, builtAt: time.Now()}
+	m.indexes[tableName] = idx
+
+	return idx.Index, nil
+}
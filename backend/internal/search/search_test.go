@@ -0,0 +1,134 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+var stocks = []*models.FormattedStock{
+	{Ticker: "AAPL", Company: "Apple Inc.", Brokerage: "Morgan Stanley"},
+	{Ticker: "APLE", Company: "Apple Hospitality REIT", Brokerage: "Morgan Stanley"},
+	{Ticker: "MS", Company: "Morgan Stanley", Brokerage: "Goldman Sachs"},
+	{Ticker: "PINE", Company: "Alpine Income Property Trust", Brokerage: "Raymond James"},
+	{Ticker: "GS", Company: "The Goldman Sachs Group", Brokerage: "JPMorgan Chase & Co."},
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		value  string
+		query  string
+		better string
+	}{
+		{"Exact ticker over prefix", FieldTicker, "AAPL", "aapl", "AAPLX"},
+		{"Prefix over word prefix", FieldCompany, "Apple Inc.", "app", "Pineapple Apps"},
+		{"Word prefix over substring", FieldCompany, "The Goldman Sachs Group", "gold", "Marigold Corp"},
+		{"Substring over typo", FieldCompany, "Alpine Income", "pine", "Pone Industries"},
+		{"Ticker over company on ties", FieldTicker, "MS", "ms", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := Score(tt.field, tt.value, tt.query)
+			other := Score(FieldCompany, tt.better, tt.query)
+			if tt.better == "" {
+				other = Score(FieldCompany, tt.value, tt.query)
+			}
+
+			if score <= other {
+				t.Errorf("Score(%q) = %v, want more than %v", tt.value, score, other)
+			}
+		})
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := NewIndex(stocks)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"Prefixes before substrings", "ap", []string{"APLE", "Apple Inc.", "Apple Hospitality REIT", "AAPL"}},
+		{"Short query within a word", "pl", []string{"AAPL", "APLE", "Apple Inc.", "Apple Hospitality REIT"}},
+		{"Company word", "goldman", []string{"Goldman Sachs", "The Goldman Sachs Group"}},
+		{"Typo", "morgn stanley", []string{"Morgan Stanley", "Morgan Stanley"}},
+		{"Brokerage listed once", "raymond", []string{"Raymond James"}},
+		{"No match", "zzz", nil},
+		{"Blank query", "  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := idx.Search(tt.query, 4)
+
+			var got []string
+			for _, suggestion := range suggestions {
+				got = append(got, suggestion.Value)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	stocks []*models.FormattedStock
+	run    *models.SyncRun
+	loads  int
+}
+
+func (s *fakeStore) GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error) {
+	s.loads++
+	return s.stocks, nil
+}
+
+func (s *fakeStore) LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error) {
+	if s.run == nil {
+		return nil, db.ErrSyncRunNotFound
+	}
+	return s.run, nil
+}
+
+func TestMemoryRebuiltAfterSync(t *testing.T) {
+	store := &fakeStore{stocks: stocks[:1], run: &models.SyncRun{ID: "run-1"}}
+	memory := NewMemory(store)
+	ctx := context.Background()
+
+	search := func(query string) int {
+		t.Helper()
+		suggestions, err := memory.Search(ctx, "stocks", query, 10)
+		if err != nil {
+			t.Fatalf("Search returned unexpected error: %v", err)
+		}
+		return len(suggestions)
+	}
+
+	if got := search("goldman"); got != 0 {
+		t.Errorf("got %d suggestions before the sync, want 0", got)
+	}
+
+	store.stocks = stocks
+	if got := search("goldman"); got != 0 || store.loads != 1 {
+		t.Errorf("got %d suggestions after %d loads, want the index of the same run to be reused", got, store.loads)
+	}
+
+	store.run = &models.SyncRun{ID: "run-2"}
+	if got := search("goldman"); got != 2 || store.loads != 2 {
+		t.Errorf("got %d suggestions after %d loads, want the index rebuilt for the new run", got, store.loads)
+	}
+}
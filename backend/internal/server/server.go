@@ -0,0 +1,123 @@
+// Package server runs the API handlers behind a plain net/http server, for
+// local development and deployments outside of Lambda.
+package server
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/CorreaJose13/StockAPI/internal/telemetry"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// PrometheusPath serves the metrics registry of the process.
+	PrometheusPath = "/metrics/prometheus"
+
+	maxBodyBytes = 1 << 20
+)
+
+// Route is an endpoint and its handler, already wrapped with its middlewares.
+type Route struct {
+	Endpoint *spec.Endpoint
+	Handler  spec.Handler
+}
+
+// New returns a server for the routes, plus the Prometheus metrics.
+func New(addr string, routes []Route) *http.Server {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		// the methods are left to the handlers since CORS answers OPTIONS
+		mux.Handle(route.Endpoint.Path, Adapt(route.Handler))
+	}
+
+	mux.Handle(PrometheusPath, promhttp.HandlerFor(telemetry.Registry, promhttp.HandlerOpts{}))
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// Adapt serves a handler written for API Gateway proxy events over net/http.
+func Adapt(handler spec.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := toEvent(r)
+		if err != nil {
+			resp, _ := response.ErrorContext(r.Context(), err)
+			write(w, resp)
+			return
+		}
+
+		resp, err := handler(r.Context(), req)
+		if err != nil {
+			resp, _ = response.ErrorContext(r.Context(), err)
+		}
+
+		write(w, resp)
+	})
+}
+
+func toEvent(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	sourceIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	req := events.APIGatewayProxyRequest{
+		Resource:                        r.URL.Path,
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string(r.Header.Clone()),
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string(r.URL.Query()),
+		Body:                            string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: r.Method,
+			Path:       r.URL.Path,
+			Identity:   events.APIGatewayRequestIdentity{SourceIP: sourceIP},
+		},
+	}
+
+	// like API Gateway, the single value maps keep the last value
+	for key, values := range r.Header {
+		req.Headers[key] = values[len(values)-1]
+	}
+	for key, values := range r.URL.Query() {
+		req.QueryStringParameters[key] = values[len(values)-1]
+	}
+
+	return req, nil
+}
+
+func write(w http.ResponseWriter, resp events.APIGatewayProxyResponse) {
+	for key, value := range resp.Headers {
+		w.Header().Set(key, value)
+	}
+	for key, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err == nil {
+			body = decoded
+		}
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/spec"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestServer(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	echo := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		got = req
+		if req.QueryStringParameters["fail"] != "" {
+			return events.APIGatewayProxyResponse{}, errors.New("boom")
+		}
+		return response.Success(map[string]string{"ok": "true"})
+	}
+
+	srv := httptest.NewServer(New(":0", []Route{{Endpoint: spec.Stocks, Handler: echo}}).Handler)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantQuery  map[string]string
+	}{
+		{
+			name:       "Query and headers reach the handler",
+			path:       "/stocks?page=2&limit=5&limit=10",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ok":"true"}`,
+			wantQuery:  map[string]string{"page": "2", "limit": "10"},
+		},
+		{
+			name:       "Handler errors are typed",
+			path:       "/stocks?fail=1",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `"code":"INTERNAL_ERROR"`,
+		},
+		{
+			name:       "Unknown path",
+			path:       "/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Prometheus metrics",
+			path:       PrometheusPath,
+			wantStatus: http.StatusOK,
+			wantBody:   "go_goroutines",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("X-Api-Key", "key")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", body, tt.wantBody)
+			}
+
+			for key, want := range tt.wantQuery {
+				if got.QueryStringParameters[key] != want {
+					t.Errorf("query %s = %q, want %q", key, got.QueryStringParameters[key], want)
+				}
+			}
+			if tt.wantQuery != nil {
+				if got.Headers["X-Api-Key"] != "key" || got.HTTPMethod != http.MethodGet || got.Path != "/stocks" {
+					t.Errorf("unexpected request %+v", got)
+				}
+			}
+		})
+	}
+}
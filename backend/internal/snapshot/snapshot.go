@@ -0,0 +1,208 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/analysis"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/money"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	KindAnalysis = "analysis"
+	KindSummary  = "summary"
+)
+
+var (
+	ErrUnknownKind = errors.New("unknown snapshot kind")
+
+	// renderers build the response of every snapshot kind, the same way
+	// whether it is stored or computed on demand.
+	renderers = map[string]func(*analysis.Analysis) any{
+		KindAnalysis: func(a *analysis.Analysis) any { return a.Analyze() },
+		KindSummary:  func(a *analysis.Analysis) any { return a.GetSummary() },
+	}
+)
+
+// Store reads the data the snapshots are built from and keeps them.
+type Store interface {
+	GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error)
+	GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error)
+	LatestSyncRun(ctx context.Context, tableName string) (*models.SyncRun, error)
+	SaveSnapshots(ctx context.Context, tableName string, snapshots []*models.Snapshot) error
+	GetSnapshot(ctx context.Context, tableName, kind string, asOf time.Time) (*models.Snapshot, error)
+	GetFXRates(ctx context.Context, asOf time.Time) ([]*models.FXRate, error)
+}
+
+// Service serves and takes the snapshots of the analysis scored with its
+// profile, with the targets in the reporting currency.
+type Service struct {
+	store    Store
+	profile  analysis.Profile
+	currency string
+}
+
+func NewService(store Store, profile analysis.Profile, currency string) *Service {
+	return &Service{
+		store:    store,
+		profile:  profile,
+		currency: currency,
+	}
+}
+
+// Get returns the response of the given kind as of the given instant, the
+// zero value reads the latest one. It is served from the snapshot of the sync
+// run covering that instant, or computed from the stored versions when no
+// snapshot does.
+func (s *Service) Get(ctx context.Context, tableName, kind string, asOf time.Time) (any, error) {
+	snapshot, err := s.store.GetSnapshot(ctx, tableName, kind, asOf)
+	if err == nil {
+		return snapshot.Payload, nil
+	}
+
+	if !errors.Is(err, db.ErrSnapshotNotFound) {
+		return nil, err
+	}
+
+	a, err := s.Load(ctx, tableName, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	return render(a, kind)
+}
+
+// Refresh takes the snapshots of the latest sync run that changed the table
+// unless it already has them, which also repairs a run whose snapshots
+// failed to be saved. The stocks are read from the primary since a replica
+// may not have the run yet.
+func (s *Service) Refresh(ctx context.Context, tableName string) error {
+	ctx = db.ReadPrimary(ctx)
+
+	run, err := s.store.LatestSyncRun(ctx, tableName)
+	if errors.Is(err, db.ErrSyncRunNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.GetSnapshot(ctx, tableName, KindAnalysis, time.Time{})
+	if err == nil || !errors.Is(err, db.ErrSnapshotNotFound) {
+		return err
+	}
+
+	a, err := s.Load(ctx, tableName, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := Build(run.ID, a)
+	if err != nil {
+		return err
+	}
+
+	return s.store.SaveSnapshots(ctx, tableName, snapshots)
+}
+
+// Load reads the stocks and the rating history the analysis of the given
+// instant needs, the zero value reads the latest ones. Their targets are
+// converted to the reporting currency with the rates of that instant, so
+// the changes of cross-listed tickers compare.
+func (s *Service) Load(ctx context.Context, tableName string, asOf time.Time) (*analysis.Analysis, error) {
+	stocks, err := s.store.GetStocks(ctx, tableName, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now()
+	if !asOf.IsZero() {
+		since = asOf
+	}
+
+	history, err := s.store.GetStocksHistory(ctx, tableName, since.Add(-analysis.MomentumLookback), asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	stocks, history, err = s.report(ctx, since, stocks, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return analysis.NewAnalysisWithHistory(stocks, history).WithProfile(s.profile), nil
+}
+
+// report converts the targets to the reporting currency, the stocks in a
+// currency without a rate are kept in their own and logged.
+func (s *Service) report(ctx context.Context, asOf time.Time, stocks, history []*models.FormattedStock) (
+	[]*models.FormattedStock, []*models.FormattedStock, error) {
+	if s.currency == "" {
+		return stocks, history, nil
+	}
+
+	fxRates, err := s.store.GetFXRates(ctx, asOf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rates, err := money.NewRates(fxRates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stocks, missing, err := rates.Report(stocks, s.currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	history, missingHistory, err := rates.Report(history, s.currency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if missing = slices.Compact(slices.Sorted(slices.Values(append(missing, missingHistory...)))); len(missing) > 0 {
+		slog.WarnContext(ctx, "targets without an exchange rate", "currency", s.currency, "missing", missing)
+	}
+
+	return stocks, history, nil
+}
+
+// Build renders every snapshot kind of the analysis for the run.
+func Build(runID string, a *analysis.Analysis) ([]*models.Snapshot, error) {
+	var snapshots []*models.Snapshot
+	for _, kind := range []string{KindAnalysis, KindSummary} {
+		result, err := render(a, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s snapshot: %w", kind, err)
+		}
+
+		snapshots = append(snapshots, &models.Snapshot{
+			RunID:   runID,
+			Kind:    kind,
+			Payload: payload,
+		})
+	}
+
+	return snapshots, nil
+}
+
+func render(a *analysis.Analysis, kind string) (any, error) {
+	renderer, ok := renderers[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	return renderer(a), nil
+}
@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/analysis"
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2024, 3, 5, 19, 35, 0, 0, time.UTC)
+	stocks := []*models.FormattedStock{
+		{Ticker: "AAPL", TargetFrom: "150", TargetTo: "180", Company: "Apple", Action: "target raised by", Brokerage: "Barclays", RatingFrom: "buy", RatingTo: "buy", Time: now},
+		{Ticker: "MSFT", TargetFrom: "400", TargetTo: "380", Company: "Microsoft", Action: "target lowered by", Brokerage: "UBS Group", RatingFrom: "buy", RatingTo: "hold", Time: now.Add(-time.Hour)},
+	}
+
+	a := analysis.NewAnalysis(stocks)
+
+	snapshots, err := Build("run-1", a)
+	if err != nil {
+		t.Fatalf("Build returned unexpected error: %v", err)
+	}
+
+	if len(snapshots) != 2 || snapshots[0].Kind != KindAnalysis || snapshots[1].Kind != KindSummary {
+		t.Fatalf("Expected an analysis and a summary snapshot, got %+v", snapshots)
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.RunID != "run-1" {
+			t.Errorf("Expected the %s snapshot to belong to run-1, got %s", snapshot.Kind, snapshot.RunID)
+		}
+	}
+
+	var stored analysis.StockAnalysisResponse
+	if err := json.Unmarshal(snapshots[0].Payload, &stored); err != nil {
+		t.Fatalf("Expected the analysis snapshot to decode, got %v", err)
+	}
+
+	computed := a.Analyze()
+	if len(stored.TopStocks) != len(computed.TopStocks) || stored.TopStocks[0].Ticker != computed.TopStocks[0].Ticker {
+		t.Errorf("Expected the stored analysis to match the computed one, got %+v", stored.TopStocks)
+	}
+
+	var summary analysis.StockSummary
+	if err := json.Unmarshal(snapshots[1].Payload, &summary); err != nil {
+		t.Fatalf("Expected the summary snapshot to decode, got %v", err)
+	}
+
+	if summary != *a.GetSummary() {
+		t.Errorf("Expected summary %+v, got %+v", *a.GetSummary(), summary)
+	}
+}
+
+func TestRenderUnknownKind(t *testing.T) {
+	_, err := render(analysis.NewAnalysis(nil), "forecast")
+	if !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("Expected %v, got %v", ErrUnknownKind, err)
+	}
+}
+
+// fakeStore serves the stocks and rates from memory, the methods Load doesn't
+// call panic through the nil embedded interface.
+type fakeStore struct {
+	Store
+	stocks []*models.FormattedStock
+	rates  []*models.FXRate
+}
+
+func (s *fakeStore) GetStocks(ctx context.Context, tableName string, asOf time.Time) ([]*models.FormattedStock, error) {
+	return s.stocks, nil
+}
+
+func (s *fakeStore) GetStocksHistory(ctx context.Context, tableName string, since, asOf time.Time) ([]*models.FormattedStock, error) {
+	return s.stocks, nil
+}
+
+func (s *fakeStore) GetFXRates(ctx context.Context, asOf time.Time) ([]*models.FXRate, error) {
+	return s.rates, nil
+}
+
+func TestLoadReportingCurrency(t *testing.T) {
+	store := &fakeStore{
+		stocks: []*models.FormattedStock{
+			{Ticker: "SAP", TargetFrom: "100", TargetTo: "120", Currency: "EUR"},
+			{Ticker: "AAPL", TargetFrom: "150", TargetTo: "180", Currency: "USD"},
+			{Ticker: "7203", TargetFrom: "3000", TargetTo: "3200", Currency: "JPY"},
+		},
+		rates: []*models.FXRate{{Base: "EUR", Quote: "USD", Rate: "1.1"}},
+	}
+
+	a, err := NewService(store, analysis.DefaultProfile(), "USD").Load(context.Background(), "stocks", time.Time{})
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	for _, stocks := range [][]*models.FormattedStock{a.Stocks, a.History} {
+		if got := stocks[0]; got.TargetFrom != "110.00" || got.TargetTo != "132.00" || got.Currency != "USD" {
+			t.Errorf("Expected SAP targets of 110 and 132 USD, got %v and %v %s", got.TargetFrom, got.TargetTo, got.Currency)
+		}
+		if got := stocks[2]; got.Currency != "JPY" || got.TargetTo != "3200" {
+			t.Errorf("Expected the targets without a rate to be kept in their currency, got %v %s", got.TargetTo, got.Currency)
+		}
+	}
+
+	if store.stocks[0].Currency != "EUR" {
+		t.Errorf("Expected the stored stocks to be left as they are, SAP is now in %s", store.stocks[0].Currency)
+	}
+}
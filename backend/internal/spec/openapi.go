@@ -0,0 +1,291 @@
+package spec
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/CorreaJose13/StockAPI/internal/apierror"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	apiTitle       = "StockAPI"
+	apiVersion     = "1.0.0"
+
+	schemaRefPrefix = "#/components/schemas/"
+
+	apiKeySecurity = "apiKey"
+	bearerSecurity = "bearer"
+)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	numberType = reflect.TypeOf(json.Number(""))
+
+	// schemaNames renames the types whose Go name would clash in the
+	// generated code.
+	schemaNames = map[reflect.Type]string{
+		reflect.TypeOf(apierror.Error{}): "ErrorResponse",
+	}
+)
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security lists the alternative credentials with the scopes they need.
+	Security []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	// propertyOrder keeps the struct field order for the generated code.
+	propertyOrder []string
+}
+
+// NewDocument builds the OpenAPI document of every endpoint.
+func NewDocument() *Document {
+	builder := &schemaBuilder{schemas: map[string]*Schema{}}
+	errorSchema := builder.schemaFor(reflect.TypeOf(apierror.Error{}))
+
+	doc := &Document{
+		OpenAPI: openAPIVersion,
+		Info:    Info{Title: apiTitle, Version: apiVersion},
+		Paths:   map[string]map[string]*Operation{},
+	}
+
+	for _, endpoint := range Endpoints {
+		operation := &Operation{
+			OperationID: operationID(endpoint),
+			Summary:     endpoint.Summary,
+			Responses: map[string]*Response{
+				"200":     jsonResponse(http.StatusText(http.StatusOK), builder.schemaFor(reflect.TypeOf(endpoint.Response))),
+				"default": jsonResponse("Error", errorSchema),
+			},
+		}
+
+		if endpoint.Scope != "" {
+			operation.Security = []map[string][]string{
+				{apiKeySecurity: {endpoint.Scope}},
+				{bearerSecurity: {endpoint.Scope}},
+			}
+		}
+
+		for _, param := range endpoint.Params {
+			operation.Parameters = append(operation.Parameters, &Parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      paramSchema(param),
+			})
+		}
+
+		doc.Paths[endpoint.Path] = map[string]*Operation{strings.ToLower(endpoint.Method): operation}
+	}
+
+	doc.Components.Schemas = builder.schemas
+	doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+		apiKeySecurity: {
+			Type:        "apiKey",
+			Description: "Issued API key, also accepted as a bearer token.",
+			Name:        auth.APIKeyHeader,
+			In:          "header",
+		},
+		bearerSecurity: {
+			Type:         "http",
+			Description:  "HS256 token whose scope claim lists space separated scopes.",
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+		},
+	}
+
+	return doc
+}
+
+func operationID(endpoint *Endpoint) string {
+	return strings.ToLower(endpoint.Method) + endpoint.Name
+}
+
+func jsonResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func paramSchema(param Param) *Schema {
+	return &Schema{
+		Type:    string(param.Type),
+		Format:  param.Format,
+		Enum:    param.Enum,
+		Pattern: param.Pattern,
+		Minimum: param.Minimum,
+		Maximum: param.Maximum,
+	}
+}
+
+// schemaBuilder derives schemas from the Go types, following their json tags.
+// Structs are registered as components and referenced.
+type schemaBuilder struct {
+	schemas map[string]*Schema
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schemaFor(t.Elem())
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+
+		name := schemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			// registered before walking the fields so recursive types terminate
+			b.schemas[name] = &Schema{}
+			b.schemas[name] = b.objectSchema(t)
+		}
+
+		return &Schema{Ref: schemaRefPrefix + name}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem())}
+	case reflect.String:
+		if t == numberType {
+			// the exact decimals are encoded as JSON numbers
+			return &Schema{Type: "number"}
+		}
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		// any value
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(schema, t)
+	return schema
+}
+
+func (b *schemaBuilder) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+
+		// embedded structs are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				b.addFields(schema, fieldType)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := b.schemaFor(fieldType)
+		omitEmpty := strings.Contains(options, "omitempty")
+
+		if fieldType.Kind() == reflect.Pointer && !omitEmpty && property.Ref == "" {
+			property.Nullable = true
+		}
+
+		schema.Properties[name] = property
+		schema.propertyOrder = append(schema.propertyOrder, name)
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func schemaName(t reflect.Type) string {
+	if name, ok := schemaNames[t]; ok {
+		return name
+	}
+	return t.Name()
+}
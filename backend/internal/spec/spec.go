@@ -0,0 +1,254 @@
+package spec
+
+//go:generate go run ../../cmd/openapi -ts ../../../frontend/src/types/api.ts
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CorreaJose13/StockAPI/internal/analysis"
+	"github.com/CorreaJose13/StockAPI/internal/api/response"
+	"github.com/CorreaJose13/StockAPI/internal/apierror"
+	"github.com/CorreaJose13/StockAPI/internal/auth"
+	"github.com/CorreaJose13/StockAPI/internal/db"
+	"github.com/CorreaJose13/StockAPI/internal/functions"
+	"github.com/CorreaJose13/StockAPI/internal/search"
+	"github.com/CorreaJose13/StockAPI/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type ParamType string
+
+const (
+	String  ParamType = "string"
+	Integer ParamType = "integer"
+	Boolean ParamType = "boolean"
+)
+
+// Param describes a query string parameter of an endpoint.
+type Param struct {
+	Name        string
+	Description string
+	Type        ParamType
+	Format      string
+	Required    bool
+	// Enum values are matched case-insensitively.
+	Enum    []string
+	Pattern string
+	Minimum *int
+	Maximum *int
+	// Check runs the validation the schema can't express, its errors must map
+	// to a client error in apierror.
+	Check func(value string) error
+}
+
+// Endpoint is the single description of a route, used to validate its
+// requests and to generate the OpenAPI document and the frontend types.
+type Endpoint struct {
+	Name    string
+	Method  string
+	Path    string
+	Summary string
+	// Scope is required of the callers, the endpoint is public when empty.
+	Scope  string
+	Params []Param
+	// Response is a value of the type returned on success.
+	Response any
+}
+
+// Handler is the signature shared by the Lambda handlers.
+type Handler func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+var tickerPattern = `^[A-Za-z0-9.\-]{1,10}$`
+
+var asOfParam = Param{
+	Name:        "as_of",
+	Description: "Read the data as it was served at this instant, an RFC 3339 timestamp or a YYYY-MM-DD date read as midnight UTC.",
+	Type:        String,
+	Check: func(value string) error {
+		_, err := functions.ParseAsOf(value)
+		return err
+	},
+}
+
+// datasetParam is matched against the datasets of the config by
+// middleware.Datasets, the schema only checks the format of the name.
+var datasetParam = Param{
+	Name:        "dataset",
+	Description: "Named dataset the data is read from, such as us_equities, defaults to the one of the deployment.",
+	Type:        String,
+	Pattern:     `^[A-Za-z][A-Za-z0-9_]{0,62}$`,
+}
+
+var (
+	Stocks = &Endpoint{
+		Name:    "Stocks",
+		Method:  "GET",
+		Path:    "/stocks",
+		Summary: "Paginated list of the latest rating of every stock",
+		Scope:   auth.ScopeStocks,
+		Params: []Param{
+			// the page and limit out of range are clamped rather than refused
+			{Name: "page", Description: "Page number, starting at 1, smaller pages read the first one.", Type: Integer, Required: true},
+			{Name: "limit", Description: "Stocks per page, from 1 to 100, defaults to 10 when not positive and larger limits read 100.", Type: Integer, Required: true},
+			{Name: "field", Description: "Field the stocks are ordered by, defaults to time.", Type: String, Enum: db.SortableFields()},
+			{Name: "order", Description: "Sort direction, defaults to desc.", Type: String, Enum: []string{"asc", "desc"}},
+			{Name: "search", Description: "Matches the ticker, company or brokerage.", Type: String},
+			{Name: "include_deleted", Description: "Include the stocks that dropped out of the feed.", Type: Boolean},
+			asOfParam,
+			datasetParam,
+		},
+		Response: models.StocksResponse{},
+	}
+
+	Analyze = &Endpoint{
+		Name:     "Analyze",
+		Method:   "GET",
+		Path:     "/analyze",
+		Summary:  "Top stocks ranked by the scoring algorithm",
+		Scope:    auth.ScopeAnalysis,
+		Params:   []Param{asOfParam, datasetParam},
+		Response: analysis.StockAnalysisResponse{},
+	}
+
+	Metrics = &Endpoint{
+		Name:     "Metrics",
+		Method:   "GET",
+		Path:     "/metrics",
+		Summary:  "Summary of the target price changes",
+		Scope:    auth.ScopeMetrics,
+		Params:   []Param{asOfParam, datasetParam},
+		Response: analysis.StockSummary{},
+	}
+
+	Chart = &Endpoint{
+		Name:    "Chart",
+		Method:  "GET",
+		Path:    "/chart",
+		Summary: "Latest daily prices of a stock",
+		Scope:   auth.ScopeChart,
+		Params: []Param{
+			// the prices are shared by every dataset, Chart refuses dataset
+			{Name: "ticker", Description: "Stock ticker.", Type: String, Required: true, Pattern: tickerPattern},
+		},
+		Response: models.ChartResponse{},
+	}
+
+	Search = &Endpoint{
+		Name:    "Search",
+		Method:  "GET",
+		Path:    "/search",
+		Summary: "Tickers, companies and brokerages matching a query, for typeahead",
+		Scope:   auth.ScopeStocks,
+		Params: []Param{
+			{Name: "q", Description: "Typed query, matched by prefix, substring or similarity to tolerate typos.", Type: String, Required: true, Pattern: `^.{1,100}$`},
+			{Name: "limit", Description: "Suggestions returned, defaults to 10.", Type: Integer, Minimum: intPtr(1), Maximum: intPtr(search.MaxLimit)},
+			datasetParam,
+		},
+		Response: models.SearchResponse{},
+	}
+
+	// Token is public, it issues the bearer tokens the frontend calls the other
+	// endpoints with, limited by the address of the caller.
+	Token = &Endpoint{
+		Name:     "Token",
+		Method:   "POST",
+		Path:     "/token",
+		Summary:  "Short-lived bearer token for the browsers",
+		Response: models.TokenResponse{},
+	}
+
+	OpenAPI = &Endpoint{
+		Name:     "OpenAPI",
+		Method:   "GET",
+		Path:     "/openapi.json",
+		Summary:  "OpenAPI document of this API",
+		Response: map[string]any{},
+	}
+
+	Endpoints = []*Endpoint{Stocks, Analyze, Metrics, Chart, Search, Token, OpenAPI}
+)
+
+// Validate checks the query string against the endpoint parameters, empty
+// values are treated as missing.
+func (e *Endpoint) Validate(query map[string]string) error {
+	for _, param := range e.Params {
+		value := strings.TrimSpace(query[param.Name])
+		if value == "" {
+			if param.Required {
+				return InvalidParameter(param.Name, fmt.Sprintf("%s is required", param.Name))
+			}
+			continue
+		}
+
+		if err := param.validate(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validated runs the endpoint validation before the handler.
+func (e *Endpoint) Validated(handler Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if err := e.Validate(req.QueryStringParameters); err != nil {
+			return response.ErrorContext(ctx, err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (p Param) validate(value string) error {
+	switch p.Type {
+	case Integer:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return InvalidParameter(p.Name, fmt.Sprintf("%s must be an integer", p.Name))
+		}
+		if p.Minimum != nil && number < *p.Minimum {
+			return InvalidParameter(p.Name, fmt.Sprintf("%s must be at least %d", p.Name, *p.Minimum))
+		}
+		if p.Maximum != nil && number > *p.Maximum {
+			return InvalidParameter(p.Name, fmt.Sprintf("%s must be at most %d", p.Name, *p.Maximum))
+		}
+	case Boolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return InvalidParameter(p.Name, fmt.Sprintf("%s must be a boolean", p.Name))
+		}
+	}
+
+	if len(p.Enum) > 0 && !slices.ContainsFunc(p.Enum, func(allowed string) bool { return strings.EqualFold(allowed, value) }) {
+		return InvalidParameter(p.Name, fmt.Sprintf("%s must be one of %s", p.Name, strings.Join(p.Enum, ", ")))
+	}
+
+	if p.Pattern != "" {
+		if matched, _ := regexp.MatchString(p.Pattern, value); !matched {
+			return InvalidParameter(p.Name, fmt.Sprintf("%s has an invalid format", p.Name))
+		}
+	}
+
+	if p.Check != nil {
+		if err := p.Check(value); err != nil {
+			return apierror.From(err).WithDetails(map[string]any{"parameter": p.Name})
+		}
+	}
+
+	return nil
+}
+
+// InvalidParameter is the error returned for a query parameter that doesn't
+// match its description.
+func InvalidParameter(name, message string) error {
+	err := fmt.Errorf("%w: %s", functions.ErrInvalidParameter, message)
+	return apierror.From(err).WithDetails(map[string]any{"parameter": name})
+}
+
+func intPtr(value int) *int {
+	return &value
+}
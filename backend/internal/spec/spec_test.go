@@ -0,0 +1,130 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/internal/apierror"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		endpoint      *Endpoint
+		query         map[string]string
+		wantParameter string
+	}{
+		{"Valid stocks query", Stocks, map[string]string{"page": "1", "limit": "10", "field": "ticker", "order": "DESC", "include_deleted": "true"}, ""},
+		{"Missing page", Stocks, map[string]string{"limit": "10"}, "page"},
+		{"Empty page", Stocks, map[string]string{"page": " ", "limit": "10"}, "page"},
+		{"Page not a number", Stocks, map[string]string{"page": "one", "limit": "10"}, "page"},
+		{"Limit too large is clamped", Stocks, map[string]string{"page": "1", "limit": "500"}, ""},
+		{"Page zero is clamped", Stocks, map[string]string{"page": "0", "limit": "10"}, ""},
+		{"Unknown field", Stocks, map[string]string{"page": "1", "limit": "10", "field": "price"}, "field"},
+		{"Invalid boolean", Stocks, map[string]string{"page": "1", "limit": "10", "include_deleted": "maybe"}, "include_deleted"},
+		{"Invalid as_of", Analyze, map[string]string{"as_of": "yesterday"}, "as_of"},
+		{"Valid as_of", Metrics, map[string]string{"as_of": "2024-03-05"}, ""},
+		{"Missing ticker", Chart, map[string]string{}, "ticker"},
+		{"Invalid ticker", Chart, map[string]string{"ticker": "../etc"}, "ticker"},
+		{"Valid ticker", Chart, map[string]string{"ticker": "BRK.B"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.endpoint.Validate(tt.query)
+			if tt.wantParameter == "" {
+				if err != nil {
+					t.Fatalf("Validate returned unexpected error: %v", err)
+				}
+				return
+			}
+
+			var apiErr *apierror.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an apierror.Error, got %v", err)
+			}
+
+			if apiErr.Status != http.StatusBadRequest || apiErr.Code != apierror.CodeInvalidParameter {
+				t.Errorf("Expected a 400 %s error, got %d %s", apierror.CodeInvalidParameter, apiErr.Status, apiErr.Code)
+			}
+
+			if apiErr.Details["parameter"] != tt.wantParameter {
+				t.Errorf("Expected parameter %s in details, got %v", tt.wantParameter, apiErr.Details)
+			}
+		})
+	}
+}
+
+func TestValidatedSkipsHandler(t *testing.T) {
+	called := false
+	handler := Chart.Validated(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	resp, err := handler(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("handler returned unexpected error: %v", err)
+	}
+
+	if called || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a 400 without calling the handler, got %d (called: %v)", resp.StatusCode, called)
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	doc := NewDocument()
+
+	for _, endpoint := range Endpoints {
+		if _, ok := doc.Paths[endpoint.Path][strings.ToLower(endpoint.Method)]; !ok {
+			t.Errorf("Expected a %s operation for %s", endpoint.Method, endpoint.Path)
+		}
+	}
+
+	stock, ok := doc.Components.Schemas["FormattedStock"]
+	if !ok {
+		t.Fatal("Expected a FormattedStock schema")
+	}
+
+	if stock.Properties["time"].Format != "date-time" {
+		t.Errorf("Expected time to be a date-time, got %+v", stock.Properties["time"])
+	}
+
+	for _, required := range stock.Required {
+		if required == "deleted_at" {
+			t.Error("Expected deleted_at to be optional")
+		}
+	}
+
+	analysis := doc.Components.Schemas["StockAnalysis"]
+	if _, ok := analysis.Properties["ticker"]; !ok {
+		t.Error("Expected the embedded stock fields to be flattened into StockAnalysis")
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("Expected the document to serialize, got %v", err)
+	}
+}
+
+func TestTypeScript(t *testing.T) {
+	ts := TypeScript(NewDocument())
+
+	for _, want := range []string{
+		"export interface FormattedStock {",
+		"  deleted_at?: string\n",
+		"  top_stocks: StockAnalysis[]\n",
+		"  details?: Record<string, unknown>\n",
+		"export interface StocksParams {",
+		"  order?: 'asc' | 'desc'\n",
+		"  ticker: string\n",
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("Expected the TypeScript output to contain %q", want)
+		}
+	}
+}
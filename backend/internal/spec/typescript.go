@@ -0,0 +1,99 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const typeScriptHeader = "// Code generated by cmd/openapi from the endpoint definitions in internal/spec. DO NOT EDIT.\n"
+
+// TypeScript renders the schemas of the document and the query parameters of
+// every endpoint as TypeScript declarations.
+func TypeScript(doc *Document) string {
+	var sb strings.Builder
+	sb.WriteString(typeScriptHeader)
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		schema := doc.Components.Schemas[name]
+
+		fmt.Fprintf(&sb, "\nexport interface %s {\n", name)
+		for _, property := range schema.propertyOrder {
+			optional := ""
+			if !slices.Contains(schema.Required, property) {
+				optional = "?"
+			}
+			fmt.Fprintf(&sb, "  %s%s: %s\n", property, optional, tsType(schema.Properties[property]))
+		}
+		sb.WriteString("}\n")
+	}
+
+	for _, endpoint := range Endpoints {
+		if len(endpoint.Params) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\nexport interface %sParams {\n", endpoint.Name)
+		for _, param := range endpoint.Params {
+			optional := ""
+			if !param.Required {
+				optional = "?"
+			}
+			fmt.Fprintf(&sb, "  %s%s: %s\n", param.Name, optional, tsType(paramSchema(param)))
+		}
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
+
+func tsType(schema *Schema) string {
+	var result string
+
+	switch {
+	case schema.Ref != "":
+		result = strings.TrimPrefix(schema.Ref, schemaRefPrefix)
+	case len(schema.Enum) > 0:
+		literals := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			literals = append(literals, fmt.Sprintf("'%s'", value))
+		}
+		result = strings.Join(literals, " | ")
+	case schema.Type == "array":
+		result = tsType(schema.Items)
+		if strings.Contains(result, " ") {
+			result = "(" + result + ")"
+		}
+		result += "[]"
+	case schema.Type == "object":
+		result = "Record<string, " + tsTypeOrUnknown(schema.AdditionalProperties) + ">"
+	case schema.Type == "string":
+		result = "string"
+	case schema.Type == "integer", schema.Type == "number":
+		result = "number"
+	case schema.Type == "boolean":
+		result = "boolean"
+	default:
+		result = "unknown"
+	}
+
+	if schema.Nullable {
+		result += " | null"
+	}
+
+	return result
+}
+
+func tsTypeOrUnknown(schema *Schema) string {
+	if schema == nil {
+		return "unknown"
+	}
+	return tsType(schema)
+}
@@ -0,0 +1,326 @@
+// Package taxonomy maps the rating and action labels of the feeds to the
+// canonical categories the stocks are stored and scored with, so "accumulate"
+// or "top pick" are no longer scored as an unknown hold.
+package taxonomy
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+const (
+	KindRating = "rating"
+	KindAction = "action"
+
+	// the actions the momentum factors count as revisions
+	ActionUpgrade   = "upgraded by"
+	ActionDowngrade = "downgraded by"
+)
+
+var (
+	ErrInvalidTaxonomy = errors.New("invalid taxonomy")
+
+	//go:embed taxonomy.json
+	taxonomyJSON []byte
+
+	defaultTaxonomy = sync.OnceValue(func() *Taxonomy {
+		var file File
+		if err := json.Unmarshal(taxonomyJSON, &file); err != nil {
+			panic(fmt.Sprintf("taxonomy.json: %v", err))
+		}
+
+		taxonomy, err := New(file)
+		if err != nil {
+			panic(fmt.Sprintf("taxonomy.json: %v", err))
+		}
+
+		return taxonomy
+	})
+)
+
+// File is the JSON layout of a taxonomy.
+type File struct {
+	Ratings Scale `json:"ratings"`
+	Actions Scale `json:"actions"`
+	// Sources map labels to categories for a single source, keyed by the
+	// source name, when a feed means something else by a shared label.
+	Sources map[string]Overrides `json:"sources,omitempty"`
+}
+
+// Scale is the categories of one kind of label.
+type Scale struct {
+	// Default is the category of the empty labels, and the value of the
+	// unmapped ones.
+	Default    string     `json:"default"`
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	Name string `json:"name"`
+	// Value scores the category between 0 and 1.
+	Value  float64  `json:"value"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Overrides map labels to category names.
+type Overrides struct {
+	Ratings map[string]string `json:"ratings,omitempty"`
+	Actions map[string]string `json:"actions,omitempty"`
+}
+
+// Taxonomy resolves labels to categories. It is read only once built and safe
+// for concurrent use.
+type Taxonomy struct {
+	file    File
+	ratings *scale
+	actions *scale
+}
+
+type scale struct {
+	kind       string
+	defaults   Category
+	categories map[string]Category
+	labels     map[string]string
+	sources    map[string]map[string]string
+}
+
+// Default returns the taxonomy of the taxonomy.json embedded in the binary.
+func Default() *Taxonomy {
+	return defaultTaxonomy()
+}
+
+// New indexes the categories of the file by their name and labels, a label
+// may not belong to two categories of the same kind.
+func New(file File) (*Taxonomy, error) {
+	ratings, err := newScale(KindRating, file.Ratings, file.Sources, func(o Overrides) map[string]string { return o.Ratings })
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := newScale(KindAction, file.Actions, file.Sources, func(o Overrides) map[string]string { return o.Actions })
+	if err != nil {
+		return nil, err
+	}
+
+	return &Taxonomy{file: file, ratings: ratings, actions: actions}, nil
+}
+
+// Load reads a taxonomy file and merges it into the default one. Its
+// categories replace the default ones of the same name, its sources replace
+// the default overrides of the same source and its defaults apply when set.
+func Load(path string) (*Taxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read taxonomy: %w", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTaxonomy, path, err)
+	}
+
+	base := Default().file
+	merged := File{
+		Ratings: mergeScale(base.Ratings, file.Ratings),
+		Actions: mergeScale(base.Actions, file.Actions),
+		Sources: maps.Clone(base.Sources),
+	}
+	if merged.Sources == nil {
+		merged.Sources = map[string]Overrides{}
+	}
+	for source, overrides := range file.Sources {
+		merged.Sources[normalize(source)] = overrides
+	}
+
+	return New(merged)
+}
+
+func mergeScale(base, overrides Scale) Scale {
+	if overrides.Default != "" {
+		base.Default = overrides.Default
+	}
+
+	categories := slices.Clone(base.Categories)
+	for _, category := range overrides.Categories {
+		i := slices.IndexFunc(categories, func(c Category) bool { return normalize(c.Name) == normalize(category.Name) })
+		if i < 0 {
+			categories = append(categories, category)
+			continue
+		}
+		categories[i] = category
+	}
+	base.Categories = categories
+
+	return base
+}
+
+func newScale(kind string, file Scale, sources map[string]Overrides, labels func(Overrides) map[string]string) (*scale, error) {
+	s := &scale{
+		kind:       kind,
+		categories: make(map[string]Category, len(file.Categories)),
+		labels:     map[string]string{},
+		sources:    map[string]map[string]string{},
+	}
+
+	for _, category := range file.Categories {
+		category.Name = normalize(category.Name)
+		if category.Name == "" {
+			return nil, fmt.Errorf("%w: %s category without a name", ErrInvalidTaxonomy, kind)
+		}
+		if category.Value < 0 || category.Value > 1 {
+			return nil, fmt.Errorf("%w: %s category %q value %v must be between 0 and 1", ErrInvalidTaxonomy, kind,
+				category.Name, category.Value)
+		}
+		if _, ok := s.categories[category.Name]; ok {
+			return nil, fmt.Errorf("%w: %s category %q is listed twice", ErrInvalidTaxonomy, kind, category.Name)
+		}
+		s.categories[category.Name] = category
+
+		for _, label := range append([]string{category.Name}, category.Labels...) {
+			label = normalize(label)
+			if other, ok := s.labels[label]; ok && other != category.Name {
+				return nil, fmt.Errorf("%w: %s label %q belongs to both %s and %s", ErrInvalidTaxonomy, kind, label,
+					other, category.Name)
+			}
+			s.labels[label] = category.Name
+		}
+	}
+
+	defaults, ok := s.categories[normalize(file.Default)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s default %q is not a category", ErrInvalidTaxonomy, kind, file.Default)
+	}
+	s.defaults = defaults
+
+	for source, overrides := range sources {
+		mapped := map[string]string{}
+		for label, name := range labels(overrides) {
+			if _, ok := s.categories[normalize(name)]; !ok {
+				return nil, fmt.Errorf("%w: %s maps the %s %q to the unknown category %q", ErrInvalidTaxonomy, source,
+					kind, label, name)
+			}
+			mapped[normalize(label)] = normalize(name)
+		}
+		s.sources[normalize(source)] = mapped
+	}
+
+	return s, nil
+}
+
+// Rating returns the category of the rating label of the source, or the
+// normalized label with the value of the default category and false when it
+// is unmapped.
+func (t *Taxonomy) Rating(source, label string) (Category, bool) {
+	return t.ratings.resolve(source, label)
+}
+
+// Action returns the category of the action label of the source, like Rating.
+func (t *Taxonomy) Action(source, label string) (Category, bool) {
+	return t.actions.resolve(source, label)
+}
+
+// DefaultRating is the category of the stocks without a rating.
+func (t *Taxonomy) DefaultRating() string {
+	return t.ratings.defaults.Name
+}
+
+// DefaultAction is the category of the stocks without an action.
+func (t *Taxonomy) DefaultAction() string {
+	return t.actions.defaults.Name
+}
+
+func (s *scale) resolve(source, label string) (Category, bool) {
+	label = normalize(label)
+
+	name, ok := s.sources[normalize(source)][label]
+	if !ok {
+		name, ok = s.labels[label]
+	}
+	if !ok {
+		return Category{Name: label, Value: s.defaults.Value}, false
+	}
+
+	return s.categories[name], true
+}
+
+// normalize lowercases the label and spells hyphens, underscores and runs of
+// spaces as a single space, so "Strong-Buy" and "strong  buy" are one label.
+func normalize(label string) string {
+	label = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' {
+			return ' '
+		}
+		return r
+	}, strings.ToLower(label))
+
+	return strings.Join(strings.Fields(label), " ")
+}
+
+// Label is a rating or action label of a source missing from the taxonomy.
+type Label struct {
+	Kind   string
+	Source string
+	Label  string
+}
+
+// Unmapped counts the labels of a sync missing from the taxonomy, to be
+// reviewed and added to it.
+type Unmapped map[Label]int
+
+// Add counts the rating and action labels of the formatted stock the taxonomy
+// has no category for.
+func (u Unmapped) Add(t *Taxonomy, stock *models.FormattedStock) {
+	for _, rating := range []string{stock.RatingFrom, stock.RatingTo} {
+		if _, ok := t.Rating(stock.Source, rating); !ok {
+			u[Label{Kind: KindRating, Source: stock.Source, Label: rating}]++
+		}
+	}
+
+	if _, ok := t.Action(stock.Source, stock.Action); !ok {
+		u[Label{Kind: KindAction, Source: stock.Source, Label: stock.Action}]++
+	}
+}
+
+// Labels returns the labels from the most to the least frequent.
+func (u Unmapped) Labels() []*models.UnmappedLabel {
+	labels := make([]*models.UnmappedLabel, 0, len(u))
+	for label, count := range u {
+		labels = append(labels, &models.UnmappedLabel{
+			Kind:   label.Kind,
+			Source: label.Source,
+			Label:  label.Label,
+			Count:  count,
+		})
+	}
+
+	slices.SortFunc(labels, func(a, b *models.UnmappedLabel) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Kind, b.Kind), strings.Compare(a.Source, b.Source),
+			strings.Compare(a.Label, b.Label))
+	})
+
+	return labels
+}
+
+// Names returns the labels as kind:label, or kind:source:label for the ones
+// of a named source, from the most to the least frequent, for the logs.
+func (u Unmapped) Names() []string {
+	var names []string
+	for _, label := range u.Labels() {
+		if label.Source == "" {
+			names = append(names, label.Kind+":"+label.Label)
+			continue
+		}
+		names = append(names, label.Kind+":"+label.Source+":"+label.Label)
+	}
+	return names
+}
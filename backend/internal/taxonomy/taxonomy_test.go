@@ -0,0 +1,153 @@
+package taxonomy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CorreaJose13/StockAPI/models"
+)
+
+func testFile() File {
+	return File{
+		Ratings: Scale{Default: "hold", Categories: []Category{
+			{Name: "buy", Value: 1, Labels: []string{"strong-buy", "top pick"}},
+			{Name: "hold", Value: 0.5, Labels: []string{"neutral"}},
+			{Name: "sell", Value: 0},
+		}},
+		Actions: Scale{Default: "initiated by", Categories: []Category{
+			{Name: "upgraded by", Value: 1, Labels: []string{"upgraded"}},
+			{Name: "initiated by", Value: 0.5},
+		}},
+		Sources: map[string]Overrides{
+			"Benzinga": {Ratings: map[string]string{"Neutral": "sell"}},
+		},
+	}
+}
+
+func TestRating(t *testing.T) {
+	taxonomy, err := New(testFile())
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		source    string
+		label     string
+		want      Category
+		wantFound bool
+	}{
+		{"Category name", "", "buy", Category{Name: "buy", Value: 1}, true},
+		{"Label", "", "Top Pick", Category{Name: "buy", Value: 1}, true},
+		{"Hyphens and spaces", "", " Strong  Buy ", Category{Name: "buy", Value: 1}, true},
+		{"Source override", "benzinga", "neutral", Category{Name: "sell", Value: 0}, true},
+		{"Label of another source", "finnhub", "neutral", Category{Name: "hold", Value: 0.5}, true},
+		{"Unmapped", "", "Accumulate", Category{Name: "accumulate", Value: 0.5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := taxonomy.Rating(tt.source, tt.label)
+			if got.Name != tt.want.Name || got.Value != tt.want.Value || found != tt.wantFound {
+				t.Errorf("Rating(%q, %q) = %s %v, %v, want %s %v, %v", tt.source, tt.label, got.Name, got.Value, found,
+					tt.want.Name, tt.want.Value, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*File)
+	}{
+		{"Label of two categories", func(f *File) { f.Ratings.Categories[2].Labels = []string{"top pick"} }},
+		{"Category listed twice", func(f *File) { f.Ratings.Categories[2].Name = "Buy" }},
+		{"Value out of range", func(f *File) { f.Actions.Categories[0].Value = 2 }},
+		{"Unknown default", func(f *File) { f.Actions.Default = "reiterated by" }},
+		{"Override to an unknown category", func(f *File) {
+			f.Sources["benzinga"] = Overrides{Actions: map[string]string{"maintained": "reiterated by"}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := testFile()
+			tt.modify(&file)
+
+			if _, err := New(file); !errors.Is(err, ErrInvalidTaxonomy) {
+				t.Errorf("New error = %v, want %v", err, ErrInvalidTaxonomy)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	taxonomy := Default()
+
+	for _, label := range []string{"accumulate", "top pick", "market perform", "underweight", "strong sell"} {
+		if _, ok := taxonomy.Rating("", label); !ok {
+			t.Errorf("rating %q is unmapped", label)
+		}
+	}
+
+	if taxonomy.DefaultRating() != "hold" || taxonomy.DefaultAction() != "initiated by" {
+		t.Errorf("defaults = %q, %q, want hold, initiated by", taxonomy.DefaultRating(), taxonomy.DefaultAction())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "taxonomy.json")
+	file := `{
+		"ratings": {"categories": [{"name": "buy", "value": 0.9, "labels": ["strong buy", "buy the dip"]}]},
+		"sources": {"benzinga": {"actions": {"maintained": "target set by"}}}
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	taxonomy, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+
+	if got, ok := taxonomy.Rating("", "buy the dip"); !ok || got.Value != 0.9 {
+		t.Errorf("Rating(buy the dip) = %v, %v, want buy 0.9", got, ok)
+	}
+	if got, _ := taxonomy.Action("benzinga", "maintained"); got.Name != "target set by" {
+		t.Errorf("Action(benzinga, maintained) = %q, want target set by", got.Name)
+	}
+	if got, _ := taxonomy.Action("", "maintained"); got.Name != "reiterated by" {
+		t.Errorf("Action(maintained) = %q, want reiterated by", got.Name)
+	}
+	if got, ok := taxonomy.Rating("", "sell"); !ok || got.Value != 0 {
+		t.Errorf("default category missing after Load: %v, %v", got, ok)
+	}
+}
+
+func TestUnmapped(t *testing.T) {
+	taxonomy := Default()
+
+	unmapped := Unmapped{}
+	for _, stock := range []*models.FormattedStock{
+		{Source: "benzinga", RatingFrom: "hold", RatingTo: "conviction list", Action: "upgraded by"},
+		{Source: "benzinga", RatingFrom: "conviction list", RatingTo: "buy", Action: "removed from list"},
+		{Source: "finnhub", RatingFrom: "buy", RatingTo: "buy", Action: "initiated by"},
+	} {
+		unmapped.Add(taxonomy, stock)
+	}
+
+	labels := unmapped.Labels()
+	if len(labels) != 2 {
+		t.Fatalf("Labels = %v, want 2 labels", labels)
+	}
+
+	if got := labels[0]; got.Kind != KindRating || got.Label != "conviction list" || got.Count != 2 {
+		t.Errorf("most frequent label = %+v, want the rating conviction list twice", got)
+	}
+	if got := labels[1]; got.Kind != KindAction || got.Source != "benzinga" || got.Count != 1 {
+		t.Errorf("second label = %+v, want the benzinga action once", got)
+	}
+}
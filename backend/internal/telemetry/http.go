@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewHTTPClient returns a client tracing the upstream calls, see Transport,
+// and giving up on them after the timeout unless it's zero.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: Transport(nil), Timeout: timeout}
+}
+
+// Transport wraps base, http.DefaultTransport when nil, with a client span and
+// a latency observation per request. The trace context and the request ID are forwarded to the upstream.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the query is left out since it may hold credentials, like the Alpha
+	// Vantage key
+	ctx, span := tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	// the request must not be modified by a RoundTripper
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if id := RequestID(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	ObserveUpstream(req.URL.Host, req.Method, status, err, time.Since(start))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// Extract continues the trace propagated in the headers of an incoming
+// request, which API Gateway may pass in any case.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	carrier := propagation.MapCarrier{}
+	for key, value := range headers {
+		carrier.Set(strings.ToLower(key), value)
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func TestTransport(t *testing.T) {
+	recorder := recordSpans(t)
+
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus codes.Code
+	}{
+		{name: "Successful call", status: http.StatusOK, wantStatus: codes.Unset},
+		{name: "Upstream failure", status: http.StatusBadGateway, wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRequestID, gotTraceparent string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequestID = r.Header.Get(RequestIDHeader)
+				gotTraceparent = r.Header.Get("traceparent")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			ctx := WithRequestID(context.Background(), "req-1")
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/query?apikey=secret", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := NewHTTPClient(time.Second).Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if gotRequestID != "req-1" {
+				t.Errorf("X-Request-ID = %q, want req-1", gotRequestID)
+			}
+			if gotTraceparent == "" {
+				t.Error("expected the trace context to be propagated")
+			}
+			if req.Header.Get("traceparent") != "" {
+				t.Error("the caller's request was modified")
+			}
+
+			spans := recorder.Ended()
+			span := spans[len(spans)-1]
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("span status = %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+
+			attrs := attribute.NewSet(span.Attributes()...)
+			if path, _ := attrs.Value("url.path"); path.AsString() != "/query" {
+				t.Errorf("url.path = %q, want /query", path.AsString())
+			}
+			if status, _ := attrs.Value("http.response.status_code"); status.AsInt64() != int64(tt.status) {
+				t.Errorf("http.response.status_code = %d, want %d", status.AsInt64(), tt.status)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	recordSpans(t)
+
+	traceparent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := Extract(context.Background(), map[string]string{"Traceparent": traceparent})
+
+	_, span := Start(ctx, "child")
+	defer span.End()
+
+	if got := span.SpanContext().TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("trace ID = %s, want the one of the caller", got)
+	}
+}
@@ -0,0 +1,113 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewLogger(t *testing.T) {
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+
+	tests := []struct {
+		name     string
+		level    string
+		format   string
+		ctx      context.Context
+		wantErr  error
+		wantLogs bool
+		wantKeys map[string]string
+		wantNone []string
+	}{
+		{
+			name:     "Request and trace IDs are added",
+			level:    "info",
+			ctx:      trace.ContextWithSpanContext(WithRequestID(context.Background(), "req-1"), spanCtx),
+			wantLogs: true,
+			wantKeys: map[string]string{
+				"request_id": "req-1",
+				"trace_id":   spanCtx.TraceID().String(),
+				"span_id":    spanCtx.SpanID().String(),
+			},
+		},
+		{
+			name:     "Context without IDs",
+			format:   "JSON",
+			ctx:      context.Background(),
+			wantLogs: true,
+			wantNone: []string{"request_id", "trace_id", "span_id"},
+		},
+		{
+			name:  "Records below the level are dropped",
+			level: "warn",
+			ctx:   context.Background(),
+		},
+		{
+			name:    "Invalid level",
+			level:   "verbose",
+			wantErr: ErrInvalidLogLevel,
+		},
+		{
+			name:    "Invalid format",
+			format:  "xml",
+			wantErr: ErrInvalidLogFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := NewLogger(&buf, tt.level, tt.format)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewLogger error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			logger.With("service", "test").InfoContext(tt.ctx, "hello")
+
+			if !tt.wantLogs {
+				if buf.Len() != 0 {
+					t.Fatalf("expected no logs, got %s", buf.String())
+				}
+				return
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("failed to decode log %q: %v", buf.String(), err)
+			}
+
+			if record["service"] != "test" {
+				t.Errorf("service = %v, want test", record["service"])
+			}
+			for key, want := range tt.wantKeys {
+				if record[key] != want {
+					t.Errorf("%s = %v, want %s", key, record[key], want)
+				}
+			}
+			for _, key := range tt.wantNone {
+				if _, ok := record[key]; ok {
+					t.Errorf("unexpected %s in %s", key, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestInvocationRequestID(t *testing.T) {
+	first := InvocationRequestID(context.Background())
+	second := InvocationRequestID(context.Background())
+
+	if len(first) != 32 || first == second {
+		t.Errorf("expected distinct generated IDs, got %q and %q", first, second)
+	}
+}
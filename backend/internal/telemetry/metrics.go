@@ -0,0 +1,182 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	metricsNamespace = "stockapi"
+	pushJob          = "stockapi"
+
+	// the requests push at most this often, in the background
+	pushInterval = 10 * time.Second
+	pushTimeout  = 5 * time.Second
+
+	// the outcome label of the operations without a status code
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+var (
+	// Registry holds every metric of the process, served by the standalone
+	// server and pushed by the Lambdas.
+	Registry = prometheus.NewRegistry()
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of the calls to the upstream APIs by host, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"host", "method", "status"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of the repository methods by method and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the API requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	fetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingest_fetch_duration_seconds",
+		Help:      "Time taken to fetch every page of a ratings source by source and outcome.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"source", "outcome"})
+
+	ingestPages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingest_pages_total",
+		Help:      "Pages fetched from the ratings sources.",
+	}, []string{"source"})
+
+	ingestRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingest_rows_total",
+		Help:      "Rows handled by the syncs by stage: fetched, rejected, written, inserted, updated, deleted or purged.",
+	}, []string{"stage"})
+
+	// pusher is set by Setup when a Pushgateway is configured.
+	pusher *push.Pusher
+	// pushing guards the background pushes, lastPush is when the last one
+	// ended in Unix nanoseconds.
+	pushing  atomic.Bool
+	lastPush atomic.Int64
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		upstreamDuration,
+		queryDuration,
+		requestDuration,
+		fetchDuration,
+		ingestPages,
+		ingestRows,
+	)
+}
+
+// setupPush pushes the registry under the group of the function, shared by
+// its instances so the series don't grow with every instance Lambda starts.
+func setupPush(service, url string) {
+	if url == "" {
+		return
+	}
+
+	pusher = push.New(url, pushJob).
+		Gatherer(Registry).
+		Grouping("service", service)
+}
+
+// Push sends the metrics to the Pushgateway, if one is configured. Failures
+// are only logged so they never fail the invocation.
+func Push(ctx context.Context) {
+	if pusher == nil {
+		return
+	}
+
+	if err := pusher.PushContext(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to push metrics", "error", err)
+	}
+}
+
+// PushInBackground pushes the metrics without holding up the request, at most
+// once every pushInterval and one push at a time.
+func PushInBackground(ctx context.Context) {
+	if pusher == nil || time.Since(time.Unix(0, lastPush.Load())) < pushInterval || !pushing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer pushing.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pushTimeout)
+		defer cancel()
+
+		Push(ctx)
+		lastPush.Store(time.Now().UnixNano())
+	}()
+}
+
+// DeletePushed deletes the group of the function from the Pushgateway, called
+// when the Lambda instance shuts down so it doesn't keep stale series.
+func DeletePushed() {
+	if pusher == nil {
+		return
+	}
+
+	if err := pusher.Delete(); err != nil {
+		slog.Warn("failed to delete pushed metrics", "error", err)
+	}
+}
+
+func ObserveUpstream(host, method string, status int, err error, duration time.Duration) {
+	label := strconv.Itoa(status)
+	if err != nil {
+		label = OutcomeError
+	}
+
+	upstreamDuration.WithLabelValues(host, method, label).Observe(duration.Seconds())
+}
+
+func ObserveQuery(method string, err error, duration time.Duration) {
+	queryDuration.WithLabelValues(method, outcome(err)).Observe(duration.Seconds())
+}
+
+func ObserveRequest(route, method string, status int, duration time.Duration) {
+	requestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func ObserveFetch(source string, err error, duration time.Duration) {
+	fetchDuration.WithLabelValues(source, outcome(err)).Observe(duration.Seconds())
+}
+
+func CountPage(source string) {
+	ingestPages.WithLabelValues(source).Inc()
+}
+
+func CountRows(stage string, rows int) {
+	ingestRows.WithLabelValues(stage).Add(float64(rows))
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeOK
+}
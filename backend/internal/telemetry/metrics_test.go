@@ -0,0 +1,143 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		observe func()
+		metric  string
+		labels  map[string]string
+		want    float64
+	}{
+		{
+			name:    "Failed upstream calls are labeled error",
+			observe: func() { ObserveUpstream("api.test", "GET", 0, errors.New("timeout"), time.Second) },
+			metric:  "stockapi_upstream_request_duration_seconds",
+			labels:  map[string]string{"host": "api.test", "status": OutcomeError},
+			want:    1,
+		},
+		{
+			name:    "Upstream calls are labeled with the status",
+			observe: func() { ObserveUpstream("api.test", "GET", 503, nil, time.Second) },
+			metric:  "stockapi_upstream_request_duration_seconds",
+			labels:  map[string]string{"host": "api.test", "status": "503"},
+			want:    1,
+		},
+		{
+			name:    "Queries are labeled with the outcome",
+			observe: func() { ObserveQuery("GetStocks", nil, time.Millisecond) },
+			metric:  "stockapi_db_query_duration_seconds",
+			labels:  map[string]string{"method": "GetStocks", "outcome": OutcomeOK},
+			want:    1,
+		},
+		{
+			name:    "Requests are labeled with the route",
+			observe: func() { ObserveRequest("/stocks", "GET", 200, time.Millisecond) },
+			metric:  "stockapi_http_request_duration_seconds",
+			labels:  map[string]string{"route": "/stocks", "status": "200"},
+			want:    1,
+		},
+		{
+			name:    "Rows are added per stage",
+			observe: func() { CountRows("fetched", 3); CountRows("fetched", 2) },
+			metric:  "stockapi_ingest_rows_total",
+			labels:  map[string]string{"stage": "fetched"},
+			want:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := gathered(t, tt.metric, tt.labels)
+			tt.observe()
+
+			if got := gathered(t, tt.metric, tt.labels) - before; got != tt.want {
+				t.Errorf("%s%v increased by %v, want %v", tt.metric, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+// gathered sums the counter values, or histogram sample counts, of the series
+// of the metric matching the labels.
+func gathered(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	var total float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			if !matches(metric, labels) {
+				continue
+			}
+
+			if histogram := metric.GetHistogram(); histogram != nil {
+				total += float64(histogram.GetSampleCount())
+			} else {
+				total += metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return total
+}
+
+func matches(metric *dto.Metric, labels map[string]string) bool {
+	found := 0
+	for _, pair := range metric.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			found++
+		}
+	}
+	return found == len(labels)
+}
+
+func TestPushInBackground(t *testing.T) {
+	paths := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	setupPush("stocks", server.URL)
+	lastPush.Store(0)
+	defer func() { pusher = nil }()
+
+	PushInBackground(context.Background())
+	// throttled since the first push is either in flight or just ended
+	PushInBackground(context.Background())
+
+	if got, want := <-paths, "PUT /metrics/job/stockapi/service/stocks"; got != want {
+		t.Errorf("Expected push %q, got %q", want, got)
+	}
+	for pushing.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	DeletePushed()
+	if got, want := <-paths, "DELETE /metrics/job/stockapi/service/stocks"; got != want {
+		t.Errorf("Expected delete %q, got %q", want, got)
+	}
+}
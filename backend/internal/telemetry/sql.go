@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Connector wraps the connector of a SQL driver so every query, statement and
+// transaction run through database/sql opens a client span.
+func Connector(base driver.Connector, system string) driver.Connector {
+	return &connector{base: base, system: system}
+}
+
+type connector struct {
+	base   driver.Connector
+	system string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	baseConn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: baseConn, system: c.system}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// conn forwards to the driver connection, returning driver.ErrSkip for the
+// optional interfaces it doesn't implement so database/sql falls back.
+type conn struct {
+	driver.Conn
+	system string
+}
+
+func (c *conn) start(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system.name", c.system),
+		attribute.String("db.operation.name", operation),
+	}
+	if query != "" {
+		attrs = append(attrs, attribute.String("db.query.text", query))
+	}
+
+	return tracer().Start(ctx, spanName(operation, query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.start(ctx, "query", query)
+	defer func() { End(span, skipped(err)) }()
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.start(ctx, "exec", query)
+	defer func() { End(span, skipped(err)) }()
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (_ driver.Stmt, err error) {
+	ctx, span := c.start(ctx, "prepare", query)
+	defer func() { End(span, err) }()
+
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (_ driver.Tx, err error) {
+	ctx, span := c.start(ctx, "begin", "")
+	defer func() { End(span, err) }()
+
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+// skipped drops driver.ErrSkip, which only makes database/sql retry the call
+// another way.
+func skipped(err error) error {
+	if err == driver.ErrSkip {
+		return nil
+	}
+
+	return err
+}
+
+// spanName is the operation followed by the first keyword of the query, like
+// "query SELECT", since the full text is too long and variable for a name.
+func spanName(operation, query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "db." + operation
+	}
+
+	return "db." + operation + " " + strings.ToUpper(words[0])
+}
@@ -0,0 +1,24 @@
+package telemetry
+
+import "testing"
+
+func TestSpanName(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		query     string
+		want      string
+	}{
+		{name: "Keyword is upper cased", operation: "query", query: "select * from stocks", want: "db.query SELECT"},
+		{name: "Leading whitespace", operation: "exec", query: "\n\t\tINSERT INTO stocks", want: "db.exec INSERT"},
+		{name: "No query", operation: "begin", want: "db.begin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spanName(tt.operation, tt.query); got != tt.want {
+				t.Errorf("spanName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
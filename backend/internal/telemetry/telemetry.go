@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/CorreaJose13/StockAPI/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	instrumentationName = "github.com/CorreaJose13/StockAPI"
+)
+
+var (
+	ErrUnknownExporter  = errors.New("unknown trace exporter")
+	ErrInvalidLogLevel  = errors.New("invalid log level")
+	ErrInvalidLogFormat = errors.New("invalid log format")
+
+	// provider is kept to flush the spans before a Lambda invocation returns,
+	// it stays nil when tracing is disabled.
+	provider *sdktrace.TracerProvider
+)
+
+// Setup installs the default slog logger, the metrics pusher and the tracer
+// provider of the service. The OTLP exporter reads its endpoint and headers from the standard
+// OTEL_EXPORTER_OTLP_* variables.
+func Setup(ctx context.Context, service string, cfg *config.Config) error {
+	logger, err := NewLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger.With("service", service))
+	setupPush(service, cfg.MetricsPushgatewayURL)
+
+	exporter, err := newExporter(ctx, cfg.TraceExporter)
+	if err != nil || exporter == nil {
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return nil
+}
+
+func newExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExporter, name)
+	}
+}
+
+// Flush exports the buffered spans, Lambda freezes the process as soon as the
+// invocation returns.
+func Flush(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+
+	if err := provider.ForceFlush(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to flush spans", "error", err)
+	}
+}
+
+// Shutdown flushes and stops the tracer provider, for the processes that exit.
+func Shutdown(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+
+	if err := provider.Shutdown(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to shut down tracing", "error", err)
+	}
+}
+
+// Start opens a span, a no-op one when tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer opens the span of a request served by the API.
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// tracer is looked up on every span so the provider installed by Setup is
+// used even by the clients built before it.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records err, if any, on the span and closes it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
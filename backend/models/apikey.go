@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RateLimits bounds the requests of a client, zero values fall back to the
+// configured defaults.
+type RateLimits struct {
+	// RatePerMinute is the token bucket refill rate.
+	RatePerMinute int `json:"rate_per_minute"`
+	// Burst is the token bucket size.
+	Burst int `json:"burst"`
+	// DailyQuota caps the requests per UTC day.
+	DailyQuota int `json:"daily_quota"`
+}
+
+// APIKey is an issued key, only its hash is stored.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	Limits    RateLimits `json:"limits"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Usage is the rate limiting state of a client.
+type Usage struct {
+	ClientID   string
+	Tokens     float64
+	RefilledAt time.Time
+	// QuotaDay is the UTC day QuotaUsed counts the requests of.
+	QuotaDay  string
+	QuotaUsed int
+}
@@ -9,6 +9,11 @@ type DailyData struct {
 	Date   string  `json:"date"`
 }
 
+type DailyBar struct {
+	Ticker string `json:"ticker"`
+	DailyData
+}
+
 type StockData struct {
 	TimeSeriesDaily map[string]struct {
 		Open   string `json:"1. open"`
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StockFilter selects, sorts and paginates the stocks listing.
+type StockFilter struct {
+	Field          string
+	Order          string
+	Search         string
+	IncludeDeleted bool
+	// AsOf reads the stocks as they were served at that instant, the zero
+	// value reads the latest ones.
+	AsOf  time.Time
+	Page  int
+	Limit int
+}
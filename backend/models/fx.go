@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// FXRate is how many units of Quote one unit of Base was worth on AsOf. Rate
+// is the decimal as stored, to be read without rounding.
+type FXRate struct {
+	Base  string    `json:"base"`
+	Quote string    `json:"quote"`
+	Rate  string    `json:"rate"`
+	AsOf  time.Time `json:"as_of"`
+}
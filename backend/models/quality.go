@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// QualityReport summarizes the data-quality issues of the stocks of a sync
+// run, it is stored with the run.
+type QualityReport struct {
+	Checked int `json:"checked"`
+	// Rejected, Warned and Flagged count the stocks by their most severe
+	// issue.
+	Rejected int                           `json:"rejected"`
+	Warned   int                           `json:"warned"`
+	Flagged  int                           `json:"flagged"`
+	Rules    map[string]*QualityRuleReport `json:"rules,omitempty"`
+}
+
+type QualityRuleReport struct {
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+	// Samples are the first issues of the rule, to look them up in the feed.
+	Samples []*QualityIssue `json:"samples,omitempty"`
+}
+
+type QualityIssue struct {
+	Ticker    string    `json:"ticker"`
+	Brokerage string    `json:"brokerage"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+}
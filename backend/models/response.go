@@ -0,0 +1,20 @@
+package models
+
+type StocksResponse struct {
+	Stocks []*FormattedStock `json:"stocks"`
+	Length int               `json:"length"`
+}
+
+type ChartResponse struct {
+	TimeSeries []DailyData `json:"time_series"`
+}
+
+// TokenResponse is a short-lived bearer token issued to a browser, in the
+// shape of an OAuth 2.0 access token response.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the validity of the token in seconds.
+	ExpiresIn int    `json:"expires_in"`
+	Scope     string `json:"scope"`
+}
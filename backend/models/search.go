@@ -0,0 +1,16 @@
+package models
+
+// SearchSuggestion is a ticker, company or brokerage matching a search query,
+// Ticker is empty for brokerages.
+type SearchSuggestion struct {
+	Field  string  `json:"field"`
+	Value  string  `json:"value"`
+	Ticker string  `json:"ticker,omitempty"`
+	Score  float64 `json:"score"`
+}
+
+// SearchResponse lists the suggestions of the query from the most relevant.
+type SearchResponse struct {
+	Query       string             `json:"query"`
+	Suggestions []SearchSuggestion `json:"suggestions"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Snapshot is a response precomputed from the stocks of a sync run.
+type Snapshot struct {
+	RunID string
+	Kind  string
+	// TakenAt is when the run finished, the snapshot serves the reads from
+	// then until the next run that changed the stocks.
+	TakenAt time.Time
+	Payload json.RawMessage
+}
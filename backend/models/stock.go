@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultSource is the ratings source of records that predate multiple feeds.
+const DefaultSource = "api"
 
 type Stock struct {
 	Ticker     string `json:"ticker"`
@@ -12,6 +18,7 @@ type Stock struct {
 	RatingFrom string `json:"rating_from"`
 	RatingTo   string `json:"rating_to"`
 	Time       string `json:"time"`
+	Source     string `json:"source"`
 }
 
 type Response struct {
@@ -20,13 +27,23 @@ type Response struct {
 }
 
 type FormattedStock struct {
-	Ticker     string    `json:"ticker"`
-	TargetFrom float64   `json:"target_from"`
-	TargetTo   float64   `json:"target_to"`
+	Ticker string `json:"ticker"`
+	// TargetFrom and TargetTo are exact decimals such as "45.10", encoded as
+	// JSON numbers.
+	TargetFrom json.Number `json:"target_from"`
+	TargetTo   json.Number `json:"target_to"`
+	// Currency is the ISO 4217 code of the targets.
+	Currency   string    `json:"currency"`
 	Company    string    `json:"company"`
 	Action     string    `json:"action"`
 	Brokerage  string    `json:"brokerage"`
 	RatingFrom string    `json:"rating_from"`
 	RatingTo   string    `json:"rating_to"`
 	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	// DeletedAt is set once the stock dropped out of the feed.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// RecordedAt is when a rating of the history was stored, which can be
+	// well after its time for a late or backfilled rating.
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
 }
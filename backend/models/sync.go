@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// SyncOptions controls how a staging table is reconciled with the stocks table.
+type SyncOptions struct {
+	// DryRun computes the planned changes without applying them.
+	DryRun bool
+	// MaxDeletePercent refuses runs that would delete more than this share of
+	// the live stocks. Zero disables the check.
+	MaxDeletePercent float64
+	// Force applies the deletes even when they exceed MaxDeletePercent.
+	Force bool
+	// Retention is how long soft deleted stocks are kept before being purged.
+	// Zero keeps them forever.
+	Retention time.Duration
+	// Quality is the data-quality report of the staged stocks, stored with
+	// the run.
+	Quality *QualityReport
+	// Rejected lists the stocks the run rejected, missing from the staging
+	// table but still in the feed, so the stored ones aren't soft deleted.
+	Rejected []StockKey
+}
+
+// StockKey identifies a stock of a table, which holds one row per ticker and
+// source.
+type StockKey struct {
+	Ticker string `json:"ticker"`
+	Source string `json:"source"`
+}
+
+// SyncPlan lists the rows a reconciliation inserts, updates, soft deletes and
+// purges.
+type SyncPlan struct {
+	Inserts  []*FormattedStock `json:"inserts"`
+	Updates  []*FormattedStock `json:"updates"`
+	Deletes  []*FormattedStock `json:"deletes"`
+	Purges   []*FormattedStock `json:"purges"`
+	LiveRows int               `json:"live_rows"`
+	DryRun   bool              `json:"dry_run"`
+	// RunID identifies the applied run in the sync runs table.
+	RunID string `json:"run_id,omitempty"`
+}
+
+// SyncRun records a sync applied to a stocks table, the latest run that
+// changed it versions the data served from it.
+type SyncRun struct {
+	ID         string    `json:"id"`
+	Table      string    `json:"table"`
+	FinishedAt time.Time `json:"finished_at"`
+	Inserts    int       `json:"inserts"`
+	Updates    int       `json:"updates"`
+	Deletes    int       `json:"deletes"`
+	Purges     int       `json:"purges"`
+}
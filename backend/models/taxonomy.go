@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UnmappedLabel is a rating or action label of a source the taxonomy has no
+// category for, kept for review with how often the syncs have seen it.
+type UnmappedLabel struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Label  string `json:"label"`
+	Count  int    `json:"count"`
+	// FirstSeen and LastSeen are the first and latest syncs that stored it,
+	// set by SaveUnmappedLabels.
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
@@ -1,12 +1,15 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
-	"strconv"
+	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/CorreaJose13/StockAPI/internal/brokerage"
+	"github.com/CorreaJose13/StockAPI/internal/money"
+	"github.com/CorreaJose13/StockAPI/internal/taxonomy"
 	"github.com/CorreaJose13/StockAPI/models"
 )
 
@@ -19,11 +22,25 @@ var (
 	ErrEmptyBrokerageString = fmt.Errorf("empty brokerage")
 	ErrEmptyTargetString    = fmt.Errorf("empty target")
 	ErrNegativeTarget       = fmt.Errorf("negative target")
+	ErrInvalidTargetFormat  = fmt.Errorf("invalid target format")
+	ErrCurrencyMismatch     = fmt.Errorf("currency mismatch")
 	ErrEmptyTimeString      = fmt.Errorf("empty timestamp")
 	ErrInvalidTimeFormat    = fmt.Errorf("invalid format")
 )
 
+// StockFormatter formats the stocks of the feeds, naming their brokerages by
+// the canonical names of its registry and their ratings and actions by the
+// categories of its taxonomy, the default ones when nil.
+type StockFormatter struct {
+	Brokerages *brokerage.Registry
+	Taxonomy   *taxonomy.Taxonomy
+}
+
 func Formatter(stock *models.Stock) (*models.FormattedStock, error) {
+	return StockFormatter{}.Format(stock)
+}
+
+func (f StockFormatter) Format(stock *models.Stock) (*models.FormattedStock, error) {
 
 	formattedTicker, err := formatTicker(stock.Ticker)
 	if err != nil {
@@ -35,7 +52,7 @@ func Formatter(stock *models.Stock) (*models.FormattedStock, error) {
 		return nil, fmt.Errorf("failed to format company for '%s': %w", stock.Ticker, err)
 	}
 
-	formattedBrokerage, err := formatBrokerage(stock.Brokerage)
+	formattedBrokerage, err := f.formatBrokerage(stock.Brokerage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format brokerage for '%s': %w", stock.Ticker, err)
 	}
@@ -50,21 +67,30 @@ func Formatter(stock *models.Stock) (*models.FormattedStock, error) {
 		return nil, fmt.Errorf("failed to format target_to for '%s': %w", stock.Ticker, err)
 	}
 
+	currency, err := targetCurrency(formattedTargetFrom, formattedTargetTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format targets for '%s': %w", stock.Ticker, err)
+	}
+
 	formattedTime, err := formatTime(stock.Time)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format time for '%s': %w", stock.Ticker, err)
 	}
 
+	source := strings.TrimSpace(strings.ToLower(stock.Source))
+
 	return &models.FormattedStock{
 		Ticker:     formattedTicker,
-		TargetFrom: formattedTargetFrom,
-		TargetTo:   formattedTargetTo,
+		TargetFrom: json.Number(formattedTargetFrom.Amount.String()),
+		TargetTo:   json.Number(formattedTargetTo.Amount.String()),
+		Currency:   currency,
 		Company:    formattedCompany,
-		Action:     formatAction(stock.Action),
+		Action:     f.formatAction(source, stock.Action),
 		Brokerage:  formattedBrokerage,
-		RatingFrom: formatRating(stock.RatingFrom),
-		RatingTo:   formatRating(stock.RatingTo),
+		RatingFrom: f.formatRating(source, stock.RatingFrom),
+		RatingTo:   f.formatRating(source, stock.RatingTo),
 		Time:       formattedTime,
+		Source:     source,
 	}, nil
 }
 
@@ -85,48 +111,85 @@ func formatCompany(company string) (string, error) {
 	return formatField("company", company, ErrEmptyCompanyString)
 }
 
-func formatBrokerage(brokerage string) (string, error) {
-	return formatField("brokerage", brokerage, ErrEmptyBrokerageString)
+func (f StockFormatter) formatBrokerage(name string) (string, error) {
+	fmtBrokerage, err := formatField("brokerage", name, ErrEmptyBrokerageString)
+	if err != nil {
+		return "", err
+	}
+
+	canonical, _ := f.registry().Canonical(fmtBrokerage)
+	return canonical, nil
+}
+
+func (f StockFormatter) registry() *brokerage.Registry {
+	if f.Brokerages == nil {
+		return brokerage.Default()
+	}
+	return f.Brokerages
+}
+
+func (f StockFormatter) taxonomy() *taxonomy.Taxonomy {
+	if f.Taxonomy == nil {
+		return taxonomy.Default()
+	}
+	return f.Taxonomy
 }
 
-func formatTarget(target string) (float64, error) {
+func formatTarget(target string) (money.Money, error) {
 	target = strings.TrimSpace(target)
 	if target == "" {
-		return 0, fmt.Errorf("%w: target value is required", ErrEmptyTargetString)
+		return money.Money{}, fmt.Errorf("%w: target value is required", ErrEmptyTargetString)
 	}
 
-	targetWithoutDollar := strings.ReplaceAll(target, "$", "")
-	targetWithoutCommas := strings.ReplaceAll(targetWithoutDollar, ",", "")
-
-	value, err := strconv.ParseFloat(targetWithoutCommas, 64)
+	value, err := money.Parse(target)
 	if err != nil {
-		return 0, fmt.Errorf("%w: failed to parse target price '%s': %v", ErrInvalidTimeFormat, targetWithoutCommas, err)
+		return money.Money{}, fmt.Errorf("%w: failed to parse target price '%s': %v", ErrInvalidTargetFormat, target, err)
 	}
 
-	if value < 0 {
-		return 0, fmt.Errorf("%w: target price cannot be negative: %v", ErrNegativeTarget, value)
+	if value.Amount < 0 {
+		return money.Money{}, fmt.Errorf("%w: target price cannot be negative: %v", ErrNegativeTarget, value.Amount)
 	}
 
 	return value, nil
 }
 
+// targetCurrency returns the currency of the targets, a bare number takes the
+// currency of the other target and two bare numbers are dollars.
+func targetCurrency(from, to money.Money) (string, error) {
+	switch {
+	case from.Currency == "" && to.Currency == "":
+		return money.DefaultCurrency, nil
+	case from.Currency == "":
+		return to.Currency, nil
+	case to.Currency == "" || from.Currency == to.Currency:
+		return from.Currency, nil
+	default:
+		return "", fmt.Errorf("%w: target_from in %s and target_to in %s", ErrCurrencyMismatch, from.Currency, to.Currency)
+	}
+}
+
 func formatDefaultField(field string, fieldValue string, defaultValue string) string {
 	fieldValue = strings.TrimSpace(strings.ToLower(fieldValue))
 	if len(fieldValue) == 0 {
-		log.Printf("warning: empty %s defaulted to '%s'", field, defaultValue)
+		slog.Warn("empty field defaulted", "field", field, "default", defaultValue)
 		return defaultValue
 	}
 
 	return fieldValue
 }
 
-func formatRating(rating string) string {
+// formatRating keeps the unmapped ratings as the feed labels them, so they
+// are scored as such once added to the taxonomy.
+func (f StockFormatter) formatRating(source, rating string) string {
 	formattedRating := formatDefaultField("rating", rating, DefaultRating)
-	return narrowRating(formattedRating)
+	category, _ := f.taxonomy().Rating(source, formattedRating)
+	return category.Name
 }
 
-func formatAction(action string) string {
-	return formatDefaultField("action", action, DefaultAction)
+func (f StockFormatter) formatAction(source, action string) string {
+	formattedAction := formatDefaultField("action", action, DefaultAction)
+	category, _ := f.taxonomy().Action(source, formattedAction)
+	return category.Name
 }
 
 func formatTime(timeStr string) (time.Time, error) {
@@ -142,25 +205,3 @@ func formatTime(timeStr string) (time.Time, error) {
 
 	return parsedTime, nil
 }
-
-func narrowRating(rating string) string {
-	switch rating {
-	case "buy", "strong-buy", "positive":
-		return "buy"
-
-	case "outperform", "sector outperform", "market outperform", "overweight", "outperformer", "speculative buy":
-		return "outperform"
-
-	case "hold", "neutral", "unchanged", "market perform", "equal weight", "in-line", "sector perform",
-		"sector weight", "peer perform":
-		return "hold"
-
-	case "underperform", "underweight", "sector underperform", "under perform", "reduce":
-		return "underperform"
-
-	case "sell", "negative":
-		return "sell"
-	default:
-		return rating
-	}
-}
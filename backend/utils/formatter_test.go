@@ -30,10 +30,10 @@ func TestFormatter(t *testing.T) {
 			},
 			expected: &models.FormattedStock{
 				Ticker:     "AAPL",
-				TargetFrom: 150.00,
-				TargetTo:   170.50,
+				TargetFrom: "150.00",
+				TargetTo:   "170.50",
 				Company:    "Apple Inc.",
-				Action:     "upgraded",
+				Action:     "upgraded by",
 				Brokerage:  "Morgan Stanley",
 				RatingFrom: "hold",
 				RatingTo:   "buy",
@@ -56,11 +56,11 @@ func TestFormatter(t *testing.T) {
 			},
 			expected: &models.FormattedStock{
 				Ticker:     "AMZN",
-				TargetFrom: 1200.00,
-				TargetTo:   1500.00,
+				TargetFrom: "1200.00",
+				TargetTo:   "1500.00",
 				Company:    "Amazon.com Inc.",
-				Action:     "downgraded",
-				Brokerage:  "Goldman Sachs",
+				Action:     "downgraded by",
+				Brokerage:  "The Goldman Sachs Group",
 				RatingFrom: "buy",
 				RatingTo:   "neutral",
 				Time:       time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC),
@@ -82,11 +82,11 @@ func TestFormatter(t *testing.T) {
 			},
 			expected: &models.FormattedStock{
 				Ticker:     "MSFT",
-				TargetFrom: 300.00,
-				TargetTo:   350.00,
+				TargetFrom: "300.00",
+				TargetTo:   "350.00",
 				Company:    "Microsoft Corporation",
 				Action:     "initiated by",
-				Brokerage:  "JP Morgan",
+				Brokerage:  "JPMorgan Chase & Co.",
 				RatingFrom: "neutral",
 				RatingTo:   "neutral",
 				Time:       time.Date(2023, time.July, 20, 9, 15, 30, 0, time.UTC),
@@ -125,6 +125,22 @@ func TestFormatter(t *testing.T) {
 			expected: nil,
 			wantErr:  true,
 		},
+		{
+			name: "Targets in different currencies",
+			input: &models.Stock{
+				Ticker:     "SHOP",
+				TargetFrom: "C$90",
+				TargetTo:   "$70",
+				Company:    "Shopify Inc.",
+				Action:     "Upgraded",
+				Brokerage:  "Barclays",
+				RatingFrom: "Hold",
+				RatingTo:   "Buy",
+				Time:       "2023-09-12T11:45:30Z",
+			},
+			expected: nil,
+			wantErr:  true,
+		},
 		{
 			name: "Invalid time format",
 			input: &models.Stock{
@@ -168,10 +184,11 @@ func TestFormatter(t *testing.T) {
 
 func TestFormatTarget(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		expected float64
-		wantErr  bool
+		name         string
+		input        string
+		expected     float64
+		wantCurrency string
+		wantErr      bool
 	}{
 		{
 			name:     "Simple dollar amount",
@@ -203,6 +220,35 @@ func TestFormatTarget(t *testing.T) {
 			expected: 500.0,
 			wantErr:  false,
 		},
+		{
+			name:         "Euro symbol",
+			input:        "€45",
+			expected:     45.0,
+			wantCurrency: "EUR",
+		},
+		{
+			name:         "Canadian dollar symbol",
+			input:        "C$30",
+			expected:     30.0,
+			wantCurrency: "CAD",
+		},
+		{
+			name:         "Pence code",
+			input:        "GBX 1,200",
+			expected:     12.0,
+			wantCurrency: "GBP",
+		},
+		{
+			name:         "Decimal comma and trailing symbol",
+			input:        "1.234,56 €",
+			expected:     1234.56,
+			wantCurrency: "EUR",
+		},
+		{
+			name:    "Unknown currency",
+			input:   "XYZ 30",
+			wantErr: true,
+		},
 		{
 			name:    "Invalid number",
 			input:   "not-a-price",
@@ -225,7 +271,10 @@ func TestFormatTarget(t *testing.T) {
 			}
 
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expected, result.Amount.Float64())
+			if tt.wantCurrency != "" {
+				assert.Equal(t, tt.wantCurrency, result.Currency)
+			}
 		})
 	}
 }
@@ -256,6 +305,16 @@ func TestFormatRating(t *testing.T) {
 			input:    "  hold  ",
 			expected: "hold",
 		},
+		{
+			name:     "Label of a category",
+			input:    "Accumulate",
+			expected: "outperform",
+		},
+		{
+			name:     "Unmapped rating",
+			input:    "Speculative  Hold",
+			expected: "speculative hold",
+		},
 		{
 			name:     "Empty rating",
 			input:    "",
@@ -270,7 +329,7 @@ func TestFormatRating(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := formatRating(tt.input)
+			result := StockFormatter{}.formatRating("", tt.input)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -285,17 +344,17 @@ func TestFormatAction(t *testing.T) {
 		{
 			name:     "Lowercase action",
 			input:    "upgraded",
-			expected: "upgraded",
+			expected: "upgraded by",
 		},
 		{
 			name:     "Uppercase action",
 			input:    "DOWNGRADED",
-			expected: "downgraded",
+			expected: "downgraded by",
 		},
 		{
 			name:     "Mixed case action",
 			input:    "MaInTaInEd",
-			expected: "maintained",
+			expected: "reiterated by",
 		},
 		{
 			name:     "Action with spaces",
@@ -316,7 +375,7 @@ func TestFormatAction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := formatAction(tt.input)
+			result := StockFormatter{}.formatAction("", tt.input)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
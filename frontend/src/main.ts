@@ -7,6 +7,10 @@ import Aura from '@primeuix/themes/aura'
 
 import App from './App.vue'
 import router from './router'
+import { installAuth } from './services/auth'
+
+// every endpoint but /openapi.json and /token requires a scoped credential
+installAuth()
 
 const app = createApp(App)
 
@@ -0,0 +1,56 @@
+import { API_URL } from '@/config/config'
+import axios, { type AxiosError, type InternalAxiosRequestConfig } from 'axios'
+import type { TokenResponse } from '@/types/api'
+
+// tokens are renewed this long before they expire
+const RENEW_MARGIN_MS = 30_000
+
+type RetriedConfig = InternalAxiosRequestConfig & { retried?: boolean }
+
+let token: string | undefined
+let expiresAt = 0
+let pending: Promise<string> | undefined
+
+// the token is requested without the interceptors so it doesn't wait on itself
+const tokenClient = axios.create()
+
+export const getToken = async (): Promise<string> => {
+  if (token && Date.now() < expiresAt - RENEW_MARGIN_MS) {
+    return token
+  }
+
+  pending ??= tokenClient
+    .post(`${API_URL}/token`)
+    .then((response) => {
+      const data = response.data as TokenResponse
+      token = data.access_token
+      expiresAt = Date.now() + data.expires_in * 1000
+      return token
+    })
+    .finally(() => {
+      pending = undefined
+    })
+
+  return pending
+}
+
+// installAuth sends a short-lived token issued by the backend with every call,
+// so the bundle never holds an api key, and renews it once when a call is
+// refused with a 401
+export const installAuth = () => {
+  axios.interceptors.request.use(async (config) => {
+    config.headers.Authorization = `Bearer ${await getToken()}`
+    return config
+  })
+
+  axios.interceptors.response.use(undefined, async (error: AxiosError) => {
+    const config = error.config as RetriedConfig | undefined
+    if (error.response?.status !== 401 || !config || config.retried) {
+      throw error
+    }
+
+    config.retried = true
+    token = undefined
+    return axios(config)
+  })
+}
@@ -0,0 +1,17 @@
+import { API_URL } from '@/config/config'
+import axios from 'axios'
+import type { SearchParams, SearchResponse, SearchSuggestion } from '@/types/api'
+
+// the suggestions come from the dataset of the table they filter, the default
+// one of the deployment when dataset is omitted
+export const fetchSuggestions = async (q: string, dataset?: string): Promise<SearchSuggestion[]> => {
+  try {
+    const params: SearchParams = { q, dataset }
+    const response = await axios.get(`${API_URL}/search`, { params })
+    const data = response.data as SearchResponse
+    return data.suggestions
+  } catch (err: any) {
+    console.error('Error fetching suggestions:', err)
+    return []
+  }
+}
@@ -0,0 +1,128 @@
+// Code generated by cmd/openapi from the endpoint definitions in internal/spec. DO NOT EDIT.
+
+export interface ChartResponse {
+  time_series: DailyData[]
+}
+
+export interface DailyData {
+  open: number
+  high: number
+  low: number
+  close: number
+  volume: number
+  date: string
+}
+
+export interface ErrorResponse {
+  code: string
+  message: string
+  details?: Record<string, unknown>
+}
+
+export interface FormattedStock {
+  ticker: string
+  target_from: number
+  target_to: number
+  currency: string
+  company: string
+  action: string
+  brokerage: string
+  rating_from: string
+  rating_to: string
+  time: string
+  source: string
+  deleted_at?: string
+  recorded_at?: string
+}
+
+export interface Momentum {
+  net_revisions_7d: number
+  net_revisions_30d: number
+  net_revisions_90d: number
+  target_raise_streak: number
+  revision_speed: number
+}
+
+export interface SearchResponse {
+  query: string
+  suggestions: SearchSuggestion[]
+}
+
+export interface SearchSuggestion {
+  field: string
+  value: string
+  ticker?: string
+  score: number
+}
+
+export interface StockAnalysis {
+  ticker: string
+  target_from: number
+  target_to: number
+  currency: string
+  company: string
+  action: string
+  brokerage: string
+  rating_from: string
+  rating_to: string
+  time: string
+  source: string
+  deleted_at?: string
+  recorded_at?: string
+  score: number
+  momentum?: Momentum
+}
+
+export interface StockAnalysisResponse {
+  top_stocks: StockAnalysis[]
+}
+
+export interface StockSummary {
+  total_stocks: number
+  positive_change: number
+  negative_change: number
+  no_change: number
+}
+
+export interface StocksResponse {
+  stocks: FormattedStock[]
+  length: number
+}
+
+export interface TokenResponse {
+  access_token: string
+  token_type: string
+  expires_in: number
+  scope: string
+}
+
+export interface StocksParams {
+  page: number
+  limit: number
+  field?: 'action' | 'brokerage' | 'company' | 'currency' | 'rating_from' | 'rating_to' | 'source' | 'target_from' | 'target_to' | 'ticker' | 'time'
+  order?: 'asc' | 'desc'
+  search?: string
+  include_deleted?: boolean
+  as_of?: string
+  dataset?: string
+}
+
+export interface AnalyzeParams {
+  as_of?: string
+  dataset?: string
+}
+
+export interface MetricsParams {
+  as_of?: string
+  dataset?: string
+}
+
+export interface ChartParams {
+  ticker: string
+}
+
+export interface SearchParams {
+  q: string
+  limit?: number
+  dataset?: string
+}
@@ -1,12 +1,5 @@
-export interface ChartData {
-  open: number
-  high: number
-  low: number
-  close: number
-  volume: number
-  date: string
-}
+import type { DailyData } from './api'
 
-export interface ChartResponse {
-  time_series: ChartData[]
-}
+export type { ChartResponse } from './api'
+
+export type ChartData = DailyData
@@ -1,14 +1,8 @@
-export interface Stock {
-  ticker: string
-  target_from: number
-  target_to: number
-  company: string
-  action: string
-  brokerage: string
-  rating_from: string
-  rating_to: string
-  time: string
-}
+import type { FormattedStock, StockSummary, StocksResponse } from './api'
+
+export type { ErrorResponse } from './api'
+
+export type Stock = FormattedStock
 
 export interface StockWithScore extends Stock {
   index: number
@@ -19,22 +13,10 @@ export interface AnalysisResponse {
   top_stocks: StockWithScore[]
 }
 
-export interface MetricsResponse {
-  total_stocks: number
-  positive_change: number
-  negative_change: number
-  no_change: number
-}
+export type MetricsResponse = StockSummary
 
-export interface StockResponse {
-  stocks: Stock[]
-  length: number
-}
+export type StockResponse = StocksResponse
 
 export interface StockWithScoreResponse {
   stocks: StockWithScore[]
 }
-
-export interface ErrorResponse {
-  message: string
-}
@@ -73,10 +73,10 @@ export const formatDateLong = (date: string) => {
   })
 }
 
-export const formatPrice = (price: number) => {
+export const formatPrice = (price: number, currency = 'USD') => {
   return price.toLocaleString('en-US', {
     style: 'currency',
-    currency: 'USD',
+    currency: currency || 'USD',
   })
 }
 
@@ -1,3 +1,10 @@
+locals {
+  cors_env_vars = {
+    CORS_ALLOWED_ORIGINS   = var.CORS_ALLOWED_ORIGINS
+    CORS_ALLOW_CREDENTIALS = var.CORS_ALLOW_CREDENTIALS
+  }
+}
+
 module "metrics_endpoint" {
   source             = "../../modules/lambda_api_integration/"
   lambda_source_path = "${path.module}/../../../backend/internal/functions/metrics/main.go"
@@ -7,7 +14,7 @@ module "metrics_endpoint" {
   memory_size        = 128
   log_retention_days = 7
 
-  env_vars = { DB_URL = var.DB_URL }
+  env_vars = merge(local.cors_env_vars, { DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
 
   endpoint_name     = "metrics"
   rest_api_id       = module.api_gateway.id
@@ -26,7 +33,7 @@ module "analyze_endpoint" {
   timeout            = 10
   memory_size        = 128
   log_retention_days = 7
-  env_vars           = { DB_URL = var.DB_URL }
+  env_vars           = merge(local.cors_env_vars, { DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
 
   endpoint_name     = "analyze"
   rest_api_id       = module.api_gateway.id
@@ -45,7 +52,7 @@ module "stocks_endpoint" {
   timeout            = 12
   memory_size        = 128
   log_retention_days = 7
-  env_vars           = { DB_URL = var.DB_URL }
+  env_vars           = merge(local.cors_env_vars, { DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
 
   endpoint_name     = "stocks"
   rest_api_id       = module.api_gateway.id
@@ -64,7 +71,7 @@ module "chart_endpoint" {
   timeout            = 12
   memory_size        = 128
   log_retention_days = 7
-  env_vars           = { API_KEY = var.API_KEY }
+  env_vars           = merge(local.cors_env_vars, { API_KEY = var.API_KEY, DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
 
   endpoint_name     = "chart"
   rest_api_id       = module.api_gateway.id
@@ -75,11 +82,68 @@ module "chart_endpoint" {
   stage             = var.stage
 }
 
+module "search_endpoint" {
+  source             = "../../modules/lambda_api_integration/"
+  lambda_source_path = "${path.module}/../../../backend/internal/functions/search/main.go"
+  s3_bucket          = module.lambda_bucket.bucket
+  lambda_role        = module.lambda_role.arn
+  timeout            = 5
+  memory_size        = 128
+  log_retention_days = 7
+  env_vars           = merge(local.cors_env_vars, { DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
+
+  endpoint_name     = "search"
+  rest_api_id       = module.api_gateway.id
+  rest_api_exec_arn = module.api_gateway.execution_arn
+  parent_id         = module.api_gateway.root_resource_id
+  endpoint_path     = "search"
+  http_method       = "GET"
+  stage             = var.stage
+}
+
+module "token_endpoint" {
+  source             = "../../modules/lambda_api_integration/"
+  lambda_source_path = "${path.module}/../../../backend/internal/functions/token/main.go"
+  s3_bucket          = module.lambda_bucket.bucket
+  lambda_role        = module.lambda_role.arn
+  timeout            = 3
+  memory_size        = 128
+  log_retention_days = 7
+  env_vars           = merge(local.cors_env_vars, { DB_URL = var.DB_URL, AUTH_JWT_SECRET = var.AUTH_JWT_SECRET })
+
+  endpoint_name     = "token"
+  rest_api_id       = module.api_gateway.id
+  rest_api_exec_arn = module.api_gateway.execution_arn
+  parent_id         = module.api_gateway.root_resource_id
+  endpoint_path     = "token"
+  http_method       = "POST"
+  stage             = var.stage
+}
+
+module "openapi_endpoint" {
+  source             = "../../modules/lambda_api_integration/"
+  lambda_source_path = "${path.module}/../../../backend/internal/functions/openapi/main.go"
+  s3_bucket          = module.lambda_bucket.bucket
+  lambda_role        = module.lambda_role.arn
+  timeout            = 3
+  memory_size        = 128
+  log_retention_days = 7
+  env_vars           = local.cors_env_vars
+
+  endpoint_name     = "openapi"
+  rest_api_id       = module.api_gateway.id
+  rest_api_exec_arn = module.api_gateway.execution_arn
+  parent_id         = module.api_gateway.root_resource_id
+  endpoint_path     = "openapi.json"
+  http_method       = "GET"
+  stage             = var.stage
+}
+
 // TO DO: Improve redeployment strategy
 resource "aws_api_gateway_deployment" "deployment" {
   rest_api_id = module.api_gateway.id
 
-  depends_on = [module.api_gateway, module.metrics_endpoint, module.analyze_endpoint, module.stocks_endpoint]
+  depends_on = [module.api_gateway, module.metrics_endpoint, module.analyze_endpoint, module.stocks_endpoint, module.search_endpoint, module.token_endpoint, module.openapi_endpoint]
 
   lifecycle {
     create_before_destroy = true
@@ -1,3 +1,12 @@
+locals {
+  # the schedule of every dataset of DATASETS that has one, or of the single
+  # default dataset without DATASETS
+  schedules = var.DATASETS == "" ? { default = var.sync_schedule } : {
+    for name, dataset in jsondecode(var.DATASETS) : lower(trimspace(name)) => dataset.schedule
+    if try(dataset.schedule, "") != ""
+  }
+}
+
 module "update_scheduler" {
   source             = "../../modules/lambda_scheduler_integration/"
   lambda_source_path = "${path.module}/../../../backend/internal/functions/schedule/main.go"
@@ -9,12 +18,14 @@ module "update_scheduler" {
   log_retention_days = 7
 
   env_vars = {
-    DB_URL       = var.DB_URL
-    API_URL      = var.API_URL
-    BEARER_TOKEN = var.BEARER_TOKEN
+    DB_URL          = var.DB_URL
+    API_URL         = var.API_URL
+    BEARER_TOKEN    = var.BEARER_TOKEN
+    DATASETS        = var.DATASETS
+    DEFAULT_DATASET = var.DEFAULT_DATASET
   }
 
-  schedule_expression     = "cron(35 19 ? * mon-fri *)" # Every hour from 8 AM to 3 PM, Monday to Friday
+  schedules               = local.schedules
   scheduler_name          = "update_db_scheduler"
   lambda_scheduler_role   = var.lambda_scheduler_role
   lambda_scheduler_policy = var.lambda_scheduler_policy
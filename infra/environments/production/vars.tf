@@ -53,6 +53,43 @@ variable "BEARER_TOKEN" {
   sensitive   = true
 }
 
+variable "AUTH_JWT_SECRET" {
+  description = "Secret verifying the HS256 bearer tokens of the API, empty to only accept API keys"
+  type        = string
+  sensitive   = true
+  default     = ""
+}
+
+variable "CORS_ALLOWED_ORIGINS" {
+  description = "Comma separated origins allowed to call the API, * for any"
+  type        = string
+  default     = "*"
+}
+
+variable "CORS_ALLOW_CREDENTIALS" {
+  description = "Whether browsers may send credentials, requires explicit origins"
+  type        = string
+  default     = "false"
+}
+
+variable "DATASETS" {
+  description = "JSON object of the datasets of the API, the schedule of each one sets when it's synced"
+  type        = string
+  default     = ""
+}
+
+variable "DEFAULT_DATASET" {
+  description = "Dataset served without the dataset parameter, required with several datasets"
+  type        = string
+  default     = ""
+}
+
+variable "sync_schedule" {
+  description = "Schedule of the sync of the default dataset when DATASETS is empty"
+  type        = string
+  default     = "cron(35 19 ? * mon-fri *)" # Monday to Friday at 7:35 PM
+}
+
 variable "stage" {
   description = "Stage of the API Gateway"
   type        = string
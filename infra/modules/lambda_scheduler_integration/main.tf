@@ -55,15 +55,16 @@ module "lambda_logs_policy" {
   role_name   = module.lambda_scheduler_role.name
 }
 
-
 resource "aws_scheduler_schedule" "this" {
-  name = var.scheduler_name
+  for_each = var.schedules
+
+  name = "${var.scheduler_name}_${each.key}"
 
   flexible_time_window {
     mode = "OFF"
   }
 
-  schedule_expression          = var.schedule_expression
+  schedule_expression          = each.value
   schedule_expression_timezone = "America/Bogota"
 
   target {
@@ -74,7 +75,7 @@ resource "aws_scheduler_schedule" "this" {
       FunctionName   = module.lambda_function.name
       InvocationType = "Event"
       Payload = jsonencode({
-        message = "Hello from AWS Scheduler!"
+        dataset = each.key
       })
     })
   }
@@ -3,9 +3,9 @@ variable "scheduler_name" {
   type        = string
 }
 
-variable "schedule_expression" {
-  description = "The cron expression that defines when the scheduler should run"
-  type        = string
+variable "schedules" {
+  description = "The cron or rate expression of every dataset synced on a schedule, keyed by dataset"
+  type        = map(string)
 }
 
 variable "lambda_scheduler_role" {
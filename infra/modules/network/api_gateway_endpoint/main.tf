@@ -29,7 +29,7 @@ resource "aws_lambda_permission" "this" {
   source_arn    = "${var.rest_api_exec_arn}/*/*/*"
 }
 
-# CORS configuration
+# CORS preflight requests are answered by the lambda with the configured policy
 resource "aws_api_gateway_method" "options" {
   rest_api_id   = var.rest_api_id
   resource_id   = aws_api_gateway_resource.this.id
@@ -37,32 +37,12 @@ resource "aws_api_gateway_method" "options" {
   authorization = "NONE"
 }
 
-# Integración para el método OPTIONS
 resource "aws_api_gateway_integration" "options" {
   rest_api_id = var.rest_api_id
   resource_id = aws_api_gateway_resource.this.id
-  http_method = aws_api_gateway_method.options.http_method
-  type        = "MOCK"
-  request_templates = {
-    "application/json" = jsonencode({
-      statusCode = 200
-    })
-  }
-}
-
-resource "aws_api_gateway_method_response" "cors_method_response" {
-  rest_api_id = var.rest_api_id
-  resource_id = aws_api_gateway_resource.this.id
-  http_method = aws_api_gateway_method.options.http_method
-  status_code = "200"
 
-  response_models = {
-    "application/json" = "Empty"
-  }
-
-  response_parameters = {
-    "method.response.header.Access-Control-Allow-Headers" = true
-    "method.response.header.Access-Control-Allow-Methods" = true
-    "method.response.header.Access-Control-Allow-Origin"  = true
-  }
+  http_method             = aws_api_gateway_method.options.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = var.lambda_invoke_arn
 }